@@ -0,0 +1,74 @@
+// Command backfill-mistakes builds the Firestore mistakes collection of
+// every learner, or of one with -uid, from their answer histories. Learners
+// are otherwise backfilled the first time they list their mistakes; running
+// this after deploying spares them that wait. Rerunning it is safe, and
+// refreshes sentence copies after cmd/seed has changed sentences or an admin
+// edit failed to update them (the API logs "rewrite mistake copies error").
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/hokita/eagle/internal/app"
+)
+
+func main() {
+	uid := flag.String("uid", "", "backfill only this user")
+	flag.Parse()
+
+	ctx := context.Background()
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" {
+		log.Fatal("GOOGLE_CLOUD_PROJECT is required")
+	}
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		log.Fatalf("firestore client: %v", err)
+	}
+	defer client.Close()
+
+	users, sentences, err := backfill(ctx, client, *uid)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("backfilled %d mistaken sentences for %d users\n", sentences, users)
+}
+
+func backfill(ctx context.Context, client *firestore.Client, uid string) (users, sentences int, err error) {
+	repo := app.NewFirestoreRepo(client)
+	one := func(uid string) error {
+		n, err := repo.BackfillMistakes(ctx, uid)
+		if err != nil {
+			return fmt.Errorf("backfill %s: %w", uid, err)
+		}
+		users++
+		sentences += n
+		return nil
+	}
+	if uid != "" {
+		return users, sentences, one(uid)
+	}
+	// users/{uid} docs are never written, only their subcollections, so
+	// they are listed as missing documents rather than queried.
+	it := client.Collection("users").DocumentRefs(ctx)
+	for {
+		ref, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return users, sentences, nil
+		}
+		if err != nil {
+			return users, sentences, err
+		}
+		if err := one(ref.ID); err != nil {
+			return users, sentences, err
+		}
+	}
+}
@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
-	"log"
-	"net/http"
+	"fmt"
+	"log/slog"
 	"os"
-
-	"cloud.google.com/go/firestore"
+	"strings"
+	"unicode"
 
 	"github.com/hokita/eagle/internal/app"
+	"github.com/hokita/eagle/internal/config"
 )
 
 // stubExplanation is asserted verbatim by e2e/tests/incorrect-explain.spec.ts.
@@ -23,6 +24,18 @@ func (stubExplainer) Explain(_ context.Context, _, _, _, _ string) (string, erro
 	return stubExplanation, nil
 }
 
+// ExplainStream emits stubExplanation one word at a time (each chunk keeps
+// its trailing space), so the stream endpoint delivers the same fixed
+// sequence of chunk events on every run and they rejoin to stubExplanation.
+func (stubExplainer) ExplainStream(_ context.Context, _, _, _, _ string, emit func(chunk string) error) error {
+	for _, chunk := range strings.SplitAfter(stubExplanation, " ") {
+		if err := emit(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // stubInsight is returned by stubAnalyzer so e2e runs never call real Gemini.
 const stubInsight = "This is a stub weakness insight for e2e tests."
 
@@ -32,39 +45,93 @@ func (stubAnalyzer) Analyze(_ context.Context, _ []app.MistakeSentence, _ string
 	return stubInsight, nil
 }
 
+// stubGrader stands in for Gemini when the e2e server runs with
+// GRADING_MODE=ai. It is deterministic: an answer is accepted when it
+// matches an accepted answer letter-for-letter once everything but letters
+// and digits is dropped, so "I dont have time" passes for "I don't have
+// time." while a genuinely different sentence is still rejected.
+type stubGrader struct{}
+
+const (
+	stubGradeAccepted = "Stub grader: same words as an accepted answer."
+	stubGradeRejected = "Stub grader: different words from every accepted answer."
+)
+
+func (stubGrader) Grade(_ context.Context, _ string, acceptedAnswers []string, userAnswer string) (app.GradeVerdict, error) {
+	got := lettersOnly(userAnswer)
+	for _, a := range acceptedAnswers {
+		if lettersOnly(a) == got {
+			return app.GradeVerdict{Accepted: true, Reason: stubGradeAccepted}, nil
+		}
+	}
+	return app.GradeVerdict{Accepted: false, Reason: stubGradeRejected}, nil
+}
+
+func lettersOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
 func main() {
 	ctx := context.Background()
 
-	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
-	if projectID == "" {
-		log.Fatal("GOOGLE_CLOUD_PROJECT is required")
+	// The e2e server reads the same settings as the API server, except that
+	// the stubs below stand in for every LLM setting.
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("failed to load configuration", "err", err)
+	}
+
+	logger, err := app.NewLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fatal("invalid LOG_FORMAT", "err", err)
 	}
+	slog.SetDefault(logger)
+	slog.Info("effective configuration", "config", cfg)
 
-	allowedEmails := app.ParseAllowedEmails(os.Getenv("ALLOWED_EMAILS"))
-	if len(allowedEmails) == 0 {
-		log.Fatal("ALLOWED_EMAILS is required")
+	if err := run(ctx, cfg); err != nil {
+		fatal("server failed", "err", err)
 	}
+	slog.Info("server stopped")
+}
 
-	client, err := firestore.NewClient(ctx, projectID)
+// run serves until ctx is done or serving fails, returning rather than
+// exiting so the deferred closeRepo still runs.
+func run(ctx context.Context, cfg *config.Config) error {
+	repo, closeRepo, err := app.OpenRepository(ctx, cfg.Repo)
 	if err != nil {
-		log.Fatalf("failed to create Firestore client: %v", err)
+		return fmt.Errorf("failed to open repository: %w", err)
 	}
-	defer client.Close()
+	defer closeRepo()
+	metrics := app.NewMetrics()
+	repo = app.InstrumentRepository(repo, metrics)
 
-	verifier, err := app.NewFirebaseVerifier(ctx, projectID)
+	verifier, err := app.NewFirebaseVerifier(ctx, cfg.ProjectID)
 	if err != nil {
-		log.Fatalf("failed to create auth verifier: %v", err)
+		return fmt.Errorf("failed to create auth verifier: %w", err)
 	}
 
-	srv := app.NewServer(app.NewFirestoreRepo(client), stubExplainer{}, stubAnalyzer{})
+	srv := app.NewServer(repo, stubExplainer{}, stubAnalyzer{})
+	srv.SetMetrics(metrics)
+	srv.SetMetricsToken(cfg.MetricsToken)
+	srv.SetLimits(cfg.Limits)
+	srv.SetReadiness(cfg.Readiness)
+	if cfg.GradingMode == "ai" {
+		srv.SetGrader(stubGrader{})
+	}
 
-	frontendURL := os.Getenv("FRONTEND_URL")
-	mux := app.NewMux(srv, verifier, allowedEmails, frontendURL)
+	mux := app.NewMux(srv, verifier, cfg.AllowedEmails, cfg.AdminEmails, cfg.FrontendURL)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	log.Printf("e2e server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, mux))
+	slog.Info("e2e server starting", "port", cfg.Port)
+	return app.Serve(ctx, cfg.Serve, mux)
+}
+
+// fatal logs msg and its attributes as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
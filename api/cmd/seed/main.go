@@ -1,9 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -12,31 +11,34 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+
+	"github.com/hokita/eagle/internal/app"
 )
 
-type row struct {
-	ID         int         `json:"id"`
-	Japanese   string      `json:"japanese"`
-	English    string      `json:"english"`
-	Page       json.Number `json:"page"`
-	Level      int         `json:"level"`
-	IsReported int         `json:"is_reported"`
-}
+// row is the shared NDJSON row shape, also loaded by the in-memory
+// repository (app.NewMemoryRepo) so fixtures work with either backend.
+type row = app.SeedRow
 
 // toFirestoreFields builds the Firestore write payload for one NDJSON row,
-// validating that level falls in the supported 1-5 difficulty range.
+// validating it with SeedRow.Validate (both texts present, level 1-5).
+// accepted_answers is always written as an array, never null.
 func toFirestoreFields(rw row, now string) (map[string]interface{}, error) {
-	if rw.Level < 1 || rw.Level > 5 {
-		return nil, fmt.Errorf("sentence %d: level must be 1-5, got %d", rw.ID, rw.Level)
+	if err := rw.Validate(); err != nil {
+		return nil, err
+	}
+	accepted := rw.AcceptedAnswers
+	if accepted == nil {
+		accepted = []string{}
 	}
 	return map[string]interface{}{
-		"japanese":    rw.Japanese,
-		"english":     rw.English,
-		"page":        rw.Page.String(),
-		"level":       rw.Level,
-		"is_reported": rw.IsReported != 0,
-		"created_at":  now,
-		"updated_at":  now,
+		"japanese":         rw.Japanese,
+		"english":          rw.English,
+		"page":             rw.Page.String(),
+		"level":            rw.Level,
+		"is_reported":      rw.IsReported != 0,
+		"created_at":       now,
+		"updated_at":       now,
+		"accepted_answers": accepted,
 	}, nil
 }
 
@@ -44,49 +46,72 @@ func main() {
 	path := flag.String("file", "docs/sentences_export.ndjson", "path to NDJSON export")
 	flag.Parse()
 
+	ctx := context.Background()
+	f, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("open %s: %v", *path, err)
+	}
+	defer f.Close()
+
+	var count int
+	switch backend := os.Getenv("REPO_BACKEND"); backend {
+	case "", "firestore":
+		count, err = seedFirestore(ctx, f)
+	case "sqlite", "postgres":
+		count, err = seedSQL(ctx, backend, f)
+	default:
+		log.Fatalf("unsupported REPO_BACKEND %q (want firestore, sqlite or postgres)", backend)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("seeded %d sentences\n", count)
+}
+
+func seedFirestore(ctx context.Context, f *os.File) (int, error) {
 	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
 	if projectID == "" {
-		log.Fatal("GOOGLE_CLOUD_PROJECT is required")
+		return 0, errors.New("GOOGLE_CLOUD_PROJECT is required")
 	}
 
-	ctx := context.Background()
 	client, err := firestore.NewClient(ctx, projectID)
 	if err != nil {
-		log.Fatalf("firestore client: %v", err)
+		return 0, fmt.Errorf("firestore client: %w", err)
 	}
 	defer client.Close()
 
-	f, err := os.Open(*path)
-	if err != nil {
-		log.Fatalf("open %s: %v", *path, err)
-	}
-	defer f.Close()
-
 	now := time.Now().UTC().Format(time.RFC3339)
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 	count := 0
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
-		}
-		var rw row
-		if err := json.Unmarshal(line, &rw); err != nil {
-			log.Fatalf("parse line: %v", err)
-		}
+	err = app.ScanSeedRows(f, func(rw row) error {
 		fields, err := toFirestoreFields(rw, now)
 		if err != nil {
-			log.Fatalf("invalid row: %v", err)
+			return fmt.Errorf("invalid row: %w", err)
 		}
 		_, err = client.Collection("sentences").Doc(strconv.Itoa(rw.ID)).Set(ctx, fields)
 		if err != nil {
-			log.Fatalf("write sentence %d: %v", rw.ID, err)
+			return fmt.Errorf("write sentence %d: %w", rw.ID, err)
 		}
 		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("scan: %v", err)
+	// The sentences were written around the repository, so running servers
+	// must be told to reload them.
+	if err := app.InvalidateFirestoreCatalog(ctx, client); err != nil {
+		return count, fmt.Errorf("invalidate sentence catalog: %w", err)
 	}
-	fmt.Printf("seeded %d sentences\n", count)
+	return count, nil
+}
+
+// seedSQL loads the export into a SQLite or Postgres database at
+// DATABASE_URL, creating the schema first if needed.
+func seedSQL(ctx context.Context, backend string, f *os.File) (int, error) {
+	repo, err := app.OpenSQLRepo(ctx, backend, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		return 0, err
+	}
+	defer repo.Close()
+	return repo.LoadNDJSON(ctx, f)
 }
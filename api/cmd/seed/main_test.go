@@ -33,3 +33,28 @@ func TestToFirestoreFieldsRejectsOutOfRangeLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestToFirestoreFieldsAcceptedAnswers(t *testing.T) {
+	rw := row{ID: 1, Japanese: "あ", English: "a", Page: json.Number("1"), Level: 1}
+	fields, err := toFirestoreFields(rw, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := fields["accepted_answers"].([]string); !ok || got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty accepted_answers array, got %#v", fields["accepted_answers"])
+	}
+
+	rw.AcceptedAnswers = []string{"an a"}
+	fields, err = toFirestoreFields(rw, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fields["accepted_answers"].([]string); len(got) != 1 || got[0] != "an a" {
+		t.Fatalf("expected [an a], got %#v", got)
+	}
+
+	rw.AcceptedAnswers = []string{"  "}
+	if _, err := toFirestoreFields(rw, "2026-01-01T00:00:00Z"); err == nil {
+		t.Fatal("expected error for a blank accepted answer")
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// The admin handlers back content triage: reviewing reported sentences and
+// fixing or removing them without re-running cmd/seed. NewMux serves them
+// behind requireAdmin.
+
+func (s *Server) listReportedSentences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	sentences, err := s.repo.ListReported(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "list reported error", "err", err)
+		writeInternalError(w, r)
+		return
+	}
+	writeJSON(w, r, sentences)
+}
+
+func (s *Server) updateSentence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	var req UpdateSentenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+	edit := SentenceEdit{Japanese: req.Japanese, English: req.English, Page: req.Page, Level: req.Level}
+	if err := edit.Validate(req.SentenceID); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidSentence, err.Error())
+		return
+	}
+	err := s.repo.UpdateSentence(r.Context(), req.SentenceID, edit)
+	writeAdminResult(w, r, "update sentence", err)
+}
+
+func (s *Server) unreportSentence(w http.ResponseWriter, r *http.Request) {
+	req, ok := readAdminSentenceRequest(w, r)
+	if !ok {
+		return
+	}
+	writeAdminResult(w, r, "unreport", s.repo.Unreport(r.Context(), req.SentenceID))
+}
+
+func (s *Server) deleteSentence(w http.ResponseWriter, r *http.Request) {
+	req, ok := readAdminSentenceRequest(w, r)
+	if !ok {
+		return
+	}
+	writeAdminResult(w, r, "delete sentence", s.repo.DeleteSentence(r.Context(), req.SentenceID))
+}
+
+func readAdminSentenceRequest(w http.ResponseWriter, r *http.Request) (AdminSentenceRequest, bool) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return AdminSentenceRequest{}, false
+	}
+	var req AdminSentenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidBody(w, r)
+		return AdminSentenceRequest{}, false
+	}
+	return req, true
+}
+
+// writeAdminResult answers a sentence mutation: 204 on success, 404 for an
+// unknown sentence.
+func writeAdminResult(w http.ResponseWriter, r *http.Request, op string, err error) {
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeSentenceNotFound, "Sentence not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), op+" error", "err", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
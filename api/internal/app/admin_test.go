@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	const adminEmail = "admin@example.com"
+	for name, tc := range map[string]struct {
+		verifier fakeVerifier
+		want     int
+	}{
+		"allowlisted email": {fakeVerifier{uid: "u1", email: adminEmail}, http.StatusOK},
+		"admin claim":       {fakeVerifier{uid: "u1", email: testAllowedEmail, admin: true}, http.StatusOK},
+		"plain user":        {fakeVerifier{uid: "u1", email: testAllowedEmail}, http.StatusForbidden},
+	} {
+		h := requireAuth(tc.verifier, []string{testAllowedEmail, adminEmail}, requireAdmin([]string{adminEmail}, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/sentences/reported", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestWithAdminStatus(t *testing.T) {
+	const adminEmail = "admin@example.com"
+	for name, tc := range map[string]struct {
+		verifier fakeVerifier
+		want     bool
+	}{
+		"allowlisted email": {fakeVerifier{uid: "u1", email: adminEmail}, true},
+		"admin claim":       {fakeVerifier{uid: "u1", email: testAllowedEmail, admin: true}, true},
+		"plain user":        {fakeVerifier{uid: "u1", email: testAllowedEmail}, false},
+	} {
+		var got bool
+		h := requireAuth(tc.verifier, []string{testAllowedEmail, adminEmail}, withAdminStatus([]string{adminEmail}, func(w http.ResponseWriter, r *http.Request) {
+			got = isAdminRequest(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/api/answer/override", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		h(httptest.NewRecorder(), req)
+		if got != tc.want {
+			t.Errorf("%s: expected admin %v, got %v", name, tc.want, got)
+		}
+	}
+}
+
+func TestListReportedSentences(t *testing.T) {
+	repo := &fakeRepo{reportedList: []AdminSentence{{ID: 3, Japanese: "犬", English: "a dog", Level: 1, IsReported: true}}}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	rec := httptest.NewRecorder()
+	srv.listReportedSentences(rec, authed(httptest.NewRequest(http.MethodGet, "/api/admin/sentences/reported", nil), "admin"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var got []AdminSentence
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != 3 || !got[0].IsReported {
+		t.Fatalf("unexpected sentences: %+v", got)
+	}
+}
+
+func TestUpdateSentence(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":5,"japanese":"猫","english":"a cat","page":"12","level":2}`
+	rec := httptest.NewRecorder()
+	srv.updateSentence(rec, authed(httptest.NewRequest(http.MethodPost, "/api/admin/sentence/update", strings.NewReader(body)), "admin"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	want := SentenceEdit{Japanese: "猫", English: "a cat", Page: "12", Level: 2}
+	if repo.edits[5] != want {
+		t.Fatalf("expected %+v stored for sentence 5, got %+v", want, repo.edits)
+	}
+}
+
+func TestUpdateSentenceRejectsInvalidEdits(t *testing.T) {
+	for name, body := range map[string]string{
+		"level too low":   `{"sentence_id":5,"japanese":"猫","english":"a cat","level":0}`,
+		"level too high":  `{"sentence_id":5,"japanese":"猫","english":"a cat","level":6}`,
+		"blank english":   `{"sentence_id":5,"japanese":"猫","english":" ","level":2}`,
+		"blank japanese":  `{"sentence_id":5,"japanese":"","english":"a cat","level":2}`,
+		"page not number": `{"sentence_id":5,"japanese":"猫","english":"a cat","page":"abc","level":2}`,
+		"malformed":       `{`,
+	} {
+		repo := &fakeRepo{}
+		srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+		rec := httptest.NewRecorder()
+		srv.updateSentence(rec, authed(httptest.NewRequest(http.MethodPost, "/api/admin/sentence/update", strings.NewReader(body)), "admin"))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+		if len(repo.edits) != 0 {
+			t.Errorf("%s: expected nothing stored, got %+v", name, repo.edits)
+		}
+	}
+}
+
+func TestUnreportAndDeleteSentence(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	for _, tc := range []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/api/admin/sentence/unreport", srv.unreportSentence},
+		{"/api/admin/sentence/delete", srv.deleteSentence},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler(rec, authed(httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(`{"sentence_id":7}`)), "admin"))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("%s: expected 204, got %d", tc.path, rec.Code)
+		}
+	}
+	if len(repo.unreported) != 1 || repo.unreported[0] != 7 || len(repo.deleted) != 1 || repo.deleted[0] != 7 {
+		t.Fatalf("expected sentence 7 unreported and deleted, got %v and %v", repo.unreported, repo.deleted)
+	}
+}
+
+func TestAdminMutationErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{ErrNotFound, http.StatusNotFound},
+		{errors.New("boom"), http.StatusInternalServerError},
+	} {
+		srv := NewServer(&fakeRepo{adminErr: tc.err}, &fakeExplainer{}, &fakeAnalyzer{})
+		rec := httptest.NewRecorder()
+		srv.deleteSentence(rec, authed(httptest.NewRequest(http.MethodPost, "/api/admin/sentence/delete", strings.NewReader(`{"sentence_id":7}`)), "admin"))
+		if rec.Code != tc.want {
+			t.Errorf("%v: expected %d, got %d", tc.err, tc.want, rec.Code)
+		}
+	}
+}
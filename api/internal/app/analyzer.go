@@ -18,11 +18,11 @@ type WeaknessAnalyzer interface {
 const maxWrongAnswersPerSentence = 5
 
 // maxPromptChars bounds the total size of the mistakes section of the
-// weakness-analysis prompt. maxInsightMistakes and maxWrongAnswersPerSentence
-// bound the *shape* of the input (how many sentences, how many answers
-// each), but not the length of individual fields — a learner's stored
-// answers can each be up to maxUserAnswerLength long, so a full 50 sentences
-// x 5 answers could still be very large. This is the final backstop: whole
+// weakness-analysis prompt. Limits.MaxInsightMistakes and
+// maxWrongAnswersPerSentence bound the *shape* of the input (how many
+// sentences, how many answers each), but not the length of individual fields
+// — a learner's stored answers can each be up to Limits.MaxUserAnswerLength
+// long, so a full 50 sentences x 5 answers could still be very large. This is the final backstop: whole
 // mistake blocks are dropped, oldest (least recent) first, once adding the
 // next one would exceed the budget, so the request sent to Gemini has a
 // predictable upper bound regardless of per-field content.
@@ -70,15 +70,15 @@ func TestBuildWeaknessPromptKeepsMostRecentWrongAnswersWhenCapping(t *testing.T)
 }
 
 // TestBuildWeaknessPromptEnforcesTotalCharacterBudget is a regression test:
-// maxInsightMistakes and maxWrongAnswersPerSentence bound the *shape* of the
-// input (how many sentences, how many answers each), but not the size of
-// individual fields. A learner with many sentences each carrying long
+// Limits.MaxInsightMistakes and maxWrongAnswersPerSentence bound the *shape*
+// of the input (how many sentences, how many answers each), but not the size
+// of individual fields. A learner with many sentences each carrying long
 // wrong-answer text could still produce an enormous prompt. This asserts a
 // hard ceiling on the prompt's total size regardless of per-field content.
 func TestBuildWeaknessPromptEnforcesTotalCharacterBudget(t *testing.T) {
 	longAnswer := strings.Repeat("a", 2000)
 	var mistakes []MistakeSentence
-	for i := 0; i < maxInsightMistakes; i++ {
+	for i := 0; i < DefaultLimits().MaxInsightMistakes; i++ {
 		mistakes = append(mistakes, MistakeSentence{
 			SentenceID:    i,
 			Japanese:      "文",
@@ -94,7 +94,7 @@ func TestBuildWeaknessPromptEnforcesTotalCharacterBudget(t *testing.T) {
 	if len(prompt) > maxPromptChars+1000 {
 		t.Fatalf("expected prompt length to stay near the %d-char budget, got %d", maxPromptChars, len(prompt))
 	}
-	if strings.Count(prompt, "Learner wrote:") == maxInsightMistakes*maxWrongAnswersPerSentence {
+	if strings.Count(prompt, "Learner wrote:") == DefaultLimits().MaxInsightMistakes*maxWrongAnswersPerSentence {
 		t.Fatal("expected the character budget to drop some mistakes when every field is maximally long")
 	}
 	if !strings.HasSuffix(prompt, "in English.") {
@@ -7,15 +7,27 @@ import (
 	"strings"
 )
 
-// TokenVerifier verifies a bearer credential and returns the caller's uid and
-// verified email address.
+// Identity is the caller a TokenVerifier authenticated.
+type Identity struct {
+	UID string
+	// Email is the caller's verified email address.
+	Email string
+	// Admin is set when the credential carries the admin custom claim.
+	Admin bool
+}
+
+// TokenVerifier verifies a bearer credential and returns who presented it.
 type TokenVerifier interface {
-	Verify(ctx context.Context, idToken string) (uid string, email string, err error)
+	Verify(ctx context.Context, idToken string) (Identity, error)
 }
 
 type ctxKey string
 
-const uidCtxKey ctxKey = "uid"
+const (
+	uidCtxKey      ctxKey = "uid"
+	identityCtxKey ctxKey = "identity"
+	adminCtxKey    ctxKey = "admin"
+)
 
 func withUID(ctx context.Context, uid string) context.Context {
 	return context.WithValue(ctx, uidCtxKey, uid)
@@ -40,23 +52,63 @@ func requireAuth(v TokenVerifier, allowedEmails []string, next http.HandlerFunc)
 	return func(w http.ResponseWriter, r *http.Request) {
 		authz := r.Header.Get("Authorization")
 		if !strings.HasPrefix(authz, prefix) {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 			return
 		}
 		idToken := strings.TrimSpace(strings.TrimPrefix(authz, prefix))
 		if idToken == "" {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 			return
 		}
-		uid, email, err := v.Verify(r.Context(), idToken)
+		id, err := v.Verify(r.Context(), idToken)
 		if err != nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+		if id.Email == "" || !slices.Contains(allowedEmails, id.Email) {
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 			return
 		}
-		if email == "" || !slices.Contains(allowedEmails, email) {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		if l := requestLogFromContext(r.Context()); l != nil {
+			l.uid = id.UID
+		}
+		ctx := context.WithValue(withUID(r.Context(), id.UID), identityCtxKey, id)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// isAdmin reports whether id is an admin: their token carries the admin
+// custom claim or their email is in adminEmails (the ADMIN_EMAILS
+// convention).
+func (id Identity) isAdmin(adminEmails []string) bool {
+	return id.Admin || (id.Email != "" && slices.Contains(adminEmails, id.Email))
+}
+
+// withAdminStatus records in the context whether the caller is an admin, for
+// handlers that let only admins do part of what they do (see
+// isAdminRequest). It must run inside requireAuth.
+func withAdminStatus(adminEmails []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, _ := r.Context().Value(identityCtxKey).(Identity)
+		next(w, r.WithContext(context.WithValue(r.Context(), adminCtxKey, id.isAdmin(adminEmails))))
+	}
+}
+
+func isAdminRequest(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminCtxKey).(bool)
+	return admin
+}
+
+// requireAdmin wraps a handler that only admins may call (see isAdmin). It
+// must run inside requireAuth. Anyone else is signed in but not allowed, so
+// gets 403.
+func requireAdmin(adminEmails []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, _ := r.Context().Value(identityCtxKey).(Identity)
+		if !id.isAdmin(adminEmails) {
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "Admin access required")
 			return
 		}
-		next(w, r.WithContext(withUID(r.Context(), uid)))
+		next(w, r)
 	}
 }
@@ -25,11 +25,16 @@ func NewFirebaseVerifier(ctx context.Context, projectID string) (*firebaseVerifi
 	return &firebaseVerifier{client: client}, nil
 }
 
-func (v *firebaseVerifier) Verify(ctx context.Context, idToken string) (string, string, error) {
+// adminClaim is the custom claim that grants admin access, set with the
+// Admin SDK's SetCustomUserClaims(uid, {"admin": true}).
+const adminClaim = "admin"
+
+func (v *firebaseVerifier) Verify(ctx context.Context, idToken string) (Identity, error) {
 	token, err := v.client.VerifyIDToken(ctx, idToken)
 	if err != nil {
-		return "", "", err
+		return Identity{}, err
 	}
 	email, _ := token.Claims["email"].(string)
-	return token.UID, email, nil
+	admin, _ := token.Claims[adminClaim].(bool)
+	return Identity{UID: token.UID, Email: email, Admin: admin}, nil
 }
@@ -11,11 +11,12 @@ import (
 type fakeVerifier struct {
 	uid   string
 	email string
+	admin bool
 	err   error
 }
 
-func (f fakeVerifier) Verify(_ context.Context, _ string) (string, string, error) {
-	return f.uid, f.email, f.err
+func (f fakeVerifier) Verify(_ context.Context, _ string) (Identity, error) {
+	return Identity{UID: f.uid, Email: f.email, Admin: f.admin}, f.err
 }
 
 const testAllowedEmail = "test@example.com"
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// generationStore is the part of SentenceRepository that persists LLM
+// output.
+type generationStore interface {
+	CachedGeneration(ctx context.Context, key string) (string, bool, error)
+	CacheGeneration(ctx context.Context, key, text string, ttl time.Duration) error
+}
+
+// generationCache fronts the LLM with the repository's generation cache. A
+// zero ttl disables it. Cache failures are logged and otherwise ignored: a
+// request that cannot use the cache still gets a freshly generated answer.
+type generationCache struct {
+	store generationStore
+	ttl   time.Duration
+}
+
+// get returns the cached text for key unless the cache is disabled or the
+// caller asked to refresh it.
+func (c generationCache) get(ctx context.Context, key string, refresh bool) (string, bool) {
+	if c.ttl <= 0 || refresh {
+		return "", false
+	}
+	text, ok, err := c.store.CachedGeneration(ctx, key)
+	if err != nil {
+		slog.ErrorContext(ctx, "read generation cache error", "err", err)
+		return "", false
+	}
+	return text, ok
+}
+
+// put stores text under key. Blank output is never cached, so a bad
+// generation is retried on the next request.
+func (c generationCache) put(ctx context.Context, key, text string) {
+	if c.ttl <= 0 || strings.TrimSpace(text) == "" {
+		return
+	}
+	if err := c.store.CacheGeneration(ctx, key, text, c.ttl); err != nil {
+		slog.ErrorContext(ctx, "write generation cache error", "err", err)
+	}
+}
+
+// cacheKey hashes the JSON form of parts under a readable kind prefix, so
+// keys are fixed-length and safe as Firestore document IDs.
+func cacheKey(kind string, parts any) string {
+	b, err := json.Marshal(parts)
+	if err != nil {
+		// parts is always plain strings and ints.
+		panic(err)
+	}
+	sum := sha256.Sum256(b)
+	return kind + ":" + hex.EncodeToString(sum[:])
+}
+
+// generationModeler is implemented by an Explainer or WeaknessAnalyzer that
+// can name the provider and model it generates with. Cached output is keyed
+// on it, so switching LLM_PROVIDER or a model stops serving the old output.
+type generationModeler interface {
+	generationModel() string
+}
+
+// generationModel names what g generates with, or "" if it cannot say.
+func generationModel(g any) string {
+	if m, ok := g.(generationModeler); ok {
+		return m.generationModel()
+	}
+	return ""
+}
+
+// explainCacheKey identifies an explanation by the model that writes it and
+// everything the prompt is built from. The learner's answer is normalized
+// only for whitespace and typographic punctuation: unlike grading, an
+// explanation may comment on case or a missing full stop.
+func explainCacheKey(in explainInput, model string) string {
+	answer := strings.Join(strings.Fields(answerPunctuation.Replace(in.userAnswer)), " ")
+	return cacheKey("explain", []string{model, in.japanese, in.correctAnswer, answer, in.language})
+}
+
+// insightCacheKey fingerprints the mistake set an insight is generated from:
+// each sentence and the wrong answers included for it, independent of the
+// order ListMistakesForInsight returns them in. Any new mistake, or an old
+// one overridden to correct, changes the fingerprint, as does the model.
+func insightCacheKey(mistakes []MistakeSentence, language, model string) string {
+	type entry struct {
+		ID           int      `json:"id"`
+		Japanese     string   `json:"japanese"`
+		Correct      string   `json:"correct"`
+		WrongAnswers []string `json:"wrong"`
+	}
+	entries := make([]entry, 0, len(mistakes))
+	for _, m := range mistakes {
+		wrong := make([]string, 0, len(m.WrongAnswers))
+		for _, h := range m.WrongAnswers {
+			wrong = append(wrong, h.IncorrectAnswer)
+		}
+		entries = append(entries, entry{m.SentenceID, m.Japanese, m.CorrectAnswer, wrong})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return cacheKey("insight", struct {
+		Model    string  `json:"model"`
+		Language string  `json:"language"`
+		Mistakes []entry `json:"mistakes"`
+	}{model, language, entries})
+}
+
+// cachedExplainer wraps an Explainer with the generation cache, reporting
+// whether each explanation came from it. Only a cache miss is charged to the
+// route's rate limit.
+type cachedExplainer struct {
+	Explainer
+	cache generationCache
+	model string
+}
+
+func (e cachedExplainer) explain(ctx context.Context, in explainInput) (string, bool, error) {
+	key := explainCacheKey(in, e.model)
+	if text, ok := e.cache.get(ctx, key, in.refresh); ok {
+		return text, true, nil
+	}
+	if err := chargeRateLimit(ctx); err != nil {
+		return "", false, err
+	}
+	text, err := e.Explain(ctx, in.japanese, in.correctAnswer, in.userAnswer, in.language)
+	if err != nil {
+		return "", false, err
+	}
+	e.cache.put(ctx, key, text)
+	return text, false, nil
+}
+
+// explainStream emits a cached explanation as a single chunk; otherwise it
+// streams a fresh one and caches it once the stream completes.
+func (e cachedExplainer) explainStream(ctx context.Context, in explainInput, emit func(chunk string) error) (bool, error) {
+	key := explainCacheKey(in, e.model)
+	if text, ok := e.cache.get(ctx, key, in.refresh); ok {
+		return true, emit(text)
+	}
+	if err := chargeRateLimit(ctx); err != nil {
+		return false, err
+	}
+	var full strings.Builder
+	err := e.ExplainStream(ctx, in.japanese, in.correctAnswer, in.userAnswer, in.language, func(chunk string) error {
+		full.WriteString(chunk)
+		return emit(chunk)
+	})
+	if err != nil {
+		return false, err
+	}
+	e.cache.put(ctx, key, full.String())
+	return false, nil
+}
+
+// cachedAnalyzer wraps a WeaknessAnalyzer with the generation cache,
+// reporting whether each insight came from it. Like cachedExplainer, it
+// charges the rate limit only on a miss.
+type cachedAnalyzer struct {
+	WeaknessAnalyzer
+	cache generationCache
+	model string
+}
+
+func (a cachedAnalyzer) analyze(ctx context.Context, mistakes []MistakeSentence, language string, refresh bool) (string, bool, error) {
+	key := insightCacheKey(mistakes, language, a.model)
+	if text, ok := a.cache.get(ctx, key, refresh); ok {
+		return text, true, nil
+	}
+	if err := chargeRateLimit(ctx); err != nil {
+		return "", false, err
+	}
+	text, err := a.Analyze(ctx, mistakes, language)
+	if err != nil {
+		return "", false, err
+	}
+	a.cache.put(ctx, key, text)
+	return text, false, nil
+}
@@ -0,0 +1,66 @@
+package app
+
+import "testing"
+
+func TestExplainCacheKeyNormalizesAnswer(t *testing.T) {
+	base := explainInput{japanese: "時間がありません。", correctAnswer: "I don't have time.", userAnswer: "I don't have time", language: "en"}
+	same := base
+	same.userAnswer = "  I don’t  have\ntime "
+	same.refresh = true
+	if explainCacheKey(base, "m") != explainCacheKey(same, "m") {
+		t.Fatal("expected whitespace, typographic quotes and refresh not to change the key")
+	}
+	for name, mutate := range map[string]func(*explainInput){
+		"case":        func(in *explainInput) { in.userAnswer = "i don't have time" },
+		"punctuation": func(in *explainInput) { in.userAnswer = "I don't have time." },
+		"language":    func(in *explainInput) { in.language = "ja" },
+		"reference":   func(in *explainInput) { in.correctAnswer = "I have no time." },
+	} {
+		other := base
+		mutate(&other)
+		if explainCacheKey(base, "m") == explainCacheKey(other, "m") {
+			t.Errorf("%s: expected a different key", name)
+		}
+	}
+	if explainCacheKey(base, "gemini/a") == explainCacheKey(base, "gemini/b") {
+		t.Error("model: expected a different key")
+	}
+}
+
+func TestInsightCacheKeyFingerprintsMistakeSet(t *testing.T) {
+	a := MistakeSentence{SentenceID: 1, Japanese: "犬", CorrectAnswer: "a dog", WrongAnswers: []AnswerHistory{{ID: 10, IncorrectAnswer: "dog", CreatedAt: "2026-01-01T00:00:00Z"}}}
+	b := MistakeSentence{SentenceID: 2, Japanese: "猫", CorrectAnswer: "a cat", WrongAnswers: []AnswerHistory{{ID: 11, IncorrectAnswer: "cat"}}}
+	key := insightCacheKey([]MistakeSentence{a, b}, "en", "m")
+	if key != insightCacheKey([]MistakeSentence{b, a}, "en", "m") {
+		t.Fatal("expected the key not to depend on mistake order")
+	}
+	if key == insightCacheKey([]MistakeSentence{a, b}, "ja", "m") {
+		t.Fatal("expected the language to change the key")
+	}
+	more := b
+	more.WrongAnswers = append([]AnswerHistory{{ID: 12, IncorrectAnswer: "kitty"}}, b.WrongAnswers...)
+	if key == insightCacheKey([]MistakeSentence{a, more}, "en", "m") {
+		t.Fatal("expected a new wrong answer to change the key")
+	}
+	if key == insightCacheKey([]MistakeSentence{a}, "en", "m") {
+		t.Fatal("expected a removed mistake to change the key")
+	}
+	if key == insightCacheKey([]MistakeSentence{a, b}, "en", "openai:http://localhost:11434/v1/m") {
+		t.Fatal("expected the model to change the key")
+	}
+}
+
+func TestLLMGenerationModelNamesProvider(t *testing.T) {
+	settings := ModelSettings{Model: "m"}
+	gemini := NewLLMExplainer(InstrumentContentGenerator(geminiModels{}, nil), settings)
+	local := NewLLMExplainer(&openAIChatClient{baseURL: "http://localhost:11434/v1"}, settings)
+	if got := generationModel(gemini); got != "gemini/m" {
+		t.Fatalf("expected gemini/m, got %q", got)
+	}
+	if got := generationModel(NewLLMWeaknessAnalyzer(local.models, settings)); got != "openai:http://localhost:11434/v1/m" {
+		t.Fatalf("expected the endpoint in the model, got %q", got)
+	}
+	if got := generationModel(&fakeExplainer{}); got != "" {
+		t.Fatalf("expected no model for a non-LLM explainer, got %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"slices"
+	"time"
+)
+
+// CandidateSource narrows RandomCandidate to one kind of sentence, by the
+// learner's history with it.
+type CandidateSource string
+
+const (
+	// SourceAll offers never-seen and seen sentences alike.
+	SourceAll CandidateSource = "all"
+	// SourceNew offers only sentences the learner has never answered.
+	SourceNew CandidateSource = "new"
+	// SourceSeen offers only sentences the learner has answered, due or not.
+	SourceSeen CandidateSource = "seen"
+	// SourceReview offers only answered sentences that are due for review.
+	SourceReview CandidateSource = "review"
+	// SourceMistakes offers only sentences the learner has answered
+	// incorrectly, until they master them (see ReviewPolicy.mastered).
+	SourceMistakes CandidateSource = "mistakes"
+)
+
+func (s CandidateSource) valid() bool {
+	switch s {
+	case SourceAll, SourceNew, SourceSeen, SourceReview, SourceMistakes:
+		return true
+	}
+	return false
+}
+
+// CandidateQuery narrows the sentences RandomCandidate chooses from. The
+// zero value offers every unreported sentence.
+type CandidateQuery struct {
+	// Levels keeps sentences whose level is in the set; empty keeps all,
+	// including sentences with no level set.
+	Levels []int
+	// Source is SourceAll when empty.
+	Source CandidateSource
+	// Exclude lists sentences not to offer, such as the ones a practice
+	// session has already served.
+	Exclude []int
+}
+
+func (q CandidateQuery) source() CandidateSource {
+	if q.Source == "" {
+		return SourceAll
+	}
+	return q.Source
+}
+
+// admits reports whether c, an unreported sentence, is a candidate for q:
+// it must match every filter. A review is due at now.
+//
+// Mastery only ends a sentence's place among the mistakes. Everywhere else
+// a mastered sentence stays a candidate, and its ever longer SM-2 interval
+// is what keeps it from coming back too soon.
+func (q CandidateQuery) admits(c reviewCandidate, p ReviewPolicy, now time.Time) bool {
+	s := c.sentence
+	if len(q.Levels) > 0 && !slices.Contains(q.Levels, s.Level) {
+		return false
+	}
+	if slices.Contains(q.Exclude, s.ID) {
+		return false
+	}
+	switch q.source() {
+	case SourceNew:
+		return !c.seen
+	case SourceSeen:
+		return c.seen
+	case SourceReview:
+		return c.seen && !c.state.DueAt.After(now)
+	case SourceMistakes:
+		return s.IncorrectCount > 0 && !p.mastered(s.CorrectCount, s.IncorrectCount)
+	}
+	return true
+}
@@ -115,7 +115,7 @@ func TestWithCORSHandlesPreflightWithoutCallingNext(t *testing.T) {
 	if called {
 		t.Fatal("next handler should not be called for an OPTIONS preflight")
 	}
-	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization, X-Request-ID" {
 		t.Fatalf("expected Allow-Headers to include Authorization, got %q", got)
 	}
 }
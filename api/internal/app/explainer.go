@@ -10,6 +10,10 @@ import (
 // English translation to a reference translation of a Japanese sentence.
 type Explainer interface {
 	Explain(ctx context.Context, japanese, correctAnswer, userAnswer, language string) (string, error)
+	// ExplainStream produces the same explanation incrementally, passing
+	// each chunk to emit as the model generates it. An error from emit (the
+	// client went away) stops the stream and is returned.
+	ExplainStream(ctx context.Context, japanese, correctAnswer, userAnswer, language string, emit func(chunk string) error) error
 }
 
 // validExplainLanguages is the allow-list of languages an explanation can be
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+)
+
+// RandomCandidate needs every unreported sentence and the learner's stats
+// for each one they have answered. Reading both from their collections on
+// every request costs a read per sentence plus a read per answered
+// sentence. Instead, the sentences are cached in process as a catalog, and
+// each learner's stats are mirrored into a single candidate index document,
+// so a request reads two documents: the catalog's version and the index.
+
+// catalogVersionPath is the document whose version field is bumped by every
+// write that changes which sentences are candidates or what they show: an
+// admin edit, a report, cmd/seed. Instances compare it with the version of
+// the catalog they hold.
+const catalogVersionPath = "meta/sentence_catalog"
+
+type catalogVersionDoc struct {
+	Version int64 `firestore:"version"`
+}
+
+// bumpCatalogVersion returns the write that invalidates every instance's
+// catalog, to be made in the same transaction as the change itself.
+func bumpCatalogVersion(tx *firestore.Transaction, client *firestore.Client) error {
+	return tx.Set(client.Doc(catalogVersionPath), map[string]any{"version": firestore.Increment(1)}, firestore.MergeAll)
+}
+
+// InvalidateFirestoreCatalog makes every server reload the sentence catalog
+// before its next question. cmd/seed calls it after writing sentences
+// directly.
+func InvalidateFirestoreCatalog(ctx context.Context, client *firestore.Client) error {
+	_, err := client.Doc(catalogVersionPath).Set(ctx, map[string]any{"version": firestore.Increment(1)}, firestore.MergeAll)
+	return err
+}
+
+// catalogSentence is a cached unreported sentence.
+type catalogSentence struct {
+	id int
+	sentenceDoc
+}
+
+// sentenceCatalog is the in-process cache of unreported sentences, valid
+// while the stored catalog version matches version.
+type sentenceCatalog struct {
+	mu        sync.RWMutex
+	loaded    bool
+	version   int64
+	sentences []catalogSentence
+}
+
+// get returns the sentences, reloading them when the catalog is empty,
+// invalidated or older than version. Reading version before the sentences
+// means a change landing in between is picked up on the next call.
+func (c *sentenceCatalog) get(ctx context.Context, client *firestore.Client, version int64) ([]catalogSentence, error) {
+	c.mu.RLock()
+	if c.loaded && c.version == version {
+		defer c.mu.RUnlock()
+		return c.sentences, nil
+	}
+	c.mu.RUnlock()
+
+	docs, err := client.Collection("sentences").Where("is_reported", "==", false).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sentences := make([]catalogSentence, 0, len(docs))
+	for _, ds := range docs {
+		id, convErr := strconv.Atoi(ds.Ref.ID)
+		if convErr != nil {
+			continue
+		}
+		cs := catalogSentence{id: id}
+		if err := ds.DataTo(&cs.sentenceDoc); err != nil {
+			return nil, err
+		}
+		sentences = append(sentences, cs)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded, c.version, c.sentences = true, version, sentences
+	return sentences, nil
+}
+
+// invalidate drops the cached sentences, for writes made by this instance;
+// other instances notice the version bump that accompanies them.
+func (c *sentenceCatalog) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded, c.sentences = false, nil
+}
+
+// candidateIndexDoc, at users/{uid}/indexes/candidates, mirrors every
+// sentence_stats doc of the learner, keyed by sentence ID. RecordAnswer and
+// OverrideAnswer keep it current in the transactions that change the stats.
+// At roughly 150 bytes an entry, Firestore's 1 MiB document limit holds
+// several thousand answered sentences.
+type candidateIndexDoc struct {
+	// Complete is set once the index has been built from the stats docs.
+	// Until then it may hold only the entries written since it was
+	// introduced, and is rebuilt before use.
+	Complete bool                `firestore:"complete"`
+	Stats    map[string]statsDoc `firestore:"stats"`
+}
+
+func (r *firestoreRepo) candidateIndex(uid string) *firestore.DocumentRef {
+	return r.client.Collection("users").Doc(uid).Collection("indexes").Doc("candidates")
+}
+
+// setIndexEntry returns the write that mirrors one sentence's new stats
+// into uid's candidate index.
+func (r *firestoreRepo) setIndexEntry(tx *firestore.Transaction, uid string, id int, entry any) error {
+	return tx.Set(r.candidateIndex(uid), map[string]any{
+		"stats": map[string]any{strconv.Itoa(id): entry},
+	}, firestore.MergeAll)
+}
+
+// buildCandidateIndex reads every stats doc of uid into a complete index,
+// once per learner who answered before the index existed.
+func (r *firestoreRepo) buildCandidateIndex(ctx context.Context, uid string) (map[string]statsDoc, error) {
+	var stats map[string]statsDoc
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(r.userStats(uid)).GetAll()
+		if err != nil {
+			return err
+		}
+		stats = make(map[string]statsDoc, len(docs))
+		for _, ds := range docs {
+			var st statsDoc
+			if err := ds.DataTo(&st); err != nil {
+				return err
+			}
+			stats[ds.Ref.ID] = st
+		}
+		return tx.Set(r.candidateIndex(uid), candidateIndexDoc{Complete: true, Stats: stats})
+	})
+	return stats, err
+}
+
+// candidateInputs returns the catalog and uid's stats by sentence ID,
+// reading the catalog version and the index in one round trip.
+func (r *firestoreRepo) candidateInputs(ctx context.Context, uid string) ([]catalogSentence, map[string]statsDoc, error) {
+	snaps, err := r.client.GetAll(ctx, []*firestore.DocumentRef{r.client.Doc(catalogVersionPath), r.candidateIndex(uid)})
+	if err != nil {
+		return nil, nil, err
+	}
+	var version catalogVersionDoc
+	if snaps[0].Exists() {
+		if err := snaps[0].DataTo(&version); err != nil {
+			return nil, nil, err
+		}
+	}
+	var index candidateIndexDoc
+	if snaps[1].Exists() {
+		if err := snaps[1].DataTo(&index); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !index.Complete {
+		if index.Stats, err = r.buildCandidateIndex(ctx, uid); err != nil {
+			return nil, nil, err
+		}
+	}
+	sentences, err := r.catalog.get(ctx, r.client, version.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sentences, index.Stats, nil
+}
@@ -0,0 +1,455 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Listing mistakes from sentence_stats takes a sentences read and a
+// histories query per mistaken sentence. Instead, each learner has a
+// mistakes collection holding, per sentence they have missed, a copy of the
+// sentence and their wrong answers, so a list is one query.
+//
+// RecordAnswer and OverrideAnswer keep a learner's copies current in the
+// transactions that change their history, and UpdateSentence and
+// DeleteSentence fan sentence changes out to every learner's copy. Learners
+// who answered before the collection existed are backfilled the first time
+// they list their mistakes, or ahead of time by cmd/backfill-mistakes.
+
+// mistakeDoc, at users/{uid}/mistakes/{sentence ID}, exists while the
+// learner has at least one wrong answer to the sentence. At roughly 150
+// bytes a wrong answer, Firestore's 1 MiB document limit holds several
+// thousand misses of one sentence.
+type mistakeDoc struct {
+	SentenceID int    `firestore:"sentence_id"`
+	Japanese   string `firestore:"japanese"`
+	English    string `firestore:"english"`
+	Level      int    `firestore:"level"`
+	// CorrectCount and IncorrectCount mirror the sentence_stats doc, for
+	// filtering and sorting.
+	CorrectCount   int `firestore:"correct_count"`
+	IncorrectCount int `firestore:"incorrect_count"`
+	// LastMissedAt is the newest wrong answer's created_at, which lists
+	// are ordered by.
+	LastMissedAt time.Time `firestore:"last_missed_at"`
+	// WrongAnswers are every wrong answer, newest first.
+	WrongAnswers []historyDoc `firestore:"wrong_answers"`
+}
+
+func newMistakeDoc(id int, sd sentenceDoc) *mistakeDoc {
+	return &mistakeDoc{SentenceID: id, Japanese: sd.Japanese, English: sd.English, Level: sd.Level}
+}
+
+// add records a wrong answer, keeping WrongAnswers newest first.
+func (md *mistakeDoc) add(hd historyDoc) {
+	i, _ := slices.BinarySearchFunc(md.WrongAnswers, hd.CreatedAt, func(e historyDoc, t time.Time) int {
+		return t.Compare(e.CreatedAt)
+	})
+	md.WrongAnswers = slices.Insert(md.WrongAnswers, i, hd)
+	md.LastMissedAt = md.WrongAnswers[0].CreatedAt
+}
+
+// remove drops the wrong answer with the given AnswerHistory.ID.
+func (md *mistakeDoc) remove(historyID int64) {
+	md.WrongAnswers = slices.DeleteFunc(md.WrongAnswers, func(e historyDoc) bool {
+		return e.historyID() == historyID
+	})
+	if len(md.WrongAnswers) > 0 {
+		md.LastMissedAt = md.WrongAnswers[0].CreatedAt
+	}
+}
+
+// mistakeSentence converts the doc, keeping at most limit wrong answers;
+// limit <= 0 keeps them all.
+func (md *mistakeDoc) mistakeSentence(limit int) MistakeSentence {
+	wrong := md.WrongAnswers
+	if limit > 0 && len(wrong) > limit {
+		wrong = wrong[:limit]
+	}
+	answers := make([]AnswerHistory, len(wrong))
+	for i, hd := range wrong {
+		answers[i] = hd.answerHistory()
+	}
+	return md.summary().mistakeSentence(md.Japanese, md.English, answers)
+}
+
+func (md *mistakeDoc) summary() mistakeSummary {
+	return mistakeSummary{
+		id:           md.SentenceID,
+		level:        md.Level,
+		correct:      md.CorrectCount,
+		incorrect:    md.IncorrectCount,
+		lastMissedAt: md.LastMissedAt,
+	}
+}
+
+// mistakesIndexDoc, at users/{uid}/indexes/mistakes, marks a learner whose
+// mistakes collection has been backfilled, by the mistakesVersion it was
+// backfilled at.
+type mistakesIndexDoc struct {
+	Version int `firestore:"version"`
+}
+
+// mistakesVersion is bumped whenever mistake docs gain fields, so learners
+// are backfilled again to fill them in.
+const mistakesVersion = 1
+
+func (r *firestoreRepo) userMistakes(uid string) *firestore.CollectionRef {
+	return r.client.Collection("users").Doc(uid).Collection("mistakes")
+}
+
+func (r *firestoreRepo) mistakesIndex(uid string) *firestore.DocumentRef {
+	return r.client.Collection("users").Doc(uid).Collection("indexes").Doc("mistakes")
+}
+
+// buildMistake reads, in tx, a sentence's copy and uid's wrong answers to it
+// from their histories. It returns nil for a deleted sentence.
+func (r *firestoreRepo) buildMistake(tx *firestore.Transaction, uid string, id int) (*mistakeDoc, error) {
+	ds, err := tx.Get(r.client.Collection("sentences").Doc(strconv.Itoa(id)))
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sd sentenceDoc
+	if err := ds.DataTo(&sd); err != nil {
+		return nil, err
+	}
+	md := newMistakeDoc(id, sd)
+	statsRef := r.userStats(uid).Doc(strconv.Itoa(id))
+	ss, err := tx.Get(statsRef)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if ss.Exists() {
+		var st statsDoc
+		if err := ss.DataTo(&st); err != nil {
+			return nil, err
+		}
+		md.CorrectCount, md.IncorrectCount = st.CorrectCount, st.IncorrectCount
+	}
+	docs, err := tx.Documents(statsRef.Collection("histories").
+		Where("is_correct", "==", false).
+		OrderBy("created_at", firestore.Desc)).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, hs := range docs {
+		var hd historyDoc
+		if err := hs.DataTo(&hd); err != nil {
+			return nil, err
+		}
+		md.WrongAnswers = append(md.WrongAnswers, hd)
+	}
+	if len(md.WrongAnswers) > 0 {
+		md.LastMissedAt = md.WrongAnswers[0].CreatedAt
+	}
+	return md, nil
+}
+
+// loadMistake reads, in tx, uid's mistake doc for sentence id before a change
+// to their history. missedBefore says whether they may have wrong answers
+// predating the collection, which a missing doc is then built from; with
+// it false a missing doc starts empty. It returns nil for a deleted sentence.
+func (r *firestoreRepo) loadMistake(tx *firestore.Transaction, uid string, id int, missedBefore bool) (*mistakeDoc, error) {
+	ds, err := tx.Get(r.userMistakes(uid).Doc(strconv.Itoa(id)))
+	if err == nil {
+		var md mistakeDoc
+		if err := ds.DataTo(&md); err != nil {
+			return nil, err
+		}
+		return &md, nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if missedBefore {
+		return r.buildMistake(tx, uid, id)
+	}
+	sds, err := tx.Get(r.client.Collection("sentences").Doc(strconv.Itoa(id)))
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sd sentenceDoc
+	if err := sds.DataTo(&sd); err != nil {
+		return nil, err
+	}
+	return newMistakeDoc(id, sd), nil
+}
+
+// saveMistake returns the write storing md in tx, deleting the doc once it
+// has no wrong answers left. A nil md is a deleted sentence and writes
+// nothing.
+func (r *firestoreRepo) saveMistake(tx *firestore.Transaction, uid string, md *mistakeDoc) error {
+	if md == nil {
+		return nil
+	}
+	ref := r.userMistakes(uid).Doc(strconv.Itoa(md.SentenceID))
+	if len(md.WrongAnswers) == 0 {
+		return tx.Delete(ref)
+	}
+	return tx.Set(ref, md)
+}
+
+// BackfillMistakes rebuilds uid's mistakes collection from their
+// sentence_stats, one sentence per transaction so answers recorded
+// meanwhile are not lost, and marks it complete. It returns how many
+// mistaken sentences it wrote.
+func (r *firestoreRepo) BackfillMistakes(ctx context.Context, uid string) (int, error) {
+	count := 0
+	it := r.userStats(uid).Where("incorrect_count", ">", 0).Documents(ctx)
+	for {
+		ds, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		id, convErr := strconv.Atoi(ds.Ref.ID)
+		if convErr != nil {
+			continue
+		}
+		written := false
+		err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			ref := r.userMistakes(uid).Doc(ds.Ref.ID)
+			// Reading the doc makes a concurrent RecordAnswer retry one
+			// side rather than overwrite the other.
+			if _, err := tx.Get(ref); err != nil && status.Code(err) != codes.NotFound {
+				return err
+			}
+			md, err := r.buildMistake(tx, uid, id)
+			if err != nil {
+				return err
+			}
+			if md == nil {
+				return tx.Delete(ref)
+			}
+			written = len(md.WrongAnswers) > 0
+			if err := r.saveMistake(tx, uid, md); err != nil {
+				return err
+			}
+			return tx.Set(ds.Ref, map[string]any{"mistake_synced": true}, firestore.MergeAll)
+		})
+		if err != nil {
+			return count, err
+		}
+		if written {
+			count++
+		}
+	}
+	_, err := r.mistakesIndex(uid).Set(ctx, mistakesIndexDoc{Version: mistakesVersion})
+	return count, err
+}
+
+// ensureMistakes backfills uid's mistakes collection unless it is current.
+func (r *firestoreRepo) ensureMistakes(ctx context.Context, uid string) error {
+	ds, err := r.mistakesIndex(uid).Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	if ds.Exists() {
+		var idx mistakesIndexDoc
+		if err := ds.DataTo(&idx); err != nil {
+			return err
+		}
+		if idx.Version >= mistakesVersion {
+			return nil
+		}
+	}
+	_, err = r.BackfillMistakes(ctx, uid)
+	return err
+}
+
+// listMistakes backs ListMistakesForInsight: uid's mistakes, most recently
+// missed first, each with at most historyLimit wrong answers and at most
+// scanLimit of them. Limits <= 0 leave the list unbounded.
+func (r *firestoreRepo) listMistakes(ctx context.Context, uid string, historyLimit, scanLimit int) ([]MistakeSentence, error) {
+	if err := r.ensureMistakes(ctx, uid); err != nil {
+		return nil, err
+	}
+	q := r.userMistakes(uid).OrderBy("last_missed_at", firestore.Desc)
+	if scanLimit > 0 {
+		q = q.Limit(scanLimit)
+	}
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	mistakes := make([]MistakeSentence, 0, len(docs))
+	for _, ds := range docs {
+		var md mistakeDoc
+		if err := ds.DataTo(&md); err != nil {
+			return nil, err
+		}
+		mistakes = append(mistakes, md.mistakeSentence(historyLimit))
+	}
+	return mistakes, nil
+}
+
+func (r *firestoreRepo) ListMistakes(ctx context.Context, uid string, q MistakeQuery) ([]MistakeSentence, string, error) {
+	if err := r.ensureMistakes(ctx, uid); err != nil {
+		return nil, "", err
+	}
+	if q.sort() == MistakesByRecent {
+		return r.recentMistakes(ctx, uid, q)
+	}
+	return r.sortedMistakes(ctx, uid, q)
+}
+
+// recentMistakes walks uid's mistakes in last_missed_at order, reading only
+// as far as the page needs.
+func (r *firestoreRepo) recentMistakes(ctx context.Context, uid string, q MistakeQuery) ([]MistakeSentence, string, error) {
+	after, ok, err := q.start()
+	if err != nil {
+		return nil, "", err
+	}
+	query := r.userMistakes(uid).OrderBy("last_missed_at", firestore.Desc)
+	if !q.Since.IsZero() {
+		query = query.Where("last_missed_at", ">=", q.Since)
+	}
+	if ok {
+		query = query.StartAt(after.lastMissedAt)
+	}
+	it := query.Documents(ctx)
+	defer it.Stop()
+	var page []mistakeSummary
+	docs := map[int]*mistakeDoc{}
+	for {
+		ds, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		md := &mistakeDoc{}
+		if err := ds.DataTo(md); err != nil {
+			return nil, "", err
+		}
+		m := md.summary()
+		// Past a full page, read on only through mistakes missed at the
+		// same time as its last one: Firestore orders those by document
+		// ID, not as MistakeQuery.compare does.
+		if q.Limit > 0 && len(page) > q.Limit && !m.lastMissedAt.Equal(page[len(page)-1].lastMissedAt) {
+			break
+		}
+		if !q.matches(m, r.policy) || (ok && q.compare(after, m) >= 0) {
+			continue
+		}
+		page = append(page, m)
+		docs[m.id] = md
+	}
+	slices.SortFunc(page, q.compare)
+	page, next := q.cut(page)
+	mistakes := make([]MistakeSentence, 0, len(page))
+	for _, m := range page {
+		mistakes = append(mistakes, docs[m.id].mistakeSentence(0))
+	}
+	return mistakes, next, nil
+}
+
+// sortedMistakes orders all of uid's mistakes in memory, reading only what
+// they are sorted by, and then the page's docs in full.
+func (r *firestoreRepo) sortedMistakes(ctx context.Context, uid string, q MistakeQuery) ([]MistakeSentence, string, error) {
+	query := r.userMistakes(uid).Select("sentence_id", "level", "correct_count", "incorrect_count", "last_missed_at")
+	if !q.Since.IsZero() {
+		query = query.Where("last_missed_at", ">=", q.Since)
+	}
+	summaries, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, "", err
+	}
+	all := make([]mistakeSummary, 0, len(summaries))
+	for _, ds := range summaries {
+		var md mistakeDoc
+		if err := ds.DataTo(&md); err != nil {
+			return nil, "", err
+		}
+		all = append(all, md.summary())
+	}
+	page, next, err := q.page(all, r.policy)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(page) == 0 {
+		return []MistakeSentence{}, "", nil
+	}
+	refs := make([]*firestore.DocumentRef, len(page))
+	for i, m := range page {
+		refs[i] = r.userMistakes(uid).Doc(strconv.Itoa(m.id))
+	}
+	snaps, err := r.client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, "", err
+	}
+	mistakes := make([]MistakeSentence, 0, len(snaps))
+	for _, ds := range snaps {
+		// Overridden away since the summaries were read.
+		if !ds.Exists() {
+			continue
+		}
+		var md mistakeDoc
+		if err := ds.DataTo(&md); err != nil {
+			return nil, "", err
+		}
+		mistakes = append(mistakes, md.mistakeSentence(0))
+	}
+	return mistakes, next, nil
+}
+
+// syncMistakeCopies runs rewriteMistakeCopies after a sentence change has
+// committed. The copies are eventually consistent: a failure here is logged
+// rather than returned, since the change itself succeeded, and the stale
+// copies are refreshed by rerunning cmd/backfill-mistakes.
+func (r *firestoreRepo) syncMistakeCopies(ctx context.Context, id int, updates []firestore.Update) {
+	if err := r.rewriteMistakeCopies(ctx, id, updates); err != nil {
+		slog.ErrorContext(ctx, "rewrite mistake copies error", "sentence_id", id, "err", err)
+	}
+}
+
+// rewriteMistakeCopies applies updates to every learner's copy of sentence
+// id, or deletes the copies when updates is nil. Copies are found with a
+// collection group query on sentence_id (see firestore.indexes.json).
+func (r *firestoreRepo) rewriteMistakeCopies(ctx context.Context, id int, updates []firestore.Update) error {
+	docs, err := r.client.CollectionGroup("mistakes").Where("sentence_id", "==", id).Documents(ctx).GetAll()
+	if err != nil || len(docs) == 0 {
+		return err
+	}
+	bw := r.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+	for _, ds := range docs {
+		var job *firestore.BulkWriterJob
+		if updates == nil {
+			job, err = bw.Delete(ds.Ref)
+		} else {
+			job, err = bw.Update(ds.Ref, updates)
+		}
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+	for _, job := range jobs {
+		// A learner's last wrong answer may have been overridden meanwhile.
+		if _, err := job.Results(); err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMistakeDocKeepsWrongAnswersNewestFirst(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	md := &mistakeDoc{SentenceID: 1}
+	for _, min := range []int{1, 3, 2} {
+		md.add(historyDoc{IncorrectAnswer: "wrong", CreatedAt: base.Add(time.Duration(min) * time.Minute)})
+	}
+	if !md.LastMissedAt.Equal(base.Add(3*time.Minute)) || !md.WrongAnswers[1].CreatedAt.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("expected wrong answers newest first, got %+v", md.WrongAnswers)
+	}
+
+	md.remove(md.WrongAnswers[0].historyID())
+	if len(md.WrongAnswers) != 2 || !md.LastMissedAt.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("expected the newest answer removed and last_missed_at moved back, got %+v", md)
+	}
+	if got := md.mistakeSentence(1); len(got.WrongAnswers) != 1 {
+		t.Fatalf("expected the limit applied, got %+v", got)
+	}
+}
+
+func TestFirestoreListMistakesBackfillsLearnersFromBeforeTheIndex(t *testing.T) {
+	ctx := context.Background()
+	client := newEmulatorClient(t)
+	repo := NewFirestoreRepo(client)
+	uid := "user-legacy"
+	seedSentence(t, client, "11", "1", "A", "A-en", 1, false)
+	seedSentence(t, client, "12", "1", "B", "B-en", 1, false)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []int{11, 12, 11} {
+		repo.now = func() time.Time { return base.Add(time.Duration(i) * time.Minute) }
+		if _, err := repo.RecordAnswer(ctx, uid, id, AnswerRecord{Answer: "wrong"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Drop what RecordAnswer maintained, leaving only the stats and
+	// histories a learner had before the mistakes collection existed.
+	docs, err := repo.userMistakes(uid).Documents(ctx).GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ds := range docs {
+		if _, err := ds.Ref.Delete(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := repo.userStats(uid).Documents(ctx).GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ds := range stats {
+		if _, err := ds.Ref.Update(ctx, []firestore.Update{{Path: "mistake_synced", Value: firestore.Delete}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.mistakesIndex(uid).Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new miss of 12 must carry its earlier one along.
+	repo.now = func() time.Time { return base.Add(time.Hour) }
+	if _, err := repo.RecordAnswer(ctx, uid, 12, AnswerRecord{Answer: "wrong again"}); err != nil {
+		t.Fatal(err)
+	}
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mistakes) != 2 || mistakes[0].SentenceID != 12 || mistakes[1].SentenceID != 11 {
+		t.Fatalf("expected sentences [12 11], got %+v", mistakes)
+	}
+	if len(mistakes[0].WrongAnswers) != 2 || len(mistakes[1].WrongAnswers) != 2 {
+		t.Fatalf("expected every earlier wrong answer backfilled, got %+v", mistakes)
+	}
+}
+
+// TestFirestoreRecordAnswerTrustsSyncedMistakes guards against rebuilding a
+// mistake doc from the histories on every answer once its wrong answers have
+// been cleared: after that, a missing doc means there is nothing to rebuild.
+func TestFirestoreRecordAnswerTrustsSyncedMistakes(t *testing.T) {
+	ctx := context.Background()
+	client := newEmulatorClient(t)
+	repo := NewFirestoreRepo(client)
+	uid := "user-synced"
+	seedSentence(t, client, "31", "1", "A", "A-en", 1, false)
+	if _, err := repo.RecordAnswer(ctx, uid, 31, AnswerRecord{Answer: "wrong"}); err != nil {
+		t.Fatal(err)
+	}
+	// Drop the doc while the stats still count the miss.
+	if _, err := repo.userMistakes(uid).Doc("31").Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RecordAnswer(ctx, uid, 31, AnswerRecord{Correct: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.userMistakes(uid).Doc("31").Get(ctx); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected no mistake doc rebuilt from the histories, got %v", err)
+	}
+}
+
+func TestFirestoreUpdateSentenceRefreshesMistakeCopies(t *testing.T) {
+	ctx := context.Background()
+	client := newEmulatorClient(t)
+	repo := NewFirestoreRepo(client)
+	seedSentence(t, client, "21", "1", "A", "A-en", 1, false)
+	for _, uid := range []string{"user-a", "user-b"} {
+		if _, err := repo.RecordAnswer(ctx, uid, 21, AnswerRecord{Answer: "wrong"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateSentence(ctx, 21, SentenceEdit{Japanese: "A2", English: "A2-en", Page: "1", Level: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, uid := range []string{"user-a", "user-b"} {
+		mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != 1 || mistakes[0].Japanese != "A2" || mistakes[0].CorrectAnswer != "A2-en" {
+			t.Fatalf("expected %s's copy edited, got %+v", uid, mistakes)
+		}
+	}
+}
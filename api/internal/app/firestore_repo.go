@@ -3,9 +3,10 @@ package app
 import (
 	"context"
 	"errors"
-	"math/rand"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -15,12 +16,23 @@ import (
 )
 
 type firestoreRepo struct {
-	client *firestore.Client
-	now    func() time.Time
+	client       *firestore.Client
+	now          func() time.Time
+	policy       ReviewPolicy
+	reportPolicy ReportPolicy
+	catalog      *sentenceCatalog
 }
 
 func NewFirestoreRepo(client *firestore.Client) *firestoreRepo {
-	return &firestoreRepo{client: client, now: time.Now}
+	return &firestoreRepo{client: client, now: time.Now, policy: DefaultReviewPolicy(), catalog: &sentenceCatalog{}}
+}
+
+func (r *firestoreRepo) SetReviewPolicy(p ReviewPolicy) {
+	r.policy = p
+}
+
+func (r *firestoreRepo) SetReportPolicy(p ReportPolicy) {
+	r.reportPolicy = p
 }
 
 type sentenceDoc struct {
@@ -31,104 +43,285 @@ type sentenceDoc struct {
 	IsReported bool   `firestore:"is_reported"`
 	CreatedAt  string `firestore:"created_at"`
 	UpdatedAt  string `firestore:"updated_at"`
+	// AcceptedAnswers are alternative translations graded as correct in
+	// addition to English. Absent on sentences seeded before alternatives
+	// existed.
+	AcceptedAnswers []string `firestore:"accepted_answers"`
+	// ReportedBy lists the learners with a report in the reports
+	// subcollection, so RandomCandidate can skip their reported sentences
+	// without further reads; ReportCount is its length, which ListReported
+	// can query on.
+	ReportedBy  []string `firestore:"reported_by"`
+	ReportCount int      `firestore:"report_count"`
+}
+
+// reportDoc is one learner's report, stored at sentences/{id}/reports/{uid}.
+type reportDoc struct {
+	UID       string    `firestore:"uid"`
+	Reason    string    `firestore:"reason"`
+	Comment   string    `firestore:"comment"`
+	CreatedAt time.Time `firestore:"created_at"`
+}
+
+func (rd reportDoc) sentenceReport() SentenceReport {
+	return SentenceReport{
+		UID:       rd.UID,
+		Reason:    rd.Reason,
+		Comment:   rd.Comment,
+		CreatedAt: rd.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// addReporter returns the reporters with uid added once.
+func (sd sentenceDoc) addReporter(uid string) []string {
+	if slices.Contains(sd.ReportedBy, uid) {
+		return sd.ReportedBy
+	}
+	return append(slices.Clone(sd.ReportedBy), uid)
+}
+
+func (sd sentenceDoc) adminSentence(id int) AdminSentence {
+	return AdminSentence{
+		ID:         id,
+		Japanese:   sd.Japanese,
+		English:    sd.English,
+		Page:       sd.Page,
+		Level:      sd.Level,
+		IsReported: sd.IsReported,
+		CreatedAt:  sd.CreatedAt,
+		UpdatedAt:  sd.UpdatedAt,
+		Reports:    []SentenceReport{},
+	}
+}
+
+// acceptedAnswers is English followed by the alternatives, skipping any
+// that normalize to an answer already listed.
+func (sd sentenceDoc) acceptedAnswers() []string {
+	answers := []string{sd.English}
+	seen := map[string]bool{strings.ToLower(normalizeAnswer(sd.English)): true}
+	for _, a := range sd.AcceptedAnswers {
+		key := strings.ToLower(normalizeAnswer(a))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		answers = append(answers, a)
+	}
+	return answers
+}
+
+// addAlternative appends answer to the alternatives unless it is blank or
+// already normalizes to an accepted answer, and reports whether it did.
+func (sd *sentenceDoc) addAlternative(answer string) bool {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return false
+	}
+	if _, ok := matchAnswer(answer, sd.acceptedAnswers()); ok {
+		return false
+	}
+	sd.AcceptedAnswers = append(sd.AcceptedAnswers, answer)
+	return true
+}
+
+// cacheDoc is one generation_cache entry.
+type cacheDoc struct {
+	Text      string    `firestore:"text"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+func newCacheDoc(text string, ttl time.Duration, now time.Time) cacheDoc {
+	return cacheDoc{Text: text, ExpiresAt: now.Add(ttl).UTC()}
+}
+
+func (cd cacheDoc) fresh(now time.Time) bool {
+	return now.Before(cd.ExpiresAt)
 }
 
 type statsDoc struct {
 	CorrectCount   int `firestore:"correct_count"`
 	IncorrectCount int `firestore:"incorrect_count"`
+	// Spaced-repetition state (see ReviewState). Docs written before
+	// scheduling existed lack these fields and decode to the zero values.
+	DueAt           time.Time `firestore:"due_at"`
+	Ease            float64   `firestore:"ease"`
+	IntervalDays    int       `firestore:"interval_days"`
+	Reps            int       `firestore:"reps"`
+	FirstReviewedAt time.Time `firestore:"first_reviewed_at"`
+	LastReviewedAt  time.Time `firestore:"last_reviewed_at"`
+	// LastHistoryID is the ID given to the newest history doc.
+	LastHistoryID int64 `firestore:"last_history_id"`
+	// MistakeSynced is set once the learner's mistake doc for the sentence
+	// is kept in step with these stats, so a missing one means no wrong
+	// answers are outstanding rather than some predating the collection.
+	MistakeSynced bool `firestore:"mistake_synced"`
+}
+
+// missedBefore reports whether the learner may have wrong answers to the
+// sentence that no mistake doc records, for loadMistake.
+func (st statsDoc) missedBefore() bool {
+	return st.IncorrectCount > 0 && !st.MistakeSynced
+}
+
+// nextHistoryID returns a new history doc's ID: its creation time in Unix
+// microseconds, bumped past LastHistoryID so two answers recorded in the
+// same microsecond still get distinct IDs.
+func (st *statsDoc) nextHistoryID(now time.Time) int64 {
+	id := max(now.UnixMicro(), st.LastHistoryID+1)
+	st.LastHistoryID = id
+	return id
+}
+
+func (st statsDoc) reviewState() ReviewState {
+	return ReviewState{
+		DueAt:           st.DueAt,
+		Ease:            st.Ease,
+		IntervalDays:    st.IntervalDays,
+		Reps:            st.Reps,
+		FirstReviewedAt: st.FirstReviewedAt,
+		LastReviewedAt:  st.LastReviewedAt,
+	}
+}
+
+func (st *statsDoc) setReviewState(rs ReviewState) {
+	st.DueAt = rs.DueAt
+	st.Ease = rs.Ease
+	st.IntervalDays = rs.IntervalDays
+	st.Reps = rs.Reps
+	st.FirstReviewedAt = rs.FirstReviewedAt
+	st.LastReviewedAt = rs.LastReviewedAt
 }
 
 type historyDoc struct {
+	// ID is the AnswerHistory.ID, and on Firestore the doc's own ID. Docs
+	// written before it was stored lack it; see historyID.
+	ID              int64     `firestore:"id"`
 	IsCorrect       bool      `firestore:"is_correct"`
 	IncorrectAnswer string    `firestore:"incorrect_answer"`
 	CreatedAt       time.Time `firestore:"created_at"`
+	GradedBy        string    `firestore:"graded_by"`
+	GraderReason    string    `firestore:"grader_reason"`
+	Overridden      bool      `firestore:"overridden"`
+}
+
+func newHistoryDoc(rec AnswerRecord, now time.Time) historyDoc {
+	gradedBy := rec.GradedBy
+	if gradedBy == "" {
+		gradedBy = GradedExact
+	}
+	return historyDoc{
+		IsCorrect:       rec.Correct,
+		IncorrectAnswer: rec.Answer,
+		CreatedAt:       now,
+		GradedBy:        gradedBy,
+		GraderReason:    rec.GraderReason,
+	}
+}
+
+// historyID is the AnswerHistory.ID of a history doc. Docs written before
+// IDs were stored are identified by their creation time in Unix
+// microseconds, which Firestore timestamps store exactly.
+func (hd historyDoc) historyID() int64 {
+	if hd.ID != 0 {
+		return hd.ID
+	}
+	return hd.CreatedAt.UnixMicro()
+}
+
+func (hd historyDoc) answerHistory() AnswerHistory {
+	return AnswerHistory{
+		ID:              hd.historyID(),
+		IncorrectAnswer: hd.IncorrectAnswer,
+		CreatedAt:       hd.CreatedAt.UTC().Format(time.RFC3339Nano),
+		GradedBy:        hd.GradedBy,
+		GraderReason:    hd.GraderReason,
+		Overridden:      hd.Overridden,
+	}
+}
+
+// overridable reports whether the learner may change the entry's verdict to
+// correct: any rejection may have been a valid translation the references
+// missed, but an exact match cannot be wrong, so only a Grader's acceptance
+// can be disputed.
+func (hd historyDoc) overridable(correct bool) bool {
+	return correct || hd.GradedBy == GradedAI
+}
+
+// overrideDeltas are the correct_count and incorrect_count adjustments for
+// changing an entry's verdict to correct.
+func overrideDeltas(correct bool) (correctDelta, incorrectDelta int) {
+	if correct {
+		return 1, -1
+	}
+	return -1, 1
 }
 
 func (r *firestoreRepo) userStats(uid string) *firestore.CollectionRef {
 	return r.client.Collection("users").Doc(uid).Collection("sentence_stats")
 }
 
-func (r *firestoreRepo) RandomCandidate(ctx context.Context, uid string, levels []int) (*Sentence, error) {
-	sentenceDocs, err := r.client.Collection("sentences").
-		Where("is_reported", "==", false).Documents(ctx).GetAll()
+// RandomCandidate reads the catalog version and uid's candidate index, and
+// the sentences themselves only when the catalog has changed (see
+// firestore_catalog.go).
+func (r *firestoreRepo) RandomCandidate(ctx context.Context, uid string, q CandidateQuery) (*Sentence, error) {
+	sentences, stats, err := r.candidateInputs(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
-
-	stats := map[int]statsDoc{}
-	it := r.userStats(uid).Documents(ctx)
-	for {
-		ds, err := it.Next()
-		if errors.Is(err, iterator.Done) {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		id, convErr := strconv.Atoi(ds.Ref.ID)
-		if convErr != nil {
-			continue
-		}
-		var st statsDoc
-		if err := ds.DataTo(&st); err != nil {
-			return nil, err
-		}
-		stats[id] = st
-	}
-
-	wantLevels := map[int]bool{}
-	for _, lv := range levels {
-		wantLevels[lv] = true
+	states := make([]ReviewState, 0, len(stats))
+	for _, st := range stats {
+		states = append(states, st.reviewState())
 	}
 
-	var candidates []*Sentence
-	for _, ds := range sentenceDocs {
-		id, convErr := strconv.Atoi(ds.Ref.ID)
-		if convErr != nil {
+	now := r.now()
+	var candidates []reviewCandidate
+	for _, cs := range sentences {
+		id, sd := cs.id, cs.sentenceDoc
+		if slices.Contains(sd.ReportedBy, uid) {
 			continue
 		}
-		var sd sentenceDoc
-		if err := ds.DataTo(&sd); err != nil {
-			return nil, err
-		}
-		st := stats[id]
-		if st.CorrectCount-st.IncorrectCount >= 2 {
-			continue
+		st, seen := stats[strconv.Itoa(id)]
+		c := reviewCandidate{
+			sentence: &Sentence{
+				ID:             id,
+				Japanese:       sd.Japanese,
+				English:        sd.English,
+				Page:           sd.Page,
+				Level:          sd.Level,
+				CorrectCount:   st.CorrectCount,
+				IncorrectCount: st.IncorrectCount,
+				CreatedAt:      sd.CreatedAt,
+				UpdatedAt:      sd.UpdatedAt,
+			},
+			state: st.reviewState(),
+			seen:  seen,
 		}
-		if len(wantLevels) > 0 && !wantLevels[sd.Level] {
-			continue
+		if q.admits(c, r.policy, now) {
+			candidates = append(candidates, c)
 		}
-		candidates = append(candidates, &Sentence{
-			ID:             id,
-			Japanese:       sd.Japanese,
-			English:        sd.English,
-			Page:           sd.Page,
-			Level:          sd.Level,
-			CorrectCount:   st.CorrectCount,
-			IncorrectCount: st.IncorrectCount,
-			CreatedAt:      sd.CreatedAt,
-			UpdatedAt:      sd.UpdatedAt,
-		})
 	}
-	if len(candidates) == 0 {
+
+	newToday, reviewsToday := r.policy.dailyUsage(states, now)
+	s := r.policy.pick(candidates, newToday, reviewsToday, now)
+	if s == nil {
 		return nil, ErrNoCandidate
 	}
-	return candidates[rand.Intn(len(candidates))], nil
+	return s, nil
 }
 
-func (r *firestoreRepo) CorrectAnswer(ctx context.Context, id int) (string, error) {
+func (r *firestoreRepo) AcceptedAnswers(ctx context.Context, id int) ([]string, int, error) {
 	ds, err := r.client.Collection("sentences").Doc(strconv.Itoa(id)).Get(ctx)
 	if status.Code(err) == codes.NotFound {
-		return "", ErrNotFound
+		return nil, 0, ErrNotFound
 	}
 	if err != nil {
-		return "", err
+		return nil, 0, err
 	}
 	var sd sentenceDoc
 	if err := ds.DataTo(&sd); err != nil {
-		return "", err
+		return nil, 0, err
 	}
-	return sd.English, nil
+	return sd.acceptedAnswers(), sd.Level, nil
 }
 
 func (r *firestoreRepo) GetSentence(ctx context.Context, id int) (string, string, error) {
@@ -173,11 +366,7 @@ func (r *firestoreRepo) incorrectHistories(ctx context.Context, statsRef *firest
 		if err := ds.DataTo(&hd); err != nil {
 			return nil, err
 		}
-		histories = append(histories, AnswerHistory{
-			ID:              hd.CreatedAt.UnixMicro(),
-			IncorrectAnswer: hd.IncorrectAnswer,
-			CreatedAt:       hd.CreatedAt.UTC().Format(time.RFC3339Nano),
-		})
+		histories = append(histories, hd.answerHistory())
 	}
 	return histories, nil
 }
@@ -187,137 +376,493 @@ func (r *firestoreRepo) ListIncorrectHistories(ctx context.Context, uid string,
 }
 
 // maxInsightStatsScan bounds how many *mistaken* sentences the insight path
-// collects while scanning a user's sentence_stats docs, most-recently-touched
-// first. "Most-recently-touched" (ordered by updated_at, which every
-// RecordAnswer call bumps whether the attempt was correct or not) is an
-// approximation of "most recently mistaken" — good enough for a weakness
-// summary, and avoids requiring a composite index the way filtering on
-// incorrect_count while ordering by updated_at would.
+// collects. Firestore takes the most recently missed from the mistakes
+// collection (see firestore_mistakes.go). The memory and SQL repositories
+// scan a user's stats most-recently-touched first instead (ordered by
+// updated_at, which every RecordAnswer call bumps whether the attempt was
+// correct or not), an approximation of "most recently mistaken" that is
+// good enough for a weakness summary.
 const maxInsightStatsScan = 100
 
-// maxInsightStatsScanCeiling bounds, at the Firestore query level, the total
-// number of sentence_stats docs the insight path will examine while looking
+// maxInsightStatsScanCeiling bounds, in the memory and SQL repositories, the
+// total number of stats the insight path will examine while looking
 // for maxInsightStatsScan mistaken ones — a learner who mostly answers
 // correctly can have long runs of correct-only docs between mistakes, and
 // without this ceiling the scan for maxInsightStatsScan actual mistakes could
 // still degrade toward scanning their entire history.
 const maxInsightStatsScanCeiling = 500
 
-// listMistakes is the shared implementation behind ListMistakes and
-// ListMistakesForInsight; historyLimit is threaded straight into
-// incorrectHistories (see its doc comment for the limit<=0 convention).
-// scanLimit bounds the outer sentence_stats scan: the query itself is capped
-// at maxInsightStatsScanCeiling docs examined, and the loop stops early once
-// scanLimit *mistaken* sentences have been collected — so more-recently
-// -touched correct-only docs can't crowd real mistakes out of the result the
-// way a flat doc-count limit would. scanLimit<=0 leaves it unbounded.
-func (r *firestoreRepo) listMistakes(ctx context.Context, uid string, historyLimit, scanLimit int) ([]MistakeSentence, error) {
-	mistakes := make([]MistakeSentence, 0)
-	statsQuery := r.userStats(uid).Query
-	if scanLimit > 0 {
-		statsQuery = statsQuery.OrderBy("updated_at", firestore.Desc).Limit(maxInsightStatsScanCeiling)
-	}
-	it := statsQuery.Documents(ctx)
-	for {
-		if scanLimit > 0 && len(mistakes) >= scanLimit {
-			break
+// ListMistakesForInsight is the same as ListMistakes but caps each
+// sentence's wrong-answer history to maxWrongAnswersPerSentence and the
+// outer sentence scan to maxInsightStatsScan, both at the query level.
+// Backs GET /api/mistakes/insight only.
+func (r *firestoreRepo) ListMistakesForInsight(ctx context.Context, uid string) ([]MistakeSentence, error) {
+	return r.listMistakes(ctx, uid, maxWrongAnswersPerSentence, maxInsightStatsScan)
+}
+
+func (r *firestoreRepo) RecordAnswer(ctx context.Context, uid string, id int, rec AnswerRecord) (int64, error) {
+	now := r.now().UTC()
+	statsRef := r.userStats(uid).Doc(strconv.Itoa(id))
+	hd := newHistoryDoc(rec, now)
+
+	field := "incorrect_count"
+	if rec.Correct {
+		field = "correct_count"
+	}
+
+	// The counters could be bumped blind with Increment, but the schedule
+	// is computed from the previous review state, so the stats doc is read
+	// and rewritten in a transaction.
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var st statsDoc
+		ds, err := tx.Get(statsRef)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			if err := ds.DataTo(&st); err != nil {
+				return err
+			}
 		}
-		ds, err := it.Next()
-		if errors.Is(err, iterator.Done) {
-			break
+		hd.ID = st.nextHistoryID(now)
+		// A correct answer only changes the counts a mistake doc mirrors.
+		var md *mistakeDoc
+		if !rec.Correct || st.IncorrectCount > 0 {
+			if md, err = r.loadMistake(tx, uid, id, st.missedBefore()); err != nil {
+				return err
+			}
 		}
-		if err != nil {
-			return nil, err
+		// The mistake doc is in step once loaded here, or when no wrong answer
+		// could need one.
+		synced := st.MistakeSynced || md != nil || (rec.Correct && st.IncorrectCount == 0)
+		if md != nil && !rec.Correct {
+			md.add(hd)
 		}
-		id, convErr := strconv.Atoi(ds.Ref.ID)
-		if convErr != nil {
-			continue
+		rs := r.policy.advance(st.reviewState(), rec.Correct, now)
+		if rec.Correct {
+			st.CorrectCount++
+		} else {
+			st.IncorrectCount++
 		}
-		var st statsDoc
-		if err := ds.DataTo(&st); err != nil {
+		st.setReviewState(rs)
+		if md != nil {
+			md.CorrectCount, md.IncorrectCount = st.CorrectCount, st.IncorrectCount
+		}
+		if err := r.setIndexEntry(tx, uid, id, st); err != nil {
+			return err
+		}
+		if err := r.saveMistake(tx, uid, md); err != nil {
+			return err
+		}
+
+		if err := tx.Set(statsRef, map[string]interface{}{
+			field:               firestore.Increment(1),
+			"updated_at":        now.Format(time.RFC3339),
+			"due_at":            rs.DueAt,
+			"ease":              rs.Ease,
+			"interval_days":     rs.IntervalDays,
+			"reps":              rs.Reps,
+			"first_reviewed_at": rs.FirstReviewedAt,
+			"last_reviewed_at":  rs.LastReviewedAt,
+			"last_history_id":   st.LastHistoryID,
+			"mistake_synced":    synced,
+		}, firestore.MergeAll); err != nil {
+			return err
+		}
+		return tx.Set(r.historyRef(statsRef, hd.ID), hd)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return hd.historyID(), nil
+}
+
+// historyRef is the history doc with the given AnswerHistory.ID.
+func (r *firestoreRepo) historyRef(statsRef *firestore.DocumentRef, historyID int64) *firestore.DocumentRef {
+	return statsRef.Collection("histories").Doc(strconv.FormatInt(historyID, 10))
+}
+
+// getHistory reads the history doc with the given AnswerHistory.ID. Docs
+// written before IDs were stored have generated doc IDs and are found by
+// their created_at instead.
+func (r *firestoreRepo) getHistory(tx *firestore.Transaction, statsRef *firestore.DocumentRef, historyID int64) (*firestore.DocumentSnapshot, error) {
+	hs, err := tx.Get(r.historyRef(statsRef, historyID))
+	if err == nil {
+		return hs, nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	docs, err := tx.Documents(statsRef.Collection("histories").
+		Where("created_at", "==", time.UnixMicro(historyID).UTC())).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, ds := range docs {
+		var hd historyDoc
+		if err := ds.DataTo(&hd); err != nil {
 			return nil, err
 		}
-		if st.IncorrectCount == 0 {
-			continue
+		// A doc with a stored ID answers to that ID alone.
+		if hd.ID == 0 {
+			return ds, nil
+		}
+	}
+	return nil, ErrHistoryNotFound
+}
+
+func (r *firestoreRepo) OverrideAnswer(ctx context.Context, uid string, id int, historyID int64, ov AnswerOverride) error {
+	sentenceRef := r.client.Collection("sentences").Doc(strconv.Itoa(id))
+	statsRef := r.userStats(uid).Doc(strconv.Itoa(id))
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		hs, err := r.getHistory(tx, statsRef, historyID)
+		if err != nil {
+			return err
 		}
+		var hd historyDoc
+		if err := hs.DataTo(&hd); err != nil {
+			return err
+		}
+		if !hd.overridable(ov.Correct) {
+			return ErrNotOverridable
+		}
+		// Transactions must do every read before their first write.
+		var sd sentenceDoc
+		promote := false
+		if ov.Promote && ov.Correct {
+			snap, err := tx.Get(sentenceRef)
+			if status.Code(err) == codes.NotFound {
+				return ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if err := snap.DataTo(&sd); err != nil {
+				return err
+			}
+			promote = sd.addAlternative(hd.IncorrectAnswer)
+		}
+		var md *mistakeDoc
+		if hd.IsCorrect != ov.Correct {
+			ss, err := tx.Get(statsRef)
+			if err != nil {
+				return err
+			}
+			var st statsDoc
+			if err := ss.DataTo(&st); err != nil {
+				return err
+			}
+			if md, err = r.loadMistake(tx, uid, id, st.missedBefore()); err != nil {
+				return err
+			}
+		}
+		if promote {
+			if err := tx.Update(sentenceRef, []firestore.Update{
+				{Path: "accepted_answers", Value: sd.AcceptedAnswers},
+			}); err != nil {
+				return err
+			}
+		}
+		if hd.IsCorrect == ov.Correct {
+			return nil
+		}
+		correctDelta, incorrectDelta := overrideDeltas(ov.Correct)
+		if md != nil {
+			md.CorrectCount += correctDelta
+			md.IncorrectCount += incorrectDelta
+			if ov.Correct {
+				md.remove(hd.historyID())
+			} else {
+				hd.IsCorrect, hd.Overridden = false, true
+				md.add(hd)
+			}
+			if err := r.saveMistake(tx, uid, md); err != nil {
+				return err
+			}
+		}
+		if err := tx.Update(hs.Ref, []firestore.Update{
+			{Path: "is_correct", Value: ov.Correct},
+			{Path: "overridden", Value: true},
+		}); err != nil {
+			return err
+		}
+		if err := r.setIndexEntry(tx, uid, id, map[string]any{
+			"correct_count":   firestore.Increment(correctDelta),
+			"incorrect_count": firestore.Increment(incorrectDelta),
+		}); err != nil {
+			return err
+		}
+		updates := []firestore.Update{
+			{Path: "correct_count", Value: firestore.Increment(correctDelta)},
+			{Path: "incorrect_count", Value: firestore.Increment(incorrectDelta)},
+		}
+		if md != nil {
+			updates = append(updates, firestore.Update{Path: "mistake_synced", Value: true})
+		}
+		return tx.Update(statsRef, updates)
+	})
+}
 
-		sentDs, err := r.client.Collection("sentences").Doc(ds.Ref.ID).Get(ctx)
+func (r *firestoreRepo) Report(ctx context.Context, uid string, id int, rec ReportRecord) error {
+	sentenceRef := r.client.Collection("sentences").Doc(strconv.Itoa(id))
+	defer r.catalog.invalidate()
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		ds, err := tx.Get(sentenceRef)
 		if status.Code(err) == codes.NotFound {
-			continue
+			return ErrNotFound
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		var sd sentenceDoc
-		if err := sentDs.DataTo(&sd); err != nil {
-			return nil, err
+		if err := ds.DataTo(&sd); err != nil {
+			return err
+		}
+		reporters := sd.addReporter(uid)
+		updates := []firestore.Update{
+			{Path: "reported_by", Value: reporters},
+			{Path: "report_count", Value: len(reporters)},
+		}
+		if r.reportPolicy.hides(len(reporters)) {
+			updates = append(updates, firestore.Update{Path: "is_reported", Value: true})
+		}
+		if err := tx.Update(sentenceRef, updates); err != nil {
+			return err
+		}
+		if err := bumpCatalogVersion(tx, r.client); err != nil {
+			return err
 		}
+		return tx.Set(sentenceRef.Collection("reports").Doc(uid), reportDoc{
+			UID:       uid,
+			Reason:    rec.Reason,
+			Comment:   rec.Comment,
+			CreatedAt: r.now().UTC(),
+		})
+	})
+}
 
-		wrongAnswers, err := r.incorrectHistories(ctx, ds.Ref, historyLimit)
+// ListReported merges two single-field queries, since a sentence may be
+// flagged by cmd/seed without any reports, or have reports without being
+// hidden from everyone.
+func (r *firestoreRepo) ListReported(ctx context.Context) ([]AdminSentence, error) {
+	sentences := r.client.Collection("sentences")
+	flagged, err := sentences.Where("is_reported", "==", true).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	withReports, err := sentences.Where("report_count", ">", 0).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{}
+	out := make([]AdminSentence, 0, len(flagged)+len(withReports))
+	for _, ds := range append(flagged, withReports...) {
+		id, err := strconv.Atoi(ds.Ref.ID)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		var sd sentenceDoc
+		if err := ds.DataTo(&sd); err != nil {
+			return nil, err
+		}
+		s := sd.adminSentence(id)
+		reports, err := ds.Ref.Collection("reports").OrderBy("created_at", firestore.Asc).Documents(ctx).GetAll()
 		if err != nil {
 			return nil, err
 		}
-		if len(wrongAnswers) == 0 {
-			continue
+		for _, rs := range reports {
+			var rd reportDoc
+			if err := rs.DataTo(&rd); err != nil {
+				return nil, err
+			}
+			s.Reports = append(s.Reports, rd.sentenceReport())
 		}
-
-		mistakes = append(mistakes, MistakeSentence{
-			SentenceID:    id,
-			Japanese:      sd.Japanese,
-			CorrectAnswer: sd.English,
-			WrongAnswers:  wrongAnswers,
-		})
+		out = append(out, s)
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
 
-	sort.Slice(mistakes, func(i, j int) bool {
-		ti, _ := time.Parse(time.RFC3339, mistakes[i].WrongAnswers[0].CreatedAt)
-		tj, _ := time.Parse(time.RFC3339, mistakes[j].WrongAnswers[0].CreatedAt)
-		return ti.After(tj)
+// UpdateSentence commits the edit, then refreshes the learners' mistake
+// copies of the sentence (see syncMistakeCopies).
+func (r *firestoreRepo) UpdateSentence(ctx context.Context, id int, edit SentenceEdit) error {
+	sentenceRef := r.client.Collection("sentences").Doc(strconv.Itoa(id))
+	defer r.catalog.invalidate()
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(sentenceRef); status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if err := tx.Update(sentenceRef, []firestore.Update{
+			{Path: "japanese", Value: edit.Japanese},
+			{Path: "english", Value: edit.English},
+			{Path: "page", Value: edit.Page},
+			{Path: "level", Value: edit.Level},
+			{Path: "updated_at", Value: r.now().UTC().Format(time.RFC3339)},
+		}); err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, r.client)
 	})
-	return mistakes, nil
+	if err != nil {
+		return err
+	}
+	r.syncMistakeCopies(ctx, id, []firestore.Update{
+		{Path: "japanese", Value: edit.Japanese},
+		{Path: "english", Value: edit.English},
+		{Path: "level", Value: edit.Level},
+	})
+	return nil
 }
 
-// ListMistakes returns every sentence the user has ever answered
-// incorrectly, with its complete wrong-answer history, most recently missed
-// sentence first. Backs the raw /api/mistakes list.
-func (r *firestoreRepo) ListMistakes(ctx context.Context, uid string) ([]MistakeSentence, error) {
-	return r.listMistakes(ctx, uid, 0, 0)
+// clearReports deletes an existing sentence's reports and then runs fn on
+// the sentence, in one transaction that also invalidates the catalog.
+func (r *firestoreRepo) clearReports(ctx context.Context, id int, fn func(*firestore.Transaction, *firestore.DocumentRef) error) error {
+	sentenceRef := r.client.Collection("sentences").Doc(strconv.Itoa(id))
+	defer r.catalog.invalidate()
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		_, err := tx.Get(sentenceRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		reports, err := tx.Documents(sentenceRef.Collection("reports")).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, rs := range reports {
+			if err := tx.Delete(rs.Ref); err != nil {
+				return err
+			}
+		}
+		if err := bumpCatalogVersion(tx, r.client); err != nil {
+			return err
+		}
+		return fn(tx, sentenceRef)
+	})
 }
 
-// ListMistakesForInsight is the same as ListMistakes but caps each
-// sentence's wrong-answer history to maxWrongAnswersPerSentence and the
-// outer sentence scan to maxInsightStatsScan, both at the query level.
-// Backs GET /api/mistakes/insight only.
-func (r *firestoreRepo) ListMistakesForInsight(ctx context.Context, uid string) ([]MistakeSentence, error) {
-	return r.listMistakes(ctx, uid, maxWrongAnswersPerSentence, maxInsightStatsScan)
+func (r *firestoreRepo) Unreport(ctx context.Context, id int) error {
+	return r.clearReports(ctx, id, func(tx *firestore.Transaction, ref *firestore.DocumentRef) error {
+		return tx.Update(ref, []firestore.Update{
+			{Path: "is_reported", Value: false},
+			{Path: "reported_by", Value: []string{}},
+			{Path: "report_count", Value: 0},
+			{Path: "updated_at", Value: r.now().UTC().Format(time.RFC3339)},
+		})
+	})
 }
 
-func (r *firestoreRepo) RecordAnswer(ctx context.Context, uid string, id int, correct bool, answer string) error {
-	now := r.now().UTC()
-	statsRef := r.userStats(uid).Doc(strconv.Itoa(id))
-	histRef := statsRef.Collection("histories").NewDoc()
+func (r *firestoreRepo) DeleteSentence(ctx context.Context, id int) error {
+	err := r.clearReports(ctx, id, func(tx *firestore.Transaction, ref *firestore.DocumentRef) error {
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return err
+	}
+	r.syncMistakeCopies(ctx, id, nil)
+	return nil
+}
 
-	field := "incorrect_count"
-	if correct {
-		field = "correct_count"
+// generation_cache docs are keyed by the cache key itself. Expired docs are
+// ignored on read; a Firestore TTL policy on expires_at deletes them.
+func (r *firestoreRepo) CachedGeneration(ctx context.Context, key string) (string, bool, error) {
+	snap, err := r.client.Collection("generation_cache").Doc(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return "", false, nil
 	}
+	if err != nil {
+		return "", false, err
+	}
+	var cd cacheDoc
+	if err := snap.DataTo(&cd); err != nil {
+		return "", false, err
+	}
+	if !cd.fresh(r.now()) {
+		return "", false, nil
+	}
+	return cd.Text, true, nil
+}
 
-	batch := r.client.Batch()
-	batch.Set(statsRef, map[string]interface{}{
-		field:        firestore.Increment(1),
-		"updated_at": now.Format(time.RFC3339),
-	}, firestore.MergeAll)
-	batch.Set(histRef, historyDoc{
-		IsCorrect:       correct,
-		IncorrectAnswer: answer,
-		CreatedAt:       now,
+// rate_limits docs are keyed by RateLimiter's uid:scope key.
+func (r *firestoreRepo) UpdateRateState(ctx context.Context, key string, fn func(RateState) RateState) error {
+	ref := r.client.Collection("rate_limits").Doc(key)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var st RateState
+		snap, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			if err := snap.DataTo(&st); err != nil {
+				return err
+			}
+		}
+		return tx.Set(ref, fn(st))
 	})
-	_, err := batch.Commit(ctx)
+}
+
+func (r *firestoreRepo) userSession(uid, id string) *firestore.DocumentRef {
+	return r.client.Collection("users").Doc(uid).Collection("sessions").Doc(id)
+}
+
+func (r *firestoreRepo) CreateSession(ctx context.Context, uid string, s PracticeSession) error {
+	_, err := r.userSession(uid, s.ID).Create(ctx, s)
 	return err
 }
 
-func (r *firestoreRepo) Report(ctx context.Context, id int) error {
-	_, err := r.client.Collection("sentences").Doc(strconv.Itoa(id)).
-		Update(ctx, []firestore.Update{{Path: "is_reported", Value: true}})
+func (r *firestoreRepo) GetSession(ctx context.Context, uid, id string) (PracticeSession, error) {
+	var s PracticeSession
+	ds, err := r.userSession(uid, id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return s, ErrSessionNotFound
+	}
+	if err != nil {
+		return s, err
+	}
+	err = ds.DataTo(&s)
+	return s, err
+}
+
+func (r *firestoreRepo) UpdateSession(ctx context.Context, uid, id string, fn func(*PracticeSession) error) error {
+	ref := r.userSession(uid, id)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		ds, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var s PracticeSession
+		if err := ds.DataTo(&s); err != nil {
+			return err
+		}
+		if err := fn(&s); err != nil {
+			return err
+		}
+		return tx.Set(ref, s)
+	})
+}
+
+// Ping reads at most one sentence: a single billed read that fails, like
+// every real request would, when credentials or the database are broken.
+func (r *firestoreRepo) Ping(ctx context.Context) error {
+	docs := r.client.Collection("sentences").Limit(1).Documents(ctx)
+	defer docs.Stop()
+	if _, err := docs.Next(); err != nil && !errors.Is(err, iterator.Done) {
+		return err
+	}
+	return nil
+}
+
+func (r *firestoreRepo) CacheGeneration(ctx context.Context, key, text string, ttl time.Duration) error {
+	_, err := r.client.Collection("generation_cache").Doc(key).Set(ctx, newCacheDoc(text, ttl, r.now()))
 	return err
 }
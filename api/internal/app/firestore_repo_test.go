@@ -6,10 +6,14 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
 const emulatorProjectID = "eagle-test"
@@ -18,7 +22,7 @@ const emulatorProjectID = "eagle-test"
 // empty database. The emulator is a long-lived process shared across test
 // runs, so without this, documents from earlier runs (e.g. counters,
 // histories) leak into later assertions.
-func clearFirestoreEmulator(t *testing.T, host string) {
+func clearFirestoreEmulator(t testing.TB, host string) {
 	t.Helper()
 	url := fmt.Sprintf("http://%s/emulator/v1/projects/%s/databases/(default)/documents", host, emulatorProjectID)
 	req, err := http.NewRequest(http.MethodDelete, url, nil)
@@ -35,14 +39,14 @@ func clearFirestoreEmulator(t *testing.T, host string) {
 	}
 }
 
-func newEmulatorClient(t *testing.T) *firestore.Client {
+func newEmulatorClient(t testing.TB, opts ...option.ClientOption) *firestore.Client {
 	t.Helper()
 	host := os.Getenv("FIRESTORE_EMULATOR_HOST")
 	if host == "" {
 		t.Skip("FIRESTORE_EMULATOR_HOST not set; skipping emulator test")
 	}
 	clearFirestoreEmulator(t, host)
-	client, err := firestore.NewClient(context.Background(), emulatorProjectID)
+	client, err := firestore.NewClient(context.Background(), emulatorProjectID, opts...)
 	if err != nil {
 		t.Fatalf("firestore client: %v", err)
 	}
@@ -50,7 +54,9 @@ func newEmulatorClient(t *testing.T) *firestore.Client {
 	return client
 }
 
-func seedSentence(t *testing.T, client *firestore.Client, id, page string, jp, en string, level int, reported bool) {
+// seedSentence writes a sentence around the repository, as cmd/seed does,
+// so it also invalidates the sentence catalog.
+func seedSentence(t testing.TB, client *firestore.Client, id, page string, jp, en string, level int, reported bool) {
 	t.Helper()
 	_, err := client.Collection("sentences").Doc(id).Set(context.Background(), map[string]interface{}{
 		"japanese": jp, "english": en, "page": page, "level": level, "is_reported": reported,
@@ -59,12 +65,15 @@ func seedSentence(t *testing.T, client *firestore.Client, id, page string, jp, e
 	if err != nil {
 		t.Fatalf("seed sentence %s: %v", id, err)
 	}
+	if err := InvalidateFirestoreCatalog(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
 }
 
-func TestFirestoreCorrectAnswerNotFound(t *testing.T) {
+func TestFirestoreAcceptedAnswersNotFound(t *testing.T) {
 	client := newEmulatorClient(t)
 	repo := NewFirestoreRepo(client)
-	if _, err := repo.CorrectAnswer(context.Background(), 424242); err != ErrNotFound {
+	if _, _, err := repo.AcceptedAnswers(context.Background(), 424242); err != ErrNotFound {
 		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -78,7 +87,7 @@ func seedManyWrongAnswers(t *testing.T, ctx context.Context, repo *firestoreRepo
 		repo.now = func(i int) func() time.Time {
 			return func() time.Time { return base.Add(time.Duration(i) * time.Minute) }
 		}(i)
-		if err := repo.RecordAnswer(ctx, uid, sentenceID, false, fmt.Sprintf("wrong-%d", i)); err != nil {
+		if _, err := repo.RecordAnswer(ctx, uid, sentenceID, AnswerRecord{Answer: fmt.Sprintf("wrong-%d", i)}); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -153,7 +162,7 @@ func TestFirestoreListMistakesForInsightBoundsSentenceScanAtQueryLevel(t *testin
 		repo.now = func(i int) func() time.Time {
 			return func() time.Time { return base.Add(time.Duration(i) * time.Minute) }
 		}(i)
-		if err := repo.RecordAnswer(ctx, uid, id, false, "wrong"); err != nil {
+		if _, err := repo.RecordAnswer(ctx, uid, id, AnswerRecord{Answer: "wrong"}); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -183,7 +192,7 @@ func TestFirestoreListMistakesForInsightSkipsCorrectOnlyDocsWhenScanning(t *test
 	seedSentence(t, client, "3000", "1", "古い間違い", "old mistake", 1, false)
 	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
 	repo.now = func() time.Time { return base }
-	if err := repo.RecordAnswer(ctx, uid, 3000, false, "wrong"); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 3000, AnswerRecord{Answer: "wrong"}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -195,7 +204,7 @@ func TestFirestoreListMistakesForInsightSkipsCorrectOnlyDocsWhenScanning(t *test
 		repo.now = func(i int) func() time.Time {
 			return func() time.Time { return base.Add(time.Duration(i+1) * time.Minute) }
 		}(i)
-		if err := repo.RecordAnswer(ctx, uid, id, true, ""); err != nil {
+		if _, err := repo.RecordAnswer(ctx, uid, id, AnswerRecord{Correct: true}); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -231,12 +240,12 @@ func TestFirestoreListMistakesSentenceScanRemainsUnboundedForRawList(t *testing.
 		repo.now = func(i int) func() time.Time {
 			return func() time.Time { return base.Add(time.Duration(i) * time.Minute) }
 		}(i)
-		if err := repo.RecordAnswer(ctx, uid, id, false, "wrong"); err != nil {
+		if _, err := repo.RecordAnswer(ctx, uid, id, AnswerRecord{Answer: "wrong"}); err != nil {
 			t.Fatal(err)
 		}
 	}
 
-	mistakes, err := repo.ListMistakes(ctx, uid)
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -291,7 +300,7 @@ func TestFirestoreListMistakesRemainsUnboundedForRawList(t *testing.T) {
 	const attempts = maxWrongAnswersPerSentence + 3
 	seedManyWrongAnswers(t, ctx, repo, uid, 301, base, attempts)
 
-	mistakes, err := repo.ListMistakes(ctx, uid)
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -310,10 +319,10 @@ func TestFirestoreRecordListAndCount(t *testing.T) {
 	uid := "user-record"
 	seedSentence(t, client, "201", "5", "こんにちは", "Hello", 1, false)
 
-	if err := repo.RecordAnswer(ctx, uid, 201, false, "Hi there"); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 201, AnswerRecord{Answer: "Hi there"}); err != nil {
 		t.Fatal(err)
 	}
-	if err := repo.RecordAnswer(ctx, uid, 201, true, ""); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 201, AnswerRecord{Correct: true}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -328,7 +337,7 @@ func TestFirestoreRecordListAndCount(t *testing.T) {
 		t.Fatalf("history id/created_at should be populated, got %+v", hs[0])
 	}
 
-	s, err := repo.RandomCandidate(ctx, uid, nil)
+	s, err := repo.RandomCandidate(ctx, uid, CandidateQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -337,31 +346,19 @@ func TestFirestoreRecordListAndCount(t *testing.T) {
 	}
 }
 
-func TestFirestoreRandomExcludesMasteredAndReported(t *testing.T) {
+func TestFirestoreRandomExcludesReported(t *testing.T) {
 	ctx := context.Background()
 	client := newEmulatorClient(t)
 	repo := NewFirestoreRepo(client)
 	uid := "user-filter"
-	seedSentence(t, client, "301", "1", "A", "A", 1, false) // mastered below
 	seedSentence(t, client, "302", "1", "B", "B", 1, true)  // reported
 	seedSentence(t, client, "303", "1", "C", "C", 1, false) // remains a valid candidate
 
-	// Push 301 to net +2 (correct - incorrect >= 2) -> excluded.
-	if err := repo.RecordAnswer(ctx, uid, 301, true, ""); err != nil {
-		t.Fatal(err)
-	}
-	if err := repo.RecordAnswer(ctx, uid, 301, true, ""); err != nil {
-		t.Fatal(err)
-	}
-
 	for i := 0; i < 8; i++ {
-		s, err := repo.RandomCandidate(ctx, uid, nil)
+		s, err := repo.RandomCandidate(ctx, uid, CandidateQuery{})
 		if err != nil {
 			t.Fatalf("iteration %d: %v", i, err)
 		}
-		if s.ID == 301 {
-			t.Fatal("mastered sentence 301 should be excluded")
-		}
 		if s.ID == 302 {
 			t.Fatal("reported sentence 302 should be excluded")
 		}
@@ -378,7 +375,7 @@ func TestFirestoreRandomCandidateFiltersByLevel(t *testing.T) {
 	seedSentence(t, client, "403", "1", "C", "C", 3, false)
 
 	for i := 0; i < 5; i++ {
-		s, err := repo.RandomCandidate(ctx, uid, []int{1})
+		s, err := repo.RandomCandidate(ctx, uid, CandidateQuery{Levels: []int{1}})
 		if err != nil {
 			t.Fatalf("iteration %d: %v", i, err)
 		}
@@ -388,7 +385,7 @@ func TestFirestoreRandomCandidateFiltersByLevel(t *testing.T) {
 	}
 
 	for i := 0; i < 8; i++ {
-		s, err := repo.RandomCandidate(ctx, uid, []int{3})
+		s, err := repo.RandomCandidate(ctx, uid, CandidateQuery{Levels: []int{3}})
 		if err != nil {
 			t.Fatalf("iteration %d: %v", i, err)
 		}
@@ -413,7 +410,7 @@ func TestFirestoreRandomCandidateLevelZeroIncludesUnleveledDocs(t *testing.T) {
 		t.Fatalf("seed unleveled sentence: %v", err)
 	}
 
-	s, err := repo.RandomCandidate(ctx, uid, nil)
+	s, err := repo.RandomCandidate(ctx, uid, CandidateQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -421,7 +418,7 @@ func TestFirestoreRandomCandidateLevelZeroIncludesUnleveledDocs(t *testing.T) {
 		t.Fatalf("expected unleveled sentence 501 under level=0, got %d", s.ID)
 	}
 
-	if _, err := repo.RandomCandidate(ctx, uid, []int{2}); err != ErrNoCandidate {
+	if _, err := repo.RandomCandidate(ctx, uid, CandidateQuery{Levels: []int{2}}); err != ErrNoCandidate {
 		t.Fatalf("expected ErrNoCandidate excluding unleveled doc under level=2, got %v", err)
 	}
 }
@@ -444,7 +441,7 @@ func TestFirestoreRandomCandidateFiltersByMultipleLevels(t *testing.T) {
 	}
 
 	for i := 0; i < 8; i++ {
-		s, err := repo.RandomCandidate(ctx, uid, []int{1, 3})
+		s, err := repo.RandomCandidate(ctx, uid, CandidateQuery{Levels: []int{1, 3}})
 		if err != nil {
 			t.Fatalf("iteration %d: %v", i, err)
 		}
@@ -463,19 +460,19 @@ func TestFirestoreListMistakesGroupsWrongAnswersMostRecentSentenceFirst(t *testi
 	seedSentence(t, client, "702", "1", "彼は毎朝走ります。", "He runs every morning.", 1, false)
 
 	repo.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
-	if err := repo.RecordAnswer(ctx, uid, 701, false, "I have no time."); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 701, AnswerRecord{Answer: "I have no time."}); err != nil {
 		t.Fatal(err)
 	}
 	repo.now = func() time.Time { return time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC) }
-	if err := repo.RecordAnswer(ctx, uid, 702, false, "He run every morning."); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 702, AnswerRecord{Answer: "He run every morning."}); err != nil {
 		t.Fatal(err)
 	}
 	repo.now = func() time.Time { return time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC) }
-	if err := repo.RecordAnswer(ctx, uid, 701, false, "There is no time."); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 701, AnswerRecord{Answer: "There is no time."}); err != nil {
 		t.Fatal(err)
 	}
 
-	mistakes, err := repo.ListMistakes(ctx, uid)
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -506,11 +503,11 @@ func TestFirestoreListMistakesExcludesSentencesNeverMissed(t *testing.T) {
 	repo := NewFirestoreRepo(client)
 	uid := "user-clean"
 	seedSentence(t, client, "801", "1", "A", "A-en", 1, false)
-	if err := repo.RecordAnswer(ctx, uid, 801, true, ""); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 801, AnswerRecord{Correct: true}); err != nil {
 		t.Fatal(err)
 	}
 
-	mistakes, err := repo.ListMistakes(ctx, uid)
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -533,15 +530,15 @@ func TestFirestoreListMistakesOrdersBySubSecondPrecisionWithinSameSecond(t *test
 
 	sameSecond := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
 	repo.now = func() time.Time { return sameSecond.Add(100 * time.Millisecond) }
-	if err := repo.RecordAnswer(ctx, uid, 1001, false, "wrong A"); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 1001, AnswerRecord{Answer: "wrong A"}); err != nil {
 		t.Fatal(err)
 	}
 	repo.now = func() time.Time { return sameSecond.Add(900 * time.Millisecond) }
-	if err := repo.RecordAnswer(ctx, uid, 1002, false, "wrong B"); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 1002, AnswerRecord{Answer: "wrong B"}); err != nil {
 		t.Fatal(err)
 	}
 
-	mistakes, err := repo.ListMistakes(ctx, uid)
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -565,14 +562,14 @@ func TestFirestoreListMistakesSkipsDeletedSentence(t *testing.T) {
 	repo := NewFirestoreRepo(client)
 	uid := "user-deleted"
 	seedSentence(t, client, "901", "1", "A", "A-en", 1, false)
-	if err := repo.RecordAnswer(ctx, uid, 901, false, "wrong"); err != nil {
+	if _, err := repo.RecordAnswer(ctx, uid, 901, AnswerRecord{Answer: "wrong"}); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := client.Collection("sentences").Doc("901").Delete(ctx); err != nil {
+	if err := repo.DeleteSentence(ctx, 901); err != nil {
 		t.Fatal(err)
 	}
 
-	mistakes, err := repo.ListMistakes(ctx, uid)
+	mistakes, _, err := repo.ListMistakes(ctx, uid, MistakeQuery{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -580,3 +577,102 @@ func TestFirestoreListMistakesSkipsDeletedSentence(t *testing.T) {
 		t.Fatalf("expected deleted sentence to be skipped, got %+v", mistakes)
 	}
 }
+
+func TestFirestoreRandomCandidateSeesAnotherInstancesEdits(t *testing.T) {
+	ctx := context.Background()
+	client := newEmulatorClient(t)
+	a, b := NewFirestoreRepo(client), NewFirestoreRepo(client)
+	seedSentence(t, client, "1", "1", "A", "A-en", 1, false)
+	if _, err := b.RandomCandidate(ctx, "user-b", CandidateQuery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// b's catalog is loaded; a's edit must still reach it.
+	if err := a.UpdateSentence(ctx, 1, SentenceEdit{Japanese: "A2", English: "A2-en", Page: "1", Level: 2}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := b.RandomCandidate(ctx, "user-b", CandidateQuery{Levels: []int{2}})
+	if err != nil {
+		t.Fatalf("expected the edited sentence at its new level: %v", err)
+	}
+	if s.English != "A2-en" {
+		t.Fatalf("expected the edited sentence, got %+v", s)
+	}
+}
+
+// countReads counts the documents Firestore returns to the client, which is
+// what reads are billed by.
+type countReads struct{ n atomic.Int64 }
+
+func (c *countReads) interceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	return countingStream{cs, c}, err
+}
+
+type countingStream struct {
+	grpc.ClientStream
+	c *countReads
+}
+
+func (s countingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+	switch m := m.(type) {
+	case *firestorepb.BatchGetDocumentsResponse:
+		s.c.n.Add(1)
+	case *firestorepb.RunQueryResponse:
+		if m.Document != nil {
+			s.c.n.Add(1)
+		}
+	}
+	return nil
+}
+
+// BenchmarkFirestoreRandomCandidate reports the documents each question
+// reads from a 500-sentence catalog for a learner who has answered 100 of
+// them: with the catalog cached, and with it reloaded every time.
+func BenchmarkFirestoreRandomCandidate(b *testing.B) {
+	ctx := context.Background()
+	var reads countReads
+	client := newEmulatorClient(b, option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(reads.interceptor)))
+	repo := NewFirestoreRepo(client)
+	uid := "user-bench"
+	for i := 1; i <= 500; i++ {
+		if _, err := client.Collection("sentences").Doc(strconv.Itoa(i)).Set(ctx, sentenceDoc{
+			Japanese: "ja", English: "en", Page: "1", Level: 1 + i%3, AcceptedAnswers: []string{"en"},
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := InvalidateFirestoreCatalog(ctx, client); err != nil {
+		b.Fatal(err)
+	}
+	for i := 1; i <= 100; i++ {
+		if _, err := repo.RecordAnswer(ctx, uid, i, AnswerRecord{Answer: "x"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, bc := range []struct {
+		name string
+		cold bool
+	}{{"warm", false}, {"cold catalog", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			if _, err := repo.RandomCandidate(ctx, uid, CandidateQuery{}); err != nil {
+				b.Fatal(err)
+			}
+			reads.n.Store(0)
+			for b.Loop() {
+				if bc.cold {
+					repo.catalog.invalidate()
+				}
+				if _, err := repo.RandomCandidate(ctx, uid, CandidateQuery{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(reads.n.Load())/float64(b.N), "reads/op")
+		})
+	}
+}
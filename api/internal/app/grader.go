@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GradeVerdict is a Grader's judgement of one translation attempt.
+type GradeVerdict struct {
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason"`
+}
+
+// Grader judges a learner's English translation that did not exactly match
+// any accepted answer, accepting translations that are correct English with
+// the same meaning as the Japanese sentence. checkAnswer consults it only
+// when AI grading is enabled (Server.SetGrader).
+type Grader interface {
+	Grade(ctx context.Context, japanese string, acceptedAnswers []string, userAnswer string) (GradeVerdict, error)
+}
+
+func buildGradePrompt(japanese string, acceptedAnswers []string, userAnswer string) string {
+	var b strings.Builder
+	b.WriteString("You are grading a Japanese speaker's English translation exercise.\n\n")
+	b.WriteString(fmt.Sprintf("Japanese sentence: %s\n", japanese))
+	for _, a := range acceptedAnswers {
+		b.WriteString(fmt.Sprintf("Reference English translation: %s\n", a))
+	}
+	b.WriteString(fmt.Sprintf("Learner's English translation: %s\n\n", userAnswer))
+	b.WriteString("The reference translations are examples of correct answers, not the only correct answers. ")
+	b.WriteString("Accept the learner's translation if it is grammatically correct, natural English that conveys ")
+	b.WriteString("the same meaning as the Japanese sentence, even if its wording differs from every reference. ")
+	b.WriteString("Reject it if it has a grammar, vocabulary, or meaning error, or leaves part of the sentence untranslated. ")
+	b.WriteString("Differences in capitalization, punctuation, and contractions are never a reason to reject.\n\n")
+	b.WriteString("Respond with JSON: \"accepted\" (boolean) and \"reason\" (one short sentence in English ")
+	b.WriteString("explaining the decision).")
+	return b.String()
+}
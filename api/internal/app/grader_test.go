@@ -0,0 +1,21 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildGradePromptListsEveryAcceptedAnswer(t *testing.T) {
+	p := buildGradePrompt("時間がありません。", []string{"I don't have time.", "I have no time."}, "No time for me.")
+	for _, want := range []string{
+		"Japanese sentence: 時間がありません。",
+		"Reference English translation: I don't have time.",
+		"Reference English translation: I have no time.",
+		"Learner's English translation: No time for me.",
+		"not the only correct answers",
+	} {
+		if !strings.Contains(p, want) {
+			t.Fatalf("prompt missing %q:\n%s", want, p)
+		}
+	}
+}
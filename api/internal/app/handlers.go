@@ -1,13 +1,16 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,60 +18,173 @@ const (
 	// an authenticated caller can't exhaust memory or inflate Gemini request
 	// size via an arbitrarily large payload.
 	maxExplainRequestBytes = 4096
-	// maxUserAnswerLength bounds the submitted translation attempt itself,
+	// maxReportCommentLength bounds a report's free-text comment, which is
+	// stored and shown to admins as-is.
+	maxReportCommentLength = 1000
+)
+
+// Limits bound what a learner may submit and what is sent to the LLM on
+// their behalf.
+type Limits struct {
+	// MaxUserAnswerLength bounds the submitted translation attempt itself,
 	// independent of the overall body size limit. Enforced both when an
 	// answer is checked (so oversized text is never persisted to Firestore
 	// and later fanned into the weakness-insight prompt) and when it is
 	// explained.
-	maxUserAnswerLength = 2000
-	// maxInsightMistakes bounds how many recent mistakes are sent to the
+	MaxUserAnswerLength int
+	// MaxInsightMistakes bounds how many recent mistakes are sent to the
 	// weakness analyzer, keeping prompt size and Gemini cost predictable as a
 	// learner's mistake history grows.
-	maxInsightMistakes = 50
-)
+	MaxInsightMistakes int
+}
+
+func DefaultLimits() Limits {
+	return Limits{MaxUserAnswerLength: 2000, MaxInsightMistakes: 50}
+}
 
 type Server struct {
 	repo      SentenceRepository
 	explainer Explainer
 	analyzer  WeaknessAnalyzer
+	// grader is nil unless AI grading is enabled.
+	grader Grader
+	// cacheTTL is how long generated explanations and insights are reused;
+	// zero disables the generation cache.
+	cacheTTL time.Duration
+	// limiter is nil unless the AI routes are rate-limited.
+	limiter   *RateLimiter
+	metrics   *Metrics
+	limits    Limits
+	readiness *readiness
+	// metricsToken is the bearer token /metrics requires; empty turns
+	// /metrics off.
+	metricsToken string
+	now          func() time.Time
 }
 
 func NewServer(repo SentenceRepository, explainer Explainer, analyzer WeaknessAnalyzer) *Server {
-	return &Server{repo: repo, explainer: explainer, analyzer: analyzer}
+	return &Server{
+		repo: repo, explainer: explainer, analyzer: analyzer,
+		metrics:   NewMetrics(),
+		limits:    DefaultLimits(),
+		readiness: newReadiness(DefaultReadinessConfig(), []ReadinessCheck{RepositoryReadinessCheck(repo)}),
+		now:       time.Now,
+	}
+}
+
+// SetGrader enables AI grading: an answer that matches no accepted answer
+// exactly is passed to g, whose verdict stands unless the learner overrides
+// it via /api/answer/override.
+func (s *Server) SetGrader(g Grader) {
+	s.grader = g
+}
+
+// SetGenerationCacheTTL enables caching generated explanations and insights
+// in the repository for ttl; zero disables it.
+func (s *Server) SetGenerationCacheTTL(ttl time.Duration) {
+	s.cacheTTL = ttl
+}
+
+// SetRateLimiter limits how often each user may call the LLM-backed routes
+// NewMux wires; without one they are unlimited. A request is charged only
+// when it reaches the model, so cache hits and exact-match grades are free.
+func (s *Server) SetRateLimiter(l *RateLimiter) {
+	s.limiter = l
+}
+
+// SetMetrics has the server report to m, which the repository and LLM
+// client should also be instrumented with (see InstrumentRepository and
+// InstrumentContentGenerator), so /metrics shows them together. Without it
+// the server keeps metrics of its own requests only.
+func (s *Server) SetMetrics(m *Metrics) {
+	s.metrics = m
+}
+
+// SetMetricsToken serves /metrics to scrapers presenting token as a bearer
+// token. Without one, /metrics is off.
+func (s *Server) SetMetricsToken(token string) {
+	s.metricsToken = token
+}
+
+// SetLimits replaces DefaultLimits.
+func (s *Server) SetLimits(l Limits) {
+	s.limits = l
+}
+
+// SetReadiness configures /api/readiness, which always checks the
+// repository, to run checks too.
+func (s *Server) SetReadiness(cfg ReadinessConfig, checks ...ReadinessCheck) {
+	s.readiness = newReadiness(cfg, append([]ReadinessCheck{RepositoryReadinessCheck(s.repo)}, checks...))
+}
+
+func (s *Server) cachedExplainer() cachedExplainer {
+	return cachedExplainer{s.explainer, generationCache{s.repo, s.cacheTTL}, generationModel(s.explainer)}
+}
+
+func (s *Server) cachedAnalyzer() cachedAnalyzer {
+	return cachedAnalyzer{s.analyzer, generationCache{s.repo, s.cacheTTL}, generationModel(s.analyzer)}
+}
+
+// parseLevels parses a levels parameter: comma-separated levels 1-5, with
+// duplicates dropped. Empty means any level.
+func parseLevels(raw string) ([]int, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	var levels []int
+	for _, part := range strings.Split(raw, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, false
+		}
+		levels = append(levels, n)
+	}
+	return normalizeLevels(levels)
+}
+
+// normalizeLevels checks that every level is 1-5 and drops duplicates.
+func normalizeLevels(levels []int) ([]int, bool) {
+	var out []int
+	seen := map[int]bool{}
+	for _, n := range levels {
+		if n < 1 || n > 5 {
+			return nil, false
+		}
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out, true
 }
 
 func (s *Server) getRandomSentence(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 		return
 	}
 	uid, _ := uidFromContext(r.Context())
-	var levels []int
-	if raw := r.URL.Query().Get("levels"); raw != "" {
-		seen := map[int]bool{}
-		for _, part := range strings.Split(raw, ",") {
-			n, err := strconv.Atoi(strings.TrimSpace(part))
-			if err != nil || n < 1 || n > 5 {
-				http.Error(w, "Invalid levels parameter", http.StatusBadRequest)
-				return
-			}
-			if !seen[n] {
-				seen[n] = true
-				levels = append(levels, n)
-			}
-		}
+	levels, ok := parseLevels(r.URL.Query().Get("levels"))
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidLevels, "Invalid levels parameter")
+		return
+	}
+	source := CandidateSource(r.URL.Query().Get("source"))
+	if source != "" && !source.valid() {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidSource, "Invalid source parameter")
+		return
 	}
-	sentence, err := s.repo.RandomCandidate(r.Context(), uid, levels)
+	sentence, err := s.repo.RandomCandidate(r.Context(), uid, CandidateQuery{Levels: levels, Source: source})
 	if errors.Is(err, ErrNoCandidate) {
-		http.Error(w, "No sentences found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, CodeNoCandidate, "No sentences found")
 		return
 	}
 	if err != nil {
-		log.Printf("random candidate error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "random candidate error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
-	writeJSON(w, sentence)
+	writeJSON(w, r, sentence)
 }
 
 // answerPunctuation folds the typographic punctuation that phone keyboards,
@@ -113,144 +229,416 @@ func normalizeAnswer(s string) string {
 	return strings.TrimRight(s, terminalPunctuation)
 }
 
+// matchAnswer returns the first accepted answer userAnswer equals after
+// normalization, ignoring case, and whether there was one.
+func matchAnswer(userAnswer string, accepted []string) (string, bool) {
+	got := normalizeAnswer(userAnswer)
+	for _, a := range accepted {
+		if strings.EqualFold(got, normalizeAnswer(a)) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
 func (s *Server) checkAnswer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 		return
 	}
 	uid, _ := uidFromContext(r.Context())
 	var req CheckAnswerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeInvalidBody(w, r)
 		return
 	}
-	if len(req.UserAnswer) > maxUserAnswerLength {
-		http.Error(w, "Invalid user_answer", http.StatusBadRequest)
+	if len(req.UserAnswer) > s.limits.MaxUserAnswerLength {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidUserAnswer, "Invalid user_answer")
 		return
 	}
-	correct, err := s.repo.CorrectAnswer(r.Context(), req.SentenceID)
+	if req.SessionID != "" && !s.checkSessionAnswer(w, r, uid, req.SessionID, req.SentenceID) {
+		return
+	}
+	accepted, level, err := s.repo.AcceptedAnswers(r.Context(), req.SentenceID)
 	if errors.Is(err, ErrNotFound) {
-		http.Error(w, "Sentence not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, CodeSentenceNotFound, "Sentence not found")
 		return
 	}
 	if err != nil {
-		log.Printf("correct answer error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "correct answer error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
 	histories, err := s.repo.ListIncorrectHistories(r.Context(), uid, req.SentenceID)
 	if err != nil {
-		log.Printf("list histories error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "list histories error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
-	isCorrect := strings.EqualFold(normalizeAnswer(req.UserAnswer), normalizeAnswer(correct))
-	answer := ""
+	matched, isCorrect := matchAnswer(req.UserAnswer, accepted)
+	rec := AnswerRecord{Correct: isCorrect, GradedBy: GradedExact}
 	if !isCorrect {
-		answer = req.UserAnswer
-	}
-	if err := s.repo.RecordAnswer(r.Context(), uid, req.SentenceID, isCorrect, answer); err != nil {
-		log.Printf("record answer error: %v", err)
+		rec.Answer = req.UserAnswer
+		if s.grader != nil && strings.TrimSpace(req.UserAnswer) != "" {
+			if rec, err = s.gradeAnswer(r.Context(), req, accepted, rec); writeRateLimited(w, r, err) {
+				return
+			}
+		}
 	}
-	writeJSON(w, CheckAnswerResponse{
-		IsCorrect:     isCorrect,
-		CorrectAnswer: correct,
-		Histories:     histories,
+	s.metrics.observeGrading(level, rec)
+	historyID, err := s.repo.RecordAnswer(r.Context(), uid, req.SentenceID, rec)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "record answer error", "err", err)
+	} else if req.SessionID != "" {
+		s.recordSessionAnswer(r.Context(), uid, req, rec, historyID)
+	}
+	writeJSON(w, r, CheckAnswerResponse{
+		IsCorrect:       rec.Correct,
+		CorrectAnswer:   accepted[0],
+		HistoryID:       historyID,
+		GradedBy:        rec.GradedBy,
+		GraderReason:    rec.GraderReason,
+		MatchedAnswer:   matched,
+		AcceptedAnswers: accepted,
+		Histories:       histories,
 	})
 }
 
+// gradeAnswer asks the grader about an answer that matched no accepted
+// answer, charging the route's rate limit just before it does; the only
+// error it returns is the *RateLimitError once that is used up. Grading is
+// otherwise best-effort: if the sentence can't be loaded or the grader
+// fails, the exact-match rejection in rejected stands.
+func (s *Server) gradeAnswer(ctx context.Context, req CheckAnswerRequest, accepted []string, rejected AnswerRecord) (AnswerRecord, error) {
+	japanese, _, err := s.repo.GetSentence(ctx, req.SentenceID)
+	if err != nil {
+		slog.ErrorContext(ctx, "get sentence error", "err", err)
+		return rejected, nil
+	}
+	if err := chargeRateLimit(ctx); err != nil {
+		return rejected, err
+	}
+	verdict, err := s.grader.Grade(ctx, japanese, accepted, req.UserAnswer)
+	if err != nil {
+		slog.ErrorContext(ctx, "grade answer error", "err", err)
+		return rejected, nil
+	}
+	return AnswerRecord{
+		Correct:      verdict.Accepted,
+		Answer:       req.UserAnswer,
+		GradedBy:     GradedAI,
+		GraderReason: verdict.Reason,
+	}, nil
+}
+
+// overrideAnswer lets the learner dispute the verdict on one of their
+// attempts — typically "I was actually right" on a rejected translation —
+// optionally promoting that translation to an accepted alternative.
+func (s *Server) overrideAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	uid, _ := uidFromContext(r.Context())
+	var req OverrideAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+	if req.Promote && !req.IsCorrect {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidPromote, "Only a correct answer can be promoted")
+		return
+	}
+	// Promoting changes grading for every learner, so it is an admin's
+	// call.
+	if req.Promote && !isAdminRequest(r.Context()) {
+		writeError(w, r, http.StatusForbidden, CodeForbidden, "Only an admin can promote an answer")
+		return
+	}
+	if req.SessionID != "" {
+		if _, ok := s.loadSession(w, r, uid, req.SessionID); !ok {
+			return
+		}
+	}
+	err := s.repo.OverrideAnswer(r.Context(), uid, req.SentenceID, req.HistoryID,
+		AnswerOverride{Correct: req.IsCorrect, Promote: req.Promote})
+	if errors.Is(err, ErrHistoryNotFound) || errors.Is(err, ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeHistoryNotFound, "Answer history not found")
+		return
+	}
+	if errors.Is(err, ErrNotOverridable) {
+		writeError(w, r, http.StatusConflict, CodeNotOverridable, "Answer cannot be overridden")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "override answer error", "err", err)
+		writeInternalError(w, r)
+		return
+	}
+	if req.SessionID != "" {
+		s.overrideSessionAnswer(r.Context(), uid, req)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s *Server) getMistakes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	q, code, detail := parseMistakeQuery(r.URL.Query())
+	if code != "" {
+		writeError(w, r, http.StatusBadRequest, code, detail)
 		return
 	}
 	uid, _ := uidFromContext(r.Context())
-	mistakes, err := s.repo.ListMistakes(r.Context(), uid)
+	mistakes, next, err := s.repo.ListMistakes(r.Context(), uid, q)
+	if errors.Is(err, ErrInvalidCursor) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidCursor, "Invalid cursor")
+		return
+	}
 	if err != nil {
-		log.Printf("list mistakes error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "list mistakes error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
-	writeJSON(w, ListMistakesResponse{Mistakes: mistakes})
+	writeJSON(w, r, ListMistakesResponse{Mistakes: mistakes, NextCursor: next})
+}
+
+// Page sizes of /api/mistakes.
+const (
+	defaultMistakesPageSize = 20
+	maxMistakesPageSize     = 100
+)
+
+// parseMistakeQuery reads /api/mistakes' query parameters, returning the
+// Problem code and detail of the first invalid one.
+func parseMistakeQuery(v url.Values) (q MistakeQuery, code, detail string) {
+	q.Limit = defaultMistakesPageSize
+	if raw := v.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxMistakesPageSize {
+			return q, CodeInvalidLimit, fmt.Sprintf("limit must be 1-%d", maxMistakesPageSize)
+		}
+		q.Limit = n
+	}
+	q.Cursor = v.Get("cursor")
+	levels, ok := parseLevels(v.Get("levels"))
+	if !ok {
+		return q, CodeInvalidLevels, "Invalid levels parameter"
+	}
+	q.Levels = levels
+	if raw := v.Get("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			// A bare date means its start, in UTC.
+			if t, err = time.Parse(time.DateOnly, raw); err != nil {
+				return q, CodeInvalidSince, "since must be an RFC 3339 timestamp or a YYYY-MM-DD date"
+			}
+		}
+		q.Since = t
+	}
+	if raw := v.Get("min_incorrect"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return q, CodeInvalidMinIncorrect, "min_incorrect must be a non-negative integer"
+		}
+		q.MinIncorrect = n
+	}
+	if raw := v.Get("unmastered"); raw != "" {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, CodeInvalidUnmastered, "Invalid unmastered"
+		}
+		q.Unmastered = b
+	}
+	if raw := v.Get("sort"); raw != "" {
+		q.Sort = MistakeSort(raw)
+		if !q.Sort.valid() {
+			return q, CodeInvalidSort, "sort must be recent, most_missed or worst_accuracy"
+		}
+	}
+	return q, "", ""
 }
 
 func (s *Server) getMistakesInsight(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 		return
 	}
 	language := r.URL.Query().Get("language")
 	if !validExplainLanguages[language] {
-		http.Error(w, "Invalid language", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidLanguage, "Invalid language")
 		return
 	}
+	refresh := false
+	if v := r.URL.Query().Get("force_refresh"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidForceRefresh, "Invalid force_refresh")
+			return
+		}
+		refresh = b
+	}
 	uid, _ := uidFromContext(r.Context())
 	mistakes, err := s.repo.ListMistakesForInsight(r.Context(), uid)
 	if err != nil {
-		log.Printf("list mistakes error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "list mistakes error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
 	if len(mistakes) == 0 {
-		writeJSON(w, MistakesInsightResponse{Insight: ""})
+		writeJSON(w, r, MistakesInsightResponse{Insight: ""})
 		return
 	}
-	if len(mistakes) > maxInsightMistakes {
-		mistakes = mistakes[:maxInsightMistakes]
+	if len(mistakes) > s.limits.MaxInsightMistakes {
+		mistakes = mistakes[:s.limits.MaxInsightMistakes]
+	}
+	insight, cached, err := s.cachedAnalyzer().analyze(r.Context(), mistakes, language, refresh)
+	if writeRateLimited(w, r, err) {
+		return
 	}
-	insight, err := s.analyzer.Analyze(r.Context(), mistakes, language)
 	if err != nil {
-		log.Printf("analyze mistakes error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "analyze mistakes error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
 	if strings.TrimSpace(insight) == "" {
-		log.Printf("analyze mistakes returned an empty insight for %d mistakes", len(mistakes))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "analyze mistakes returned an empty insight", "mistakes", len(mistakes))
+		writeInternalError(w, r)
 		return
 	}
-	writeJSON(w, MistakesInsightResponse{Insight: insight})
+	writeJSON(w, r, MistakesInsightResponse{Insight: insight, Cached: cached})
 }
 
 func (s *Server) reportSentence(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 		return
 	}
+	uid, _ := uidFromContext(r.Context())
 	var req ReportSentenceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeInvalidBody(w, r)
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = ReportOther
+	}
+	if !validReportReason(req.Reason) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidReason, "Invalid reason")
+		return
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len(comment) > maxReportCommentLength {
+		writeError(w, r, http.StatusBadRequest, CodeCommentTooLong, "Comment too long")
 		return
 	}
-	if err := s.repo.Report(r.Context(), req.SentenceID); err != nil {
-		log.Printf("report error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	err := s.repo.Report(r.Context(), uid, req.SentenceID, ReportRecord{Reason: req.Reason, Comment: comment})
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeSentenceNotFound, "Sentence not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "report error", "err", err)
+		writeInternalError(w, r)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// explainInput is a validated explain request with its sentence loaded.
+type explainInput struct {
+	japanese, correctAnswer, userAnswer, language string
+	// refresh bypasses the generation cache (ExplainRequest.ForceRefresh).
+	refresh bool
+}
+
 func (s *Server) explainAnswer(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	in, ok := s.readExplainRequest(w, r)
+	if !ok {
+		return
+	}
+	explanation, cached, err := s.cachedExplainer().explain(r.Context(), in)
+	if writeRateLimited(w, r, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "explain answer error", "err", err)
+		writeInternalError(w, r)
+		return
+	}
+	writeJSON(w, r, ExplainResponse{Explanation: explanation, Cached: cached})
+}
+
+// explainAnswerStream is explainAnswer delivered as Server-Sent Events: a
+// "chunk" event per piece of the explanation as the model produces it, then
+// one "done" event carrying the whole explanation, or an "error" event if
+// the model fails part-way. Request errors, and the rate limit, are
+// reported before the stream starts, with the same statuses as
+// explainAnswer.
+func (s *Server) explainAnswerStream(w http.ResponseWriter, r *http.Request) {
+	in, ok := s.readExplainRequest(w, r)
+	if !ok {
+		return
+	}
+	// The stream starts with the first event, so a refusal by the rate
+	// limit, which comes before any, can still be a 429.
+	var sse *sseWriter
+	stream := func() *sseWriter {
+		if sse == nil {
+			sse = newSSEWriter(w)
+		}
+		return sse
+	}
+	var explanation strings.Builder
+	cached, err := s.cachedExplainer().explainStream(r.Context(), in, func(chunk string) error {
+		explanation.WriteString(chunk)
+		return stream().event("chunk", ExplainChunk{Text: chunk})
+	})
+	if sse == nil && writeRateLimited(w, r, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "explain answer stream error", "err", err)
+		if err := stream().event("error", ExplainStreamError{
+			Error:     "Internal server error",
+			Code:      CodeInternal,
+			RequestID: requestIDFromContext(r.Context()),
+		}); err != nil {
+			slog.ErrorContext(r.Context(), "explain answer stream error event", "err", err)
+		}
 		return
 	}
+	if err := stream().event("done", ExplainResponse{Explanation: explanation.String(), Cached: cached}); err != nil {
+		slog.ErrorContext(r.Context(), "explain answer stream done event", "err", err)
+	}
+}
+
+// readExplainRequest validates an explain request and loads its sentence. It
+// writes the error response itself and returns false when the request
+// cannot be explained.
+func (s *Server) readExplainRequest(w http.ResponseWriter, r *http.Request) (explainInput, bool) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return explainInput{}, false
+	}
 	r.Body = http.MaxBytesReader(w, r.Body, maxExplainRequestBytes)
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	var req ExplainRequest
 	if err := decoder.Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+		writeInvalidBody(w, r)
+		return explainInput{}, false
 	}
 	userAnswer := strings.TrimSpace(req.UserAnswer)
-	if userAnswer == "" || len(userAnswer) > maxUserAnswerLength {
-		http.Error(w, "Invalid user_answer", http.StatusBadRequest)
-		return
+	if userAnswer == "" || len(userAnswer) > s.limits.MaxUserAnswerLength {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidUserAnswer, "Invalid user_answer")
+		return explainInput{}, false
 	}
 	if !validExplainLanguages[req.Language] {
-		http.Error(w, "Invalid language", http.StatusBadRequest)
-		return
+		writeError(w, r, http.StatusBadRequest, CodeInvalidLanguage, "Invalid language")
+		return explainInput{}, false
 	}
 	// The Japanese sentence and reference answer are always loaded
 	// server-side by sentence_id, never trusted from the client — otherwise
@@ -258,21 +646,15 @@ func (s *Server) explainAnswer(w http.ResponseWriter, r *http.Request) {
 	// process under this app's own API key.
 	japanese, correctAnswer, err := s.repo.GetSentence(r.Context(), req.SentenceID)
 	if errors.Is(err, ErrNotFound) {
-		http.Error(w, "Sentence not found", http.StatusNotFound)
-		return
+		writeError(w, r, http.StatusNotFound, CodeSentenceNotFound, "Sentence not found")
+		return explainInput{}, false
 	}
 	if err != nil {
-		log.Printf("get sentence error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-	explanation, err := s.explainer.Explain(r.Context(), japanese, correctAnswer, userAnswer, req.Language)
-	if err != nil {
-		log.Printf("explain answer error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(r.Context(), "get sentence error", "err", err)
+		writeInternalError(w, r)
+		return explainInput{}, false
 	}
-	writeJSON(w, ExplainResponse{Explanation: explanation})
+	return explainInput{japanese, correctAnswer, userAnswer, req.Language, req.ForceRefresh}, true
 }
 
 func livenessHandler(w http.ResponseWriter, r *http.Request) {
@@ -280,9 +662,9 @@ func livenessHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "OK")
 }
 
-func writeJSON(w http.ResponseWriter, v interface{}) {
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(v); err != nil {
-		log.Printf("encode error: %v", err)
+		slog.ErrorContext(r.Context(), "encode error", "err", err)
 	}
 }
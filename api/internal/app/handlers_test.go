@@ -5,44 +5,83 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 type recordedAnswer struct {
-	uid     string
-	id      int
-	correct bool
-	answer  string
+	uid          string
+	id           int
+	correct      bool
+	answer       string
+	gradedBy     string
+	graderReason string
+}
+
+type overrideCall struct {
+	uid       string
+	id        int
+	historyID int64
+	ov        AnswerOverride
+}
+
+type reportCall struct {
+	uid string
+	id  int
+	rec ReportRecord
 }
 
 type fakeRepo struct {
 	random           *Sentence
 	randomErr        error
 	randomLevelCalls [][]int
+	randomSources    []CandidateSource
 	correct          string
+	alternatives     []string
+	level            int
 	correctErr       error
 	sentenceJapanese string
 	sentenceEnglish  string
 	sentenceErr      error
 	histories        []AnswerHistory
 	recorded         []recordedAnswer
-	reported         []int
+	reported         []reportCall
 	mistakes         []MistakeSentence
 	mistakesErr      error
+	mistakesQuery    MistakeQuery
+	mistakesNext     string
+	overrides        []overrideCall
+	reportErr        error
+	overrideErr      error
+	cache            map[string]string
+	cacheErr         error
+	reportedList     []AdminSentence
+	edits            map[int]SentenceEdit
+	unreported       []int
+	deleted          []int
+	adminErr         error
+	pingErr          error
+	sessions         map[string]PracticeSession
 
 	listMistakesCalls           int
 	listMistakesForInsightCalls int
 }
 
-func (f *fakeRepo) RandomCandidate(_ context.Context, _ string, levels []int) (*Sentence, error) {
-	f.randomLevelCalls = append(f.randomLevelCalls, levels)
+func (f *fakeRepo) RandomCandidate(_ context.Context, _ string, q CandidateQuery) (*Sentence, error) {
+	f.randomLevelCalls = append(f.randomLevelCalls, q.Levels)
+	f.randomSources = append(f.randomSources, q.Source)
 	return f.random, f.randomErr
 }
-func (f *fakeRepo) CorrectAnswer(_ context.Context, _ int) (string, error) {
-	return f.correct, f.correctErr
+func (f *fakeRepo) AcceptedAnswers(_ context.Context, _ int) ([]string, int, error) {
+	if f.correctErr != nil {
+		return nil, 0, f.correctErr
+	}
+	return append([]string{f.correct}, f.alternatives...), f.level, nil
 }
 func (f *fakeRepo) GetSentence(_ context.Context, _ int) (string, string, error) {
 	return f.sentenceJapanese, f.sentenceEnglish, f.sentenceErr
@@ -53,15 +92,16 @@ func (f *fakeRepo) ListIncorrectHistories(_ context.Context, _ string, _ int) ([
 	}
 	return f.histories, nil
 }
-func (f *fakeRepo) ListMistakes(_ context.Context, _ string) ([]MistakeSentence, error) {
+func (f *fakeRepo) ListMistakes(_ context.Context, _ string, q MistakeQuery) ([]MistakeSentence, string, error) {
 	f.listMistakesCalls++
+	f.mistakesQuery = q
 	if f.mistakesErr != nil {
-		return nil, f.mistakesErr
+		return nil, "", f.mistakesErr
 	}
 	if f.mistakes == nil {
-		return []MistakeSentence{}, nil
+		return []MistakeSentence{}, "", nil
 	}
-	return f.mistakes, nil
+	return f.mistakes, f.mistakesNext, nil
 }
 
 // ListMistakesForInsight shares fakeRepo's mistakes/mistakesErr fixtures
@@ -80,12 +120,83 @@ func (f *fakeRepo) ListMistakesForInsight(_ context.Context, _ string) ([]Mistak
 	}
 	return f.mistakes, nil
 }
-func (f *fakeRepo) RecordAnswer(_ context.Context, uid string, id int, correct bool, answer string) error {
-	f.recorded = append(f.recorded, recordedAnswer{uid, id, correct, answer})
+func (f *fakeRepo) RecordAnswer(_ context.Context, uid string, id int, rec AnswerRecord) (int64, error) {
+	f.recorded = append(f.recorded, recordedAnswer{uid, id, rec.Correct, rec.Answer, rec.GradedBy, rec.GraderReason})
+	return int64(len(f.recorded)), nil
+}
+func (f *fakeRepo) OverrideAnswer(_ context.Context, uid string, id int, historyID int64, ov AnswerOverride) error {
+	f.overrides = append(f.overrides, overrideCall{uid, id, historyID, ov})
+	return f.overrideErr
+}
+func (f *fakeRepo) Report(_ context.Context, uid string, id int, rec ReportRecord) error {
+	f.reported = append(f.reported, reportCall{uid, id, rec})
+	return f.reportErr
+}
+func (f *fakeRepo) ListReported(_ context.Context) ([]AdminSentence, error) {
+	if f.reportedList == nil {
+		return []AdminSentence{}, f.adminErr
+	}
+	return f.reportedList, f.adminErr
+}
+func (f *fakeRepo) UpdateSentence(_ context.Context, id int, edit SentenceEdit) error {
+	if f.edits == nil {
+		f.edits = map[int]SentenceEdit{}
+	}
+	f.edits[id] = edit
+	return f.adminErr
+}
+func (f *fakeRepo) Unreport(_ context.Context, id int) error {
+	f.unreported = append(f.unreported, id)
+	return f.adminErr
+}
+func (f *fakeRepo) DeleteSentence(_ context.Context, id int) error {
+	f.deleted = append(f.deleted, id)
+	return f.adminErr
+}
+func (f *fakeRepo) UpdateRateState(_ context.Context, _ string, fn func(RateState) RateState) error {
+	fn(RateState{})
 	return nil
 }
-func (f *fakeRepo) Report(_ context.Context, id int) error {
-	f.reported = append(f.reported, id)
+func (f *fakeRepo) CreateSession(_ context.Context, _ string, s PracticeSession) error {
+	if f.sessions == nil {
+		f.sessions = map[string]PracticeSession{}
+	}
+	f.sessions[s.ID] = s
+	return nil
+}
+func (f *fakeRepo) GetSession(_ context.Context, _, id string) (PracticeSession, error) {
+	s, ok := f.sessions[id]
+	if !ok {
+		return PracticeSession{}, ErrSessionNotFound
+	}
+	return s, nil
+}
+func (f *fakeRepo) UpdateSession(_ context.Context, _, id string, fn func(*PracticeSession) error) error {
+	s, ok := f.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if err := fn(&s); err != nil {
+		return err
+	}
+	f.sessions[id] = s
+	return nil
+}
+func (f *fakeRepo) Ping(context.Context) error {
+	return f.pingErr
+}
+func (f *fakeRepo) CachedGeneration(_ context.Context, key string) (string, bool, error) {
+	text, ok := f.cache[key]
+	return text, ok, f.cacheErr
+}
+func (f *fakeRepo) CacheGeneration(_ context.Context, key, text string, _ time.Duration) error {
+	if f.cacheErr != nil {
+		return f.cacheErr
+	}
+	if f.cache == nil {
+		f.cache = map[string]string{}
+	}
+	f.cache[key] = text
 	return nil
 }
 
@@ -98,8 +209,10 @@ type explainCall struct {
 
 type fakeExplainer struct {
 	explanation string
-	err         error
-	calledWith  []explainCall
+	// chunks are what ExplainStream emits before returning err.
+	chunks     []string
+	err        error
+	calledWith []explainCall
 }
 
 func (f *fakeExplainer) Explain(_ context.Context, japanese, correctAnswer, userAnswer, language string) (string, error) {
@@ -107,6 +220,16 @@ func (f *fakeExplainer) Explain(_ context.Context, japanese, correctAnswer, user
 	return f.explanation, f.err
 }
 
+func (f *fakeExplainer) ExplainStream(_ context.Context, japanese, correctAnswer, userAnswer, language string, emit func(string) error) error {
+	f.calledWith = append(f.calledWith, explainCall{japanese, correctAnswer, userAnswer, language})
+	for _, c := range f.chunks {
+		if err := emit(c); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
 type analyzeCall struct {
 	mistakes []MistakeSentence
 	language string
@@ -123,6 +246,23 @@ func (f *fakeAnalyzer) Analyze(_ context.Context, mistakes []MistakeSentence, la
 	return f.insight, f.err
 }
 
+type gradeCall struct {
+	japanese        string
+	acceptedAnswers []string
+	userAnswer      string
+}
+
+type fakeGrader struct {
+	verdict    GradeVerdict
+	err        error
+	calledWith []gradeCall
+}
+
+func (f *fakeGrader) Grade(_ context.Context, japanese string, acceptedAnswers []string, userAnswer string) (GradeVerdict, error) {
+	f.calledWith = append(f.calledWith, gradeCall{japanese, acceptedAnswers, userAnswer})
+	return f.verdict, f.err
+}
+
 func authed(req *http.Request, uid string) *http.Request {
 	return req.WithContext(withUID(req.Context(), uid))
 }
@@ -216,6 +356,32 @@ func TestGetRandomSentenceInvalidLevels(t *testing.T) {
 	}
 }
 
+func TestGetRandomSentencePassesSourceToRepo(t *testing.T) {
+	for _, source := range []CandidateSource{"", SourceAll, SourceNew, SourceSeen, SourceReview, SourceMistakes} {
+		repo := &fakeRepo{random: &Sentence{ID: 7}}
+		srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+		rec := httptest.NewRecorder()
+		srv.getRandomSentence(rec, authed(httptest.NewRequest(http.MethodGet, "/api/sentence/random?levels=1&source="+string(source), nil), "u1"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("source=%q: expected 200, got %d", source, rec.Code)
+		}
+		if len(repo.randomSources) != 1 || repo.randomSources[0] != source {
+			t.Fatalf("source=%q: expected repo called with it, got %v", source, repo.randomSources)
+		}
+	}
+}
+
+func TestGetRandomSentenceInvalidSource(t *testing.T) {
+	repo := &fakeRepo{random: &Sentence{ID: 7}}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	rec := httptest.NewRecorder()
+	srv.getRandomSentence(rec, authed(httptest.NewRequest(http.MethodGet, "/api/sentence/random?source=wrong", nil), "u1"))
+	decodeProblem(t, rec, http.StatusBadRequest, CodeInvalidSource)
+	if len(repo.randomSources) != 0 {
+		t.Fatalf("expected repo not called, got %v", repo.randomSources)
+	}
+}
+
 func TestGetRandomSentenceNoCandidate(t *testing.T) {
 	srv := NewServer(&fakeRepo{randomErr: ErrNoCandidate}, &fakeExplainer{}, &fakeAnalyzer{})
 	rec := httptest.NewRecorder()
@@ -359,6 +525,47 @@ func TestCheckAnswerIncorrectRecordsAnswer(t *testing.T) {
 	}
 }
 
+func TestCheckAnswerAcceptsAlternative(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time.", alternatives: []string{"I have no time."}}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":1,"user_answer":"i have no time"}`
+	req := authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader(body)), "u1")
+	rec := httptest.NewRecorder()
+	srv.checkAnswer(rec, req)
+	var resp CheckAnswerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !resp.IsCorrect {
+		t.Fatal("expected an alternative to be graded correct")
+	}
+	if resp.MatchedAnswer != "I have no time." || resp.CorrectAnswer != "I don't have time." {
+		t.Fatalf("expected matched alternative and reference answer, got %+v", resp)
+	}
+	if len(resp.AcceptedAnswers) != 2 {
+		t.Fatalf("expected both accepted answers, got %q", resp.AcceptedAnswers)
+	}
+	if len(repo.recorded) != 1 || !repo.recorded[0].correct || repo.recorded[0].answer != "" {
+		t.Fatalf("expected one correct recorded answer, got %+v", repo.recorded)
+	}
+}
+
+func TestCheckAnswerIncorrectHasNoMatchedAnswer(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time.", alternatives: []string{"I have no time."}}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":1,"user_answer":"I had no time."}`
+	req := authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader(body)), "u1")
+	rec := httptest.NewRecorder()
+	srv.checkAnswer(rec, req)
+	var resp CheckAnswerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.IsCorrect || resp.MatchedAnswer != "" {
+		t.Fatalf("expected incorrect with no matched answer, got %+v", resp)
+	}
+}
+
 // TestCheckAnswerUserAnswerTooLong is a regression test: without a length
 // bound, an authenticated caller could persist arbitrarily large answer text
 // via RecordAnswer, which later flows unbounded into the weakness-insight
@@ -366,7 +573,7 @@ func TestCheckAnswerIncorrectRecordsAnswer(t *testing.T) {
 func TestCheckAnswerUserAnswerTooLong(t *testing.T) {
 	repo := &fakeRepo{correct: "It's hot today."}
 	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
-	longAnswer := strings.Repeat("a", maxUserAnswerLength+1)
+	longAnswer := strings.Repeat("a", DefaultLimits().MaxUserAnswerLength+1)
 	bodyBytes, err := json.Marshal(map[string]any{"sentence_id": 2, "user_answer": longAnswer})
 	if err != nil {
 		t.Fatalf("marshal: %v", err)
@@ -382,6 +589,21 @@ func TestCheckAnswerUserAnswerTooLong(t *testing.T) {
 	}
 }
 
+func TestCheckAnswerHonorsConfiguredLimit(t *testing.T) {
+	repo := &fakeRepo{correct: "Hot."}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	limits := DefaultLimits()
+	limits.MaxUserAnswerLength = 5
+	srv.SetLimits(limits)
+	body := `{"sentence_id":2,"user_answer":"Hot today."}`
+	req := authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader(body)), "u1")
+	rec := httptest.NewRecorder()
+	srv.checkAnswer(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an answer over the configured limit, got %d", rec.Code)
+	}
+}
+
 func TestCheckAnswerNotFound(t *testing.T) {
 	srv := NewServer(&fakeRepo{correctErr: ErrNotFound}, &fakeExplainer{}, &fakeAnalyzer{})
 	body := `{"sentence_id":999,"user_answer":"x"}`
@@ -403,8 +625,44 @@ func TestReportSentence(t *testing.T) {
 	if rec.Code != http.StatusNoContent {
 		t.Fatalf("expected 204, got %d", rec.Code)
 	}
-	if len(repo.reported) != 1 || repo.reported[0] != 5 {
-		t.Fatalf("expected sentence 5 reported, got %+v", repo.reported)
+	want := reportCall{"u1", 5, ReportRecord{Reason: ReportOther}}
+	if len(repo.reported) != 1 || repo.reported[0] != want {
+		t.Fatalf("expected sentence 5 reported by u1 as other, got %+v", repo.reported)
+	}
+}
+
+func TestReportSentenceWithReason(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":5,"reason":"wrong_translation","comment":"  should be past tense "}`
+	rec := httptest.NewRecorder()
+	srv.reportSentence(rec, authed(httptest.NewRequest(http.MethodPost, "/api/sentence/report", strings.NewReader(body)), "u1"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	want := ReportRecord{Reason: ReportWrongTranslation, Comment: "should be past tense"}
+	if len(repo.reported) != 1 || repo.reported[0].rec != want {
+		t.Fatalf("expected %+v, got %+v", want, repo.reported)
+	}
+}
+
+func TestReportSentenceErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body string
+		err  error
+		want int
+	}{
+		"unknown reason":    {`{"sentence_id":5,"reason":"rude"}`, nil, http.StatusBadRequest},
+		"comment too long":  {`{"sentence_id":5,"comment":"` + strings.Repeat("a", maxReportCommentLength+1) + `"}`, nil, http.StatusBadRequest},
+		"unknown sentence":  {`{"sentence_id":5}`, ErrNotFound, http.StatusNotFound},
+		"repository failed": {`{"sentence_id":5}`, errors.New("boom"), http.StatusInternalServerError},
+	} {
+		srv := NewServer(&fakeRepo{reportErr: tc.err}, &fakeExplainer{}, &fakeAnalyzer{})
+		rec := httptest.NewRecorder()
+		srv.reportSentence(rec, authed(httptest.NewRequest(http.MethodPost, "/api/sentence/report", strings.NewReader(tc.body)), "u1"))
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rec.Code)
+		}
 	}
 }
 
@@ -502,7 +760,7 @@ func TestExplainAnswerUserAnswerTooLong(t *testing.T) {
 	explainer := &fakeExplainer{}
 	repo := &fakeRepo{sentenceJapanese: "x", sentenceEnglish: "y"}
 	srv := NewServer(repo, explainer, &fakeAnalyzer{})
-	longAnswer := strings.Repeat("a", maxUserAnswerLength+1)
+	longAnswer := strings.Repeat("a", DefaultLimits().MaxUserAnswerLength+1)
 	bodyBytes, err := json.Marshal(map[string]any{"sentence_id": 1, "user_answer": longAnswer})
 	if err != nil {
 		t.Fatalf("marshal: %v", err)
@@ -605,6 +863,184 @@ func TestExplainAnswerMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestExplainAnswerCached(t *testing.T) {
+	explainer := &fakeExplainer{explanation: "Your answer is natural."}
+	repo := &fakeRepo{sentenceJapanese: "時間がありません。", sentenceEnglish: "I don't have time."}
+	srv := NewServer(repo, explainer, &fakeAnalyzer{})
+	srv.SetGenerationCacheTTL(time.Hour)
+
+	explain := func(body string) ExplainResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.explainAnswer(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", strings.NewReader(body)), "u1"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var resp ExplainResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return resp
+	}
+	if resp := explain(`{"sentence_id":1,"user_answer":"I have no time.","language":"en"}`); resp.Cached {
+		t.Fatalf("expected a fresh explanation, got %+v", resp)
+	}
+	// Whitespace differences normalize to the same cache entry.
+	if resp := explain(`{"sentence_id":1,"user_answer":"  I have  no time. ","language":"en"}`); !resp.Cached || resp.Explanation != explainer.explanation {
+		t.Fatalf("expected the cached explanation, got %+v", resp)
+	}
+	if resp := explain(`{"sentence_id":1,"user_answer":"I have no time.","language":"ja"}`); resp.Cached {
+		t.Fatalf("expected another language to miss the cache, got %+v", resp)
+	}
+	if resp := explain(`{"sentence_id":1,"user_answer":"I have no time.","language":"en","force_refresh":true}`); resp.Cached {
+		t.Fatalf("expected force_refresh to bypass the cache, got %+v", resp)
+	}
+	if len(explainer.calledWith) != 3 {
+		t.Fatalf("expected 3 Explain calls, got %d", len(explainer.calledWith))
+	}
+}
+
+func TestExplainAnswerCacheErrorFallsBackToExplainer(t *testing.T) {
+	explainer := &fakeExplainer{explanation: "fresh"}
+	repo := &fakeRepo{sentenceJapanese: "x", sentenceEnglish: "y", cacheErr: errors.New("cache down")}
+	srv := NewServer(repo, explainer, &fakeAnalyzer{})
+	srv.SetGenerationCacheTTL(time.Hour)
+	rec := httptest.NewRecorder()
+	body := `{"sentence_id":1,"user_answer":"z","language":"en"}`
+	srv.explainAnswer(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", strings.NewReader(body)), "u1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 despite the cache error, got %d", rec.Code)
+	}
+	if len(explainer.calledWith) != 1 {
+		t.Fatalf("expected Explain called once, got %d", len(explainer.calledWith))
+	}
+}
+
+func TestExplainAnswerStreamCached(t *testing.T) {
+	explainer := &fakeExplainer{chunks: []string{"Your answer ", "is fine."}}
+	repo := &fakeRepo{sentenceJapanese: "x", sentenceEnglish: "y"}
+	srv := NewServer(repo, explainer, &fakeAnalyzer{})
+	srv.SetGenerationCacheTTL(time.Hour)
+	body := `{"sentence_id":1,"user_answer":"z","language":"en"}`
+	stream := func() []sseEvent {
+		rec := httptest.NewRecorder()
+		srv.explainAnswerStream(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain/stream", strings.NewReader(body)), "u1"))
+		return parseSSE(t, rec.Body.String())
+	}
+	stream()
+	want := []sseEvent{
+		{"chunk", `{"text":"Your answer is fine."}`},
+		{"done", `{"explanation":"Your answer is fine.","cached":true}`},
+	}
+	if got := stream(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	if len(explainer.calledWith) != 1 {
+		t.Fatalf("expected one ExplainStream call, got %d", len(explainer.calledWith))
+	}
+}
+
+// sseEvent is one parsed Server-Sent Event.
+type sseEvent struct {
+	name string
+	data string
+}
+
+func parseSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		var ev sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				ev.name = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				ev.data = v
+			} else {
+				t.Fatalf("unexpected SSE line %q", line)
+			}
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestExplainAnswerStreamOK(t *testing.T) {
+	explainer := &fakeExplainer{chunks: []string{"Your answer ", "is fine.\nReally."}}
+	repo := &fakeRepo{sentenceJapanese: "時間がありません。", sentenceEnglish: "I don't have time."}
+	srv := NewServer(repo, explainer, &fakeAnalyzer{})
+	body := `{"sentence_id":1,"user_answer":"I have no time.","language":"en"}`
+	rec := httptest.NewRecorder()
+	srv.explainAnswerStream(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain/stream", strings.NewReader(body)), "u1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	want := []sseEvent{
+		{"chunk", `{"text":"Your answer "}`},
+		{"chunk", `{"text":"is fine.\nReally."}`},
+		{"done", `{"explanation":"Your answer is fine.\nReally.","cached":false}`},
+	}
+	if got := parseSSE(t, rec.Body.String()); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	call := explainer.calledWith[0]
+	if call.japanese != "時間がありません。" || call.correctAnswer != "I don't have time." || call.userAnswer != "I have no time." {
+		t.Fatalf("unexpected call args: %+v", call)
+	}
+}
+
+func TestExplainAnswerStreamLLMError(t *testing.T) {
+	explainer := &fakeExplainer{chunks: []string{"Partial "}, err: errors.New("gemini unavailable")}
+	repo := &fakeRepo{sentenceJapanese: "x", sentenceEnglish: "y"}
+	srv := NewServer(repo, explainer, &fakeAnalyzer{})
+	body := `{"sentence_id":1,"user_answer":"z","language":"en"}`
+	rec := httptest.NewRecorder()
+	srv.explainAnswerStream(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain/stream", strings.NewReader(body)), "u1"))
+	want := []sseEvent{
+		{"chunk", `{"text":"Partial "}`},
+		{"error", `{"error":"Internal server error","code":"internal"}`},
+	}
+	if got := parseSSE(t, rec.Body.String()); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+}
+
+// TestExplainAnswerStreamRejectsBeforeStreaming checks request errors keep
+// their plain HTTP statuses: the stream has not started when they occur.
+func TestExplainAnswerStreamRejectsBeforeStreaming(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		repo   *fakeRepo
+		body   string
+		want   int
+	}{
+		{"method", http.MethodGet, &fakeRepo{}, "", http.StatusMethodNotAllowed},
+		{"invalid language", http.MethodPost, &fakeRepo{}, `{"sentence_id":1,"user_answer":"z","language":"fr"}`, http.StatusBadRequest},
+		{"sentence not found", http.MethodPost, &fakeRepo{sentenceErr: ErrNotFound}, `{"sentence_id":1,"user_answer":"z","language":"en"}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			explainer := &fakeExplainer{}
+			srv := NewServer(tc.repo, explainer, &fakeAnalyzer{})
+			rec := httptest.NewRecorder()
+			srv.explainAnswerStream(rec, authed(httptest.NewRequest(tc.method, "/api/answer/explain/stream", strings.NewReader(tc.body)), "u1"))
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct == "text/event-stream" {
+				t.Fatal("expected a plain error response, not an event stream")
+			}
+			if len(explainer.calledWith) != 0 {
+				t.Fatal("explainer should not be called when the request is rejected")
+			}
+		})
+	}
+}
+
 func TestGetMistakesOK(t *testing.T) {
 	repo := &fakeRepo{mistakes: []MistakeSentence{
 		{
@@ -677,6 +1113,80 @@ func TestGetMistakesMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestGetMistakesPassesQueryToRepository(t *testing.T) {
+	repo := &fakeRepo{mistakes: []MistakeSentence{{SentenceID: 1, WrongAnswers: []AnswerHistory{}}}, mistakesNext: "next-page"}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	rec := httptest.NewRecorder()
+	url := "/api/mistakes?limit=5&cursor=abc&levels=2,1&since=2026-03-01&min_incorrect=2&unmastered=true&sort=worst_accuracy"
+	srv.getMistakes(rec, authed(httptest.NewRequest(http.MethodGet, url, nil), "u1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	want := MistakeQuery{
+		Levels:       []int{2, 1},
+		Since:        time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		MinIncorrect: 2,
+		Unmastered:   true,
+		Sort:         MistakesByWorstAccuracy,
+		Limit:        5,
+		Cursor:       "abc",
+	}
+	if !reflect.DeepEqual(repo.mistakesQuery, want) {
+		t.Fatalf("expected query %+v, got %+v", want, repo.mistakesQuery)
+	}
+	var resp ListMistakesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.NextCursor != "next-page" {
+		t.Fatalf("expected the next cursor passed through, got %q", resp.NextCursor)
+	}
+}
+
+func TestGetMistakesDefaultsToFirstPageOfRecent(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	rec := httptest.NewRecorder()
+	srv.getMistakes(rec, authed(httptest.NewRequest(http.MethodGet, "/api/mistakes", nil), "u1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if q := repo.mistakesQuery; q.Limit != defaultMistakesPageSize || q.Sort != "" || q.Cursor != "" {
+		t.Fatalf("expected the default page, got %+v", q)
+	}
+	if strings.Contains(rec.Body.String(), "next_cursor") {
+		t.Fatalf("expected no next_cursor on the last page, got %s", rec.Body)
+	}
+}
+
+func TestGetMistakesInvalidQuery(t *testing.T) {
+	for query, code := range map[string]string{
+		"limit=0":          CodeInvalidLimit,
+		"limit=101":        CodeInvalidLimit,
+		"levels=6":         CodeInvalidLevels,
+		"since=yesterday":  CodeInvalidSince,
+		"min_incorrect=-1": CodeInvalidMinIncorrect,
+		"unmastered=maybe": CodeInvalidUnmastered,
+		"sort=alphabetic":  CodeInvalidSort,
+	} {
+		repo := &fakeRepo{}
+		srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+		rec := httptest.NewRecorder()
+		srv.getMistakes(rec, authed(httptest.NewRequest(http.MethodGet, "/api/mistakes?"+query, nil), "u1"))
+		decodeProblem(t, rec, http.StatusBadRequest, code)
+		if repo.listMistakesCalls != 0 {
+			t.Errorf("%s: expected the repository not to be called", query)
+		}
+	}
+}
+
+func TestGetMistakesInvalidCursor(t *testing.T) {
+	srv := NewServer(&fakeRepo{mistakesErr: ErrInvalidCursor}, &fakeExplainer{}, &fakeAnalyzer{})
+	rec := httptest.NewRecorder()
+	srv.getMistakes(rec, authed(httptest.NewRequest(http.MethodGet, "/api/mistakes?cursor=stale", nil), "u1"))
+	decodeProblem(t, rec, http.StatusBadRequest, CodeInvalidCursor)
+}
+
 func TestGetMistakesInsightOK(t *testing.T) {
 	analyzer := &fakeAnalyzer{insight: "You often drop articles like 'the'."}
 	repo := &fakeRepo{mistakes: []MistakeSentence{
@@ -729,7 +1239,7 @@ func TestGetMistakesInsightEmptySkipsAnalyzer(t *testing.T) {
 }
 
 func TestGetMistakesInsightCapsToMostRecent(t *testing.T) {
-	many := make([]MistakeSentence, maxInsightMistakes+10)
+	many := make([]MistakeSentence, DefaultLimits().MaxInsightMistakes+10)
 	for i := range many {
 		many[i] = MistakeSentence{SentenceID: i, Japanese: "x", CorrectAnswer: "y", WrongAnswers: []AnswerHistory{{IncorrectAnswer: "z"}}}
 	}
@@ -740,8 +1250,8 @@ func TestGetMistakesInsightCapsToMostRecent(t *testing.T) {
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rec.Code)
 	}
-	if len(analyzer.calledWith) != 1 || len(analyzer.calledWith[0].mistakes) != maxInsightMistakes {
-		t.Fatalf("expected analyzer called with %d mistakes, got %d", maxInsightMistakes, len(analyzer.calledWith[0].mistakes))
+	if len(analyzer.calledWith) != 1 || len(analyzer.calledWith[0].mistakes) != DefaultLimits().MaxInsightMistakes {
+		t.Fatalf("expected analyzer called with %d mistakes, got %d", DefaultLimits().MaxInsightMistakes, len(analyzer.calledWith[0].mistakes))
 	}
 }
 
@@ -823,3 +1333,232 @@ func TestGetMistakesInsightMethodNotAllowed(t *testing.T) {
 		t.Fatalf("expected 405, got %d", rec.Code)
 	}
 }
+
+func TestGetMistakesInsightCached(t *testing.T) {
+	analyzer := &fakeAnalyzer{insight: "You often drop articles."}
+	repo := &fakeRepo{mistakes: []MistakeSentence{{SentenceID: 1, Japanese: "x", CorrectAnswer: "y", WrongAnswers: []AnswerHistory{{IncorrectAnswer: "z"}}}}}
+	srv := NewServer(repo, &fakeExplainer{}, analyzer)
+	srv.SetGenerationCacheTTL(time.Hour)
+
+	get := func(query string) MistakesInsightResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.getMistakesInsight(rec, authed(httptest.NewRequest(http.MethodGet, "/api/mistakes/insight?"+query, nil), "u1"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var resp MistakesInsightResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return resp
+	}
+	if resp := get("language=en"); resp.Cached || resp.Insight != analyzer.insight {
+		t.Fatalf("expected a fresh insight, got %+v", resp)
+	}
+	if resp := get("language=en"); !resp.Cached || resp.Insight != analyzer.insight {
+		t.Fatalf("expected the cached insight, got %+v", resp)
+	}
+	if len(analyzer.calledWith) != 1 {
+		t.Fatalf("expected one Analyze call for an unchanged mistake set, got %d", len(analyzer.calledWith))
+	}
+	if resp := get("language=en&force_refresh=true"); resp.Cached {
+		t.Fatalf("expected force_refresh to bypass the cache, got %+v", resp)
+	}
+	repo.mistakes = append(repo.mistakes, MistakeSentence{SentenceID: 2, Japanese: "a", CorrectAnswer: "b", WrongAnswers: []AnswerHistory{{IncorrectAnswer: "c"}}})
+	if resp := get("language=en"); resp.Cached {
+		t.Fatalf("expected a new mistake to miss the cache, got %+v", resp)
+	}
+	if len(analyzer.calledWith) != 3 {
+		t.Fatalf("expected 3 Analyze calls, got %d", len(analyzer.calledWith))
+	}
+}
+
+func TestGetMistakesInsightInvalidForceRefresh(t *testing.T) {
+	analyzer := &fakeAnalyzer{insight: "x"}
+	repo := &fakeRepo{mistakes: []MistakeSentence{{SentenceID: 1, Japanese: "x", CorrectAnswer: "y", WrongAnswers: []AnswerHistory{{IncorrectAnswer: "z"}}}}}
+	srv := NewServer(repo, &fakeExplainer{}, analyzer)
+	rec := httptest.NewRecorder()
+	srv.getMistakesInsight(rec, authed(httptest.NewRequest(http.MethodGet, "/api/mistakes/insight?language=en&force_refresh=maybe", nil), "u1"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if len(analyzer.calledWith) != 0 {
+		t.Fatal("analyzer must not be called for an invalid force_refresh")
+	}
+}
+
+func checkWithGrader(t *testing.T, repo *fakeRepo, grader *fakeGrader, userAnswer string) CheckAnswerResponse {
+	t.Helper()
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	srv.SetGrader(grader)
+	body, _ := json.Marshal(CheckAnswerRequest{SentenceID: 1, UserAnswer: userAnswer})
+	req := authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", bytes.NewReader(body)), "u1")
+	rec := httptest.NewRecorder()
+	srv.checkAnswer(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp CheckAnswerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return resp
+}
+
+func TestCheckAnswerGraderAcceptsMismatch(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time.", sentenceJapanese: "時間がありません。"}
+	grader := &fakeGrader{verdict: GradeVerdict{Accepted: true, Reason: "Same meaning."}}
+	resp := checkWithGrader(t, repo, grader, "I have no time at all.")
+	if !resp.IsCorrect || resp.GradedBy != GradedAI || resp.GraderReason != "Same meaning." {
+		t.Fatalf("expected an AI acceptance, got %+v", resp)
+	}
+	if resp.MatchedAnswer != "" {
+		t.Fatalf("no accepted answer matched, got matched_answer %q", resp.MatchedAnswer)
+	}
+	if resp.HistoryID == 0 {
+		t.Fatal("expected the recorded history id in the response")
+	}
+	if len(grader.calledWith) != 1 || grader.calledWith[0].japanese != "時間がありません。" ||
+		grader.calledWith[0].userAnswer != "I have no time at all." {
+		t.Fatalf("unexpected grader calls: %+v", grader.calledWith)
+	}
+	want := recordedAnswer{"u1", 1, true, "I have no time at all.", GradedAI, "Same meaning."}
+	if len(repo.recorded) != 1 || repo.recorded[0] != want {
+		t.Fatalf("expected %+v recorded, got %+v", want, repo.recorded)
+	}
+}
+
+func TestCheckAnswerGraderRejectsMismatch(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time."}
+	grader := &fakeGrader{verdict: GradeVerdict{Accepted: false, Reason: "Wrong tense."}}
+	resp := checkWithGrader(t, repo, grader, "I didn't have time.")
+	if resp.IsCorrect || resp.GradedBy != GradedAI || resp.GraderReason != "Wrong tense." {
+		t.Fatalf("expected an AI rejection, got %+v", resp)
+	}
+	want := recordedAnswer{"u1", 1, false, "I didn't have time.", GradedAI, "Wrong tense."}
+	if len(repo.recorded) != 1 || repo.recorded[0] != want {
+		t.Fatalf("expected %+v recorded, got %+v", want, repo.recorded)
+	}
+}
+
+func TestCheckAnswerGraderSkippedOnExactMatch(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time."}
+	grader := &fakeGrader{verdict: GradeVerdict{Accepted: false}}
+	resp := checkWithGrader(t, repo, grader, "I don't have time")
+	if !resp.IsCorrect || resp.GradedBy != GradedExact {
+		t.Fatalf("expected an exact match, got %+v", resp)
+	}
+	if len(grader.calledWith) != 0 {
+		t.Fatal("grader must not be called for an exact match")
+	}
+}
+
+func TestCheckAnswerGraderErrorFallsBackToExactRejection(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time."}
+	grader := &fakeGrader{err: errors.New("gemini unavailable")}
+	resp := checkWithGrader(t, repo, grader, "I have no time.")
+	if resp.IsCorrect || resp.GradedBy != GradedExact || resp.GraderReason != "" {
+		t.Fatalf("expected the exact-match rejection to stand, got %+v", resp)
+	}
+	if len(repo.recorded) != 1 || repo.recorded[0].gradedBy != GradedExact || repo.recorded[0].answer != "I have no time." {
+		t.Fatalf("expected an exact rejection recorded, got %+v", repo.recorded)
+	}
+}
+
+func TestCheckAnswerWithoutGraderIsExact(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time."}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":1,"user_answer":"I have no time."}`
+	rec := httptest.NewRecorder()
+	srv.checkAnswer(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader(body)), "u1"))
+	var resp CheckAnswerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.IsCorrect || resp.GradedBy != GradedExact {
+		t.Fatalf("expected an exact rejection, got %+v", resp)
+	}
+}
+
+func TestOverrideAnswer(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":3,"history_id":1700000000000000,"is_correct":true}`
+	rec := httptest.NewRecorder()
+	srv.overrideAnswer(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/override", strings.NewReader(body)), "u1"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	want := overrideCall{"u1", 3, 1700000000000000, AnswerOverride{Correct: true}}
+	if len(repo.overrides) != 1 || repo.overrides[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, repo.overrides)
+	}
+}
+
+// asAdmin marks req as coming from an admin, as withAdminStatus would.
+func asAdmin(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), adminCtxKey, true))
+}
+
+func TestOverrideAnswerPromote(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":3,"history_id":42,"is_correct":true,"promote":true}`
+	rec := httptest.NewRecorder()
+	srv.overrideAnswer(rec, asAdmin(authed(httptest.NewRequest(http.MethodPost, "/api/answer/override", strings.NewReader(body)), "u1")))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	want := overrideCall{"u1", 3, 42, AnswerOverride{Correct: true, Promote: true}}
+	if len(repo.overrides) != 1 || repo.overrides[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, repo.overrides)
+	}
+}
+
+func TestOverrideAnswerPromoteRequiresAdmin(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	body := `{"sentence_id":3,"history_id":42,"is_correct":true,"promote":true}`
+	rec := httptest.NewRecorder()
+	srv.overrideAnswer(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/override", strings.NewReader(body)), "u1"))
+	decodeProblem(t, rec, http.StatusForbidden, CodeForbidden)
+	if len(repo.overrides) != 0 {
+		t.Fatalf("expected nothing overridden, got %+v", repo.overrides)
+	}
+}
+
+func TestOverrideAnswerErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		body string
+		want int
+	}{
+		{"not found", ErrHistoryNotFound, `{"sentence_id":3,"history_id":1,"is_correct":true}`, http.StatusNotFound},
+		{"sentence not found", ErrNotFound, `{"sentence_id":3,"history_id":1,"is_correct":true,"promote":true}`, http.StatusNotFound},
+		{"promote incorrect", nil, `{"sentence_id":3,"history_id":1,"is_correct":false,"promote":true}`, http.StatusBadRequest},
+		{"not overridable", ErrNotOverridable, `{"sentence_id":3,"history_id":1,"is_correct":true}`, http.StatusConflict},
+		{"repo error", errors.New("boom"), `{"sentence_id":3,"history_id":1,"is_correct":true}`, http.StatusInternalServerError},
+		{"invalid body", nil, `{`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := NewServer(&fakeRepo{overrideErr: tc.err}, &fakeExplainer{}, &fakeAnalyzer{})
+			rec := httptest.NewRecorder()
+			srv.overrideAnswer(rec, asAdmin(authed(httptest.NewRequest(http.MethodPost, "/api/answer/override", strings.NewReader(tc.body)), "u1")))
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestOverrideAnswerMethodNotAllowed(t *testing.T) {
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	rec := httptest.NewRecorder()
+	srv.overrideAnswer(rec, authed(httptest.NewRequest(http.MethodGet, "/api/answer/override", nil), "u1"))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
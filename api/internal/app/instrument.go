@@ -0,0 +1,257 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+// The instrumented decorators report each call through the repository and
+// LLM seams to both Metrics and the tracer, so a slow request's trace and
+// the latency histograms describe the same calls.
+
+// repoOutcome classifies a repository error for metrics and traces. The sentinel
+// errors are answers, not failures: a missing sentence is a fast,
+// successful lookup.
+func repoOutcome(err error) string {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrNoCandidate),
+		errors.Is(err, ErrHistoryNotFound),
+		errors.Is(err, ErrInvalidCursor),
+		errors.Is(err, ErrNotOverridable):
+		return "ok"
+	}
+	return "error"
+}
+
+// InstrumentRepository times every call to repo in m and traces it as a
+// span (see StartTracing).
+func InstrumentRepository(repo SentenceRepository, m *Metrics) SentenceRepository {
+	return instrumentedRepo{repo, m}
+}
+
+type instrumentedRepo struct {
+	next SentenceRepository
+	m    *Metrics
+}
+
+// start opens op's span and returns the context to call op with and a
+// function that ends the span and records op's latency given its error.
+func (r instrumentedRepo) start(ctx context.Context, op string) (context.Context, func(*error)) {
+	begin := time.Now()
+	ctx, span := tracer().Start(ctx, "SentenceRepository."+op)
+	return ctx, func(err *error) {
+		outcome := repoOutcome(*err)
+		if outcome == "error" {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+		r.m.observeRepo(op, outcome, begin)
+	}
+}
+
+func (r instrumentedRepo) RandomCandidate(ctx context.Context, uid string, q CandidateQuery) (_ *Sentence, err error) {
+	ctx, done := r.start(ctx, "RandomCandidate")
+	defer done(&err)
+	return r.next.RandomCandidate(ctx, uid, q)
+}
+
+func (r instrumentedRepo) AcceptedAnswers(ctx context.Context, id int) (_ []string, _ int, err error) {
+	ctx, done := r.start(ctx, "AcceptedAnswers")
+	defer done(&err)
+	return r.next.AcceptedAnswers(ctx, id)
+}
+
+func (r instrumentedRepo) GetSentence(ctx context.Context, id int) (_, _ string, err error) {
+	ctx, done := r.start(ctx, "GetSentence")
+	defer done(&err)
+	return r.next.GetSentence(ctx, id)
+}
+
+func (r instrumentedRepo) ListIncorrectHistories(ctx context.Context, uid string, id int) (_ []AnswerHistory, err error) {
+	ctx, done := r.start(ctx, "ListIncorrectHistories")
+	defer done(&err)
+	return r.next.ListIncorrectHistories(ctx, uid, id)
+}
+
+func (r instrumentedRepo) ListMistakes(ctx context.Context, uid string, q MistakeQuery) (_ []MistakeSentence, _ string, err error) {
+	ctx, done := r.start(ctx, "ListMistakes")
+	defer done(&err)
+	return r.next.ListMistakes(ctx, uid, q)
+}
+
+func (r instrumentedRepo) ListMistakesForInsight(ctx context.Context, uid string) (_ []MistakeSentence, err error) {
+	ctx, done := r.start(ctx, "ListMistakesForInsight")
+	defer done(&err)
+	return r.next.ListMistakesForInsight(ctx, uid)
+}
+
+func (r instrumentedRepo) RecordAnswer(ctx context.Context, uid string, id int, rec AnswerRecord) (_ int64, err error) {
+	ctx, done := r.start(ctx, "RecordAnswer")
+	defer done(&err)
+	return r.next.RecordAnswer(ctx, uid, id, rec)
+}
+
+func (r instrumentedRepo) OverrideAnswer(ctx context.Context, uid string, id int, historyID int64, ov AnswerOverride) (err error) {
+	ctx, done := r.start(ctx, "OverrideAnswer")
+	defer done(&err)
+	return r.next.OverrideAnswer(ctx, uid, id, historyID, ov)
+}
+
+func (r instrumentedRepo) Report(ctx context.Context, uid string, id int, rec ReportRecord) (err error) {
+	ctx, done := r.start(ctx, "Report")
+	defer done(&err)
+	return r.next.Report(ctx, uid, id, rec)
+}
+
+func (r instrumentedRepo) ListReported(ctx context.Context) (_ []AdminSentence, err error) {
+	ctx, done := r.start(ctx, "ListReported")
+	defer done(&err)
+	return r.next.ListReported(ctx)
+}
+
+func (r instrumentedRepo) UpdateSentence(ctx context.Context, id int, edit SentenceEdit) (err error) {
+	ctx, done := r.start(ctx, "UpdateSentence")
+	defer done(&err)
+	return r.next.UpdateSentence(ctx, id, edit)
+}
+
+func (r instrumentedRepo) Unreport(ctx context.Context, id int) (err error) {
+	ctx, done := r.start(ctx, "Unreport")
+	defer done(&err)
+	return r.next.Unreport(ctx, id)
+}
+
+func (r instrumentedRepo) DeleteSentence(ctx context.Context, id int) (err error) {
+	ctx, done := r.start(ctx, "DeleteSentence")
+	defer done(&err)
+	return r.next.DeleteSentence(ctx, id)
+}
+
+func (r instrumentedRepo) CachedGeneration(ctx context.Context, key string) (_ string, _ bool, err error) {
+	ctx, done := r.start(ctx, "CachedGeneration")
+	defer done(&err)
+	return r.next.CachedGeneration(ctx, key)
+}
+
+func (r instrumentedRepo) CacheGeneration(ctx context.Context, key, text string, ttl time.Duration) (err error) {
+	ctx, done := r.start(ctx, "CacheGeneration")
+	defer done(&err)
+	return r.next.CacheGeneration(ctx, key, text, ttl)
+}
+
+func (r instrumentedRepo) UpdateRateState(ctx context.Context, key string, fn func(RateState) RateState) (err error) {
+	ctx, done := r.start(ctx, "UpdateRateState")
+	defer done(&err)
+	return r.next.UpdateRateState(ctx, key, fn)
+}
+
+func (r instrumentedRepo) CreateSession(ctx context.Context, uid string, s PracticeSession) (err error) {
+	ctx, done := r.start(ctx, "CreateSession")
+	defer done(&err)
+	return r.next.CreateSession(ctx, uid, s)
+}
+
+func (r instrumentedRepo) GetSession(ctx context.Context, uid, id string) (_ PracticeSession, err error) {
+	ctx, done := r.start(ctx, "GetSession")
+	defer done(&err)
+	return r.next.GetSession(ctx, uid, id)
+}
+
+func (r instrumentedRepo) UpdateSession(ctx context.Context, uid, id string, fn func(*PracticeSession) error) (err error) {
+	ctx, done := r.start(ctx, "UpdateSession")
+	defer done(&err)
+	return r.next.UpdateSession(ctx, uid, id, fn)
+}
+
+func (r instrumentedRepo) Ping(ctx context.Context) (err error) {
+	ctx, done := r.start(ctx, "Ping")
+	defer done(&err)
+	return r.next.Ping(ctx)
+}
+
+// InstrumentContentGenerator counts, times and traces the calls made
+// through gen, and records the tokens each response's usage metadata
+// reports.
+func InstrumentContentGenerator(gen contentGenerator, m *Metrics) contentGenerator {
+	return instrumentedGenerator{gen, m}
+}
+
+type instrumentedGenerator struct {
+	next contentGenerator
+	m    *Metrics
+}
+
+func (g instrumentedGenerator) provider() string {
+	if p, ok := g.next.(interface{ provider() string }); ok {
+		return p.provider()
+	}
+	return ""
+}
+
+func (g instrumentedGenerator) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	ctx, done := g.start(ctx, model, "generate")
+	resp, err := g.next.GenerateContent(ctx, model, contents, config)
+	var usage *genai.GenerateContentResponseUsageMetadata
+	if resp != nil {
+		usage = resp.UsageMetadata
+	}
+	done(usage, err)
+	return resp, err
+}
+
+// GenerateContentStream records the call when the stream ends. Gemini
+// reports running totals on each chunk, so the last usage seen is the
+// call's.
+func (g instrumentedGenerator) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		ctx, done := g.start(ctx, model, "stream")
+		var usage *genai.GenerateContentResponseUsageMetadata
+		var err error
+		defer func() { done(usage, err) }()
+		for resp, respErr := range g.next.GenerateContentStream(ctx, model, contents, config) {
+			if resp != nil && resp.UsageMetadata != nil {
+				usage = resp.UsageMetadata
+			}
+			if respErr != nil {
+				err = respErr
+			}
+			if !yield(resp, respErr) {
+				return
+			}
+		}
+	}
+}
+
+// start opens a call's span and returns the context to make the call with
+// and a function that ends the span and records the call given its usage
+// and error.
+func (g instrumentedGenerator) start(ctx context.Context, model, kind string) (context.Context, func(*genai.GenerateContentResponseUsageMetadata, error)) {
+	begin := time.Now()
+	ctx, span := tracer().Start(ctx, "llm."+kind, trace.WithAttributes(
+		attribute.String("gen_ai.request.model", model),
+	))
+	return ctx, func(usage *genai.GenerateContentResponseUsageMetadata, err error) {
+		if usage != nil {
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokenCount)),
+				attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount)),
+			)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		g.m.observeLLM(model, kind, begin, usage, err)
+	}
+}
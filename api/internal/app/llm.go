@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// LLM providers LLMConfig.Provider accepts.
+const (
+	ProviderGemini = "gemini"
+	// ProviderOpenAI is any server speaking the OpenAI chat completions API:
+	// OpenAI itself, or a local Ollama or llama.cpp server.
+	ProviderOpenAI = "openai"
+)
+
+const (
+	geminiExplainModel = "gemini-3.1-flash-lite"
+	explainTimeout     = 20 * time.Second
+
+	// maxExplainOutputTokens bounds the size of the explanation response
+	// itself. buildExplainPrompt asks for a concise 2-4 sentence answer, but
+	// nothing stops the model from ignoring that instruction — mirrors the
+	// same safeguard on the weakness-insight path (maxInsightOutputTokens).
+	maxExplainOutputTokens = 512
+
+	// defaultOpenAIBaseURL is used when LLMConfig.BaseURL is empty.
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+)
+
+// contentGenerator is the seam between the LLM-backed Explainer,
+// WeaknessAnalyzer and Grader and the model provider, so tests can
+// substitute a fake instead of making real network calls. *genai.Models
+// satisfies this interface structurally; openAIChatClient adapts an
+// OpenAI-compatible endpoint to it.
+type contentGenerator interface {
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	// GenerateContentStream yields partial responses as the model produces
+	// them; each carries only the text generated since the previous one.
+	GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error]
+}
+
+// ModelSettings configures one use of the LLM: which model serves it, how
+// long a call may take before the caller falls back, and how long the
+// response may be.
+type ModelSettings struct {
+	Model           string
+	Timeout         time.Duration
+	MaxOutputTokens int32
+}
+
+// LLMConfig selects the model provider and configures each use case
+// independently, so e.g. grading can use a small fast model while insights
+// use a larger one.
+type LLMConfig struct {
+	// Provider is ProviderGemini (the default when empty) or ProviderOpenAI.
+	Provider string
+	// APIKey is required for Gemini. OpenAI-compatible servers that need no
+	// key, such as Ollama, may leave it empty.
+	APIKey string
+	// BaseURL is the OpenAI-compatible API root, e.g.
+	// http://localhost:11434/v1 for Ollama. Ignored for Gemini.
+	BaseURL string
+
+	Explain ModelSettings
+	Insight ModelSettings
+	Grade   ModelSettings
+}
+
+// DefaultLLMConfig is Gemini with the models, timeouts and output bounds the
+// explainer, weakness analyzer and grader have always used.
+func DefaultLLMConfig() LLMConfig {
+	return LLMConfig{
+		Provider: ProviderGemini,
+		Explain:  ModelSettings{Model: geminiExplainModel, Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens},
+		Insight:  ModelSettings{Model: geminiExplainModel, Timeout: explainTimeout, MaxOutputTokens: maxInsightOutputTokens},
+		Grade:    ModelSettings{Model: geminiExplainModel, Timeout: gradeTimeout, MaxOutputTokens: maxGradeOutputTokens},
+	}
+}
+
+// Validate reports the first setting that would make the LLM unusable for
+// explanations or insights. Grade is checked separately by ValidateGrade,
+// since only AI grading uses it.
+func (c LLMConfig) Validate() error {
+	switch c.Provider {
+	case "", ProviderGemini:
+		if c.APIKey == "" {
+			return errors.New("gemini provider requires an API key")
+		}
+	case ProviderOpenAI:
+	default:
+		return fmt.Errorf("unknown LLM provider %q (want %s or %s)", c.Provider, ProviderGemini, ProviderOpenAI)
+	}
+	if err := c.Explain.validate("explain"); err != nil {
+		return err
+	}
+	return c.Insight.validate("insight")
+}
+
+// ValidateGrade reports whether Grade is usable, for a server that grades
+// answers with the LLM.
+func (c LLMConfig) ValidateGrade() error {
+	return c.Grade.validate("grade")
+}
+
+func (s ModelSettings) validate(name string) error {
+	if s.Model == "" {
+		return fmt.Errorf("%s: model is required", name)
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("%s: timeout must be positive", name)
+	}
+	if s.MaxOutputTokens <= 0 {
+		return fmt.Errorf("%s: max output tokens must be positive", name)
+	}
+	return nil
+}
+
+// NewContentGenerator validates cfg and connects to its provider. The
+// result is shared by every LLM-backed component. The returned close
+// function releases its connections and must be called on shutdown, once
+// no more calls will be made.
+func NewContentGenerator(ctx context.Context, cfg LLMConfig) (contentGenerator, func() error, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	// Each provider gets its own HTTP client, so closing it cannot affect
+	// anything else sharing http.DefaultTransport.
+	hc := &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	closeClient := func() error {
+		hc.CloseIdleConnections()
+		return nil
+	}
+	if cfg.Provider == ProviderOpenAI {
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = defaultOpenAIBaseURL
+		}
+		return &openAIChatClient{
+			baseURL: strings.TrimRight(baseURL, "/"),
+			apiKey:  cfg.APIKey,
+			http:    hc,
+		}, closeClient, nil
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     cfg.APIKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: hc,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("create genai client: %w", err)
+	}
+	return geminiModels{client.Models}, closeClient, nil
+}
+
+// modelID names model qualified by the provider gen serves it from, when gen
+// can say, so the same model name on two providers is told apart.
+func modelID(gen contentGenerator, model string) string {
+	if p, ok := gen.(interface{ provider() string }); ok && p.provider() != "" {
+		return p.provider() + "/" + model
+	}
+	return model
+}
+
+// geminiModels is the Gemini API's contentGenerator.
+type geminiModels struct {
+	*genai.Models
+}
+
+func (geminiModels) provider() string { return ProviderGemini }
+
+// listModels fetches one page of one model: the cheapest call that proves
+// the API is reachable and accepts the key, for /api/readiness.
+func (m geminiModels) listModels(ctx context.Context) error {
+	_, err := m.List(ctx, &genai.ListModelsConfig{PageSize: 1})
+	return err
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// maxInsightOutputTokens bounds the size of the weakness-insight response
+// itself. buildWeaknessPrompt asks for a short summary plus a few bullet
+// points, but nothing stops the model from ignoring that instruction — the
+// input side is tightly bounded (maxPromptChars, maxWrongAnswersPerSentence,
+// Limits.MaxInsightMistakes) and the output side should be too.
+const maxInsightOutputTokens = 1024
+
+// LLMWeaknessAnalyzer implements WeaknessAnalyzer using the configured LLM
+// provider.
+type LLMWeaknessAnalyzer struct {
+	models   contentGenerator
+	settings ModelSettings
+}
+
+func NewLLMWeaknessAnalyzer(models contentGenerator, settings ModelSettings) *LLMWeaknessAnalyzer {
+	return &LLMWeaknessAnalyzer{models: models, settings: settings}
+}
+
+func (g *LLMWeaknessAnalyzer) generationModel() string {
+	return modelID(g.models, g.settings.Model)
+}
+
+func (g *LLMWeaknessAnalyzer) Analyze(ctx context.Context, mistakes []MistakeSentence, language string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.settings.Timeout)
+	defer cancel()
+
+	prompt := buildWeaknessPrompt(mistakes, language)
+	contents := []*genai.Content{{Parts: []*genai.Part{{Text: prompt}}}}
+
+	config := &genai.GenerateContentConfig{MaxOutputTokens: g.settings.MaxOutputTokens}
+	resp, err := g.models.GenerateContent(ctx, g.settings.Model, contents, config)
+	if err != nil {
+		return "", fmt.Errorf("generate content: %w", err)
+	}
+	return resp.Text(), nil
+}
@@ -8,7 +8,7 @@ import (
 	"google.golang.org/genai"
 )
 
-func TestGeminiWeaknessAnalyzerReturnsText(t *testing.T) {
+func TestLLMWeaknessAnalyzerReturnsText(t *testing.T) {
 	fake := &fakeContentGenerator{
 		resp: &genai.GenerateContentResponse{
 			Candidates: []*genai.Candidate{
@@ -16,7 +16,7 @@ func TestGeminiWeaknessAnalyzerReturnsText(t *testing.T) {
 			},
 		},
 	}
-	g := &GeminiWeaknessAnalyzer{models: fake, model: "gemini-test"}
+	g := NewLLMWeaknessAnalyzer(fake, ModelSettings{Model: "gemini-test", Timeout: explainTimeout, MaxOutputTokens: maxInsightOutputTokens})
 
 	got, err := g.Analyze(context.Background(),
 		[]MistakeSentence{{Japanese: "x", CorrectAnswer: "y", WrongAnswers: []AnswerHistory{{IncorrectAnswer: "z"}}}},
@@ -35,7 +35,7 @@ func TestGeminiWeaknessAnalyzerReturnsText(t *testing.T) {
 	}
 }
 
-func TestGeminiWeaknessAnalyzerCapsMaxOutputTokens(t *testing.T) {
+func TestLLMWeaknessAnalyzerCapsMaxOutputTokens(t *testing.T) {
 	fake := &fakeContentGenerator{
 		resp: &genai.GenerateContentResponse{
 			Candidates: []*genai.Candidate{
@@ -43,7 +43,7 @@ func TestGeminiWeaknessAnalyzerCapsMaxOutputTokens(t *testing.T) {
 			},
 		},
 	}
-	g := &GeminiWeaknessAnalyzer{models: fake, model: "gemini-test"}
+	g := NewLLMWeaknessAnalyzer(fake, ModelSettings{Model: "gemini-test", Timeout: explainTimeout, MaxOutputTokens: maxInsightOutputTokens})
 
 	if _, err := g.Analyze(context.Background(),
 		[]MistakeSentence{{Japanese: "x", CorrectAnswer: "y", WrongAnswers: []AnswerHistory{{IncorrectAnswer: "z"}}}},
@@ -58,9 +58,9 @@ func TestGeminiWeaknessAnalyzerCapsMaxOutputTokens(t *testing.T) {
 	}
 }
 
-func TestGeminiWeaknessAnalyzerPropagatesError(t *testing.T) {
+func TestLLMWeaknessAnalyzerPropagatesError(t *testing.T) {
 	fake := &fakeContentGenerator{err: errors.New("network error")}
-	g := &GeminiWeaknessAnalyzer{models: fake, model: "gemini-test"}
+	g := NewLLMWeaknessAnalyzer(fake, ModelSettings{Model: "gemini-test", Timeout: explainTimeout, MaxOutputTokens: maxInsightOutputTokens})
 
 	_, err := g.Analyze(context.Background(), []MistakeSentence{{Japanese: "x"}}, "en")
 	if err == nil {
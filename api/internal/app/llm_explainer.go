@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// LLMExplainer implements Explainer using the configured LLM provider.
+type LLMExplainer struct {
+	models   contentGenerator
+	settings ModelSettings
+}
+
+func NewLLMExplainer(models contentGenerator, settings ModelSettings) *LLMExplainer {
+	return &LLMExplainer{models: models, settings: settings}
+}
+
+func (g *LLMExplainer) generationModel() string {
+	return modelID(g.models, g.settings.Model)
+}
+
+func (g *LLMExplainer) Explain(ctx context.Context, japanese, correctAnswer, userAnswer, language string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.settings.Timeout)
+	defer cancel()
+
+	contents, config := g.request(japanese, correctAnswer, userAnswer, language)
+	resp, err := g.models.GenerateContent(ctx, g.settings.Model, contents, config)
+	if err != nil {
+		return "", fmt.Errorf("generate content: %w", err)
+	}
+	return resp.Text(), nil
+}
+
+// ExplainStream sends the same request as Explain; the timeout covers the
+// whole stream, not each chunk.
+func (g *LLMExplainer) ExplainStream(ctx context.Context, japanese, correctAnswer, userAnswer, language string, emit func(chunk string) error) error {
+	ctx, cancel := context.WithTimeout(ctx, g.settings.Timeout)
+	defer cancel()
+
+	contents, config := g.request(japanese, correctAnswer, userAnswer, language)
+	for resp, err := range g.models.GenerateContentStream(ctx, g.settings.Model, contents, config) {
+		if err != nil {
+			return fmt.Errorf("generate content stream: %w", err)
+		}
+		if text := resp.Text(); text != "" {
+			if err := emit(text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *LLMExplainer) request(japanese, correctAnswer, userAnswer, language string) ([]*genai.Content, *genai.GenerateContentConfig) {
+	prompt := buildExplainPrompt(japanese, correctAnswer, userAnswer, language)
+	contents := []*genai.Content{{Parts: []*genai.Part{{Text: prompt}}}}
+	return contents, &genai.GenerateContentConfig{MaxOutputTokens: g.settings.MaxOutputTokens}
+}
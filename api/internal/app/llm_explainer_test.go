@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// fakeContentGenerator substitutes for the model provider in tests, so no
+// test makes a real network call.
+type fakeContentGenerator struct {
+	resp *genai.GenerateContentResponse
+	err  error
+	// stream is what GenerateContentStream yields, one response per chunk,
+	// followed by err if set.
+	stream []string
+
+	gotModel    string
+	gotContents []*genai.Content
+	gotConfig   *genai.GenerateContentConfig
+}
+
+func (f *fakeContentGenerator) GenerateContentStream(_ context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	f.gotModel = model
+	f.gotContents = contents
+	f.gotConfig = config
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		for _, text := range f.stream {
+			if !yield(textResponse(text), nil) {
+				return
+			}
+		}
+		if f.err != nil {
+			yield(nil, f.err)
+		}
+	}
+}
+
+func (f *fakeContentGenerator) GenerateContent(_ context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	f.gotModel = model
+	f.gotContents = contents
+	f.gotConfig = config
+	return f.resp, f.err
+}
+
+func TestLLMExplainerExplainReturnsText(t *testing.T) {
+	fake := &fakeContentGenerator{
+		resp: &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{
+				{Content: &genai.Content{Parts: []*genai.Part{{Text: "explanation text"}}}},
+			},
+		},
+	}
+	g := NewLLMExplainer(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens})
+
+	got, err := g.Explain(context.Background(), "japanese", "correct", "user", "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "explanation text" {
+		t.Fatalf("unexpected explanation: %q", got)
+	}
+	if fake.gotModel != "gemini-2.5-flash" {
+		t.Fatalf("unexpected model: %q", fake.gotModel)
+	}
+	if len(fake.gotContents) != 1 || len(fake.gotContents[0].Parts) != 1 {
+		t.Fatalf("unexpected contents: %+v", fake.gotContents)
+	}
+	if fake.gotContents[0].Parts[0].Text == "" {
+		t.Fatal("expected prompt text to be sent")
+	}
+}
+
+func TestLLMExplainerCapsMaxOutputTokens(t *testing.T) {
+	fake := &fakeContentGenerator{
+		resp: &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{
+				{Content: &genai.Content{Parts: []*genai.Part{{Text: "explanation text"}}}},
+			},
+		},
+	}
+	g := NewLLMExplainer(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens})
+
+	if _, err := g.Explain(context.Background(), "japanese", "correct", "user", "en"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fake.gotConfig == nil {
+		t.Fatal("expected a GenerateContentConfig to be passed, got nil")
+	}
+	if fake.gotConfig.MaxOutputTokens != maxExplainOutputTokens {
+		t.Fatalf("expected MaxOutputTokens=%d, got %d", maxExplainOutputTokens, fake.gotConfig.MaxOutputTokens)
+	}
+}
+
+func TestLLMExplainerExplainPropagatesError(t *testing.T) {
+	fake := &fakeContentGenerator{err: errors.New("network error")}
+	g := NewLLMExplainer(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens})
+
+	_, err := g.Explain(context.Background(), "japanese", "correct", "user", "en")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLLMExplainerExplainStreamEmitsChunks(t *testing.T) {
+	fake := &fakeContentGenerator{stream: []string{"Your ", "", "answer is fine."}}
+	g := NewLLMExplainer(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens})
+
+	var got []string
+	err := g.ExplainStream(context.Background(), "japanese", "correct", "user", "en", func(chunk string) error {
+		got = append(got, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]string{"Your ", "answer is fine."}) {
+		t.Fatalf("expected the non-empty chunks in order, got %q", got)
+	}
+	if fake.gotModel != "gemini-2.5-flash" || fake.gotConfig.MaxOutputTokens != maxExplainOutputTokens {
+		t.Fatalf("expected the explain settings, got model %q config %+v", fake.gotModel, fake.gotConfig)
+	}
+}
+
+func TestLLMExplainerExplainStreamStopsOnEmitError(t *testing.T) {
+	fake := &fakeContentGenerator{stream: []string{"one", "two"}}
+	g := NewLLMExplainer(fake, ModelSettings{Model: "m", Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens})
+
+	gone := errors.New("client gone")
+	calls := 0
+	err := g.ExplainStream(context.Background(), "japanese", "correct", "user", "en", func(string) error {
+		calls++
+		return gone
+	})
+	if !errors.Is(err, gone) || calls != 1 {
+		t.Fatalf("expected the emit error after one chunk, got %v after %d", err, calls)
+	}
+}
+
+func TestLLMExplainerExplainStreamPropagatesError(t *testing.T) {
+	fake := &fakeContentGenerator{stream: []string{"partial"}, err: errors.New("network error")}
+	g := NewLLMExplainer(fake, ModelSettings{Model: "m", Timeout: explainTimeout, MaxOutputTokens: maxExplainOutputTokens})
+
+	err := g.ExplainStream(context.Background(), "japanese", "correct", "user", "en", func(string) error { return nil })
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
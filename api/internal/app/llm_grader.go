@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// gradeTimeout is shorter than explainTimeout because grading sits on
+	// the answer-check path the learner is waiting on; on timeout the
+	// exact-match rejection stands.
+	gradeTimeout = 10 * time.Second
+
+	// maxGradeOutputTokens bounds the verdict response: a boolean and a
+	// one-sentence reason.
+	maxGradeOutputTokens = 256
+)
+
+// gradeResponseSchema constrains the model to the GradeVerdict JSON shape.
+var gradeResponseSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"accepted": {Type: genai.TypeBoolean},
+		"reason":   {Type: genai.TypeString},
+	},
+	Required: []string{"accepted", "reason"},
+}
+
+// LLMGrader implements Grader using the configured LLM provider.
+type LLMGrader struct {
+	models   contentGenerator
+	settings ModelSettings
+}
+
+func NewLLMGrader(models contentGenerator, settings ModelSettings) *LLMGrader {
+	return &LLMGrader{models: models, settings: settings}
+}
+
+func (g *LLMGrader) Grade(ctx context.Context, japanese string, acceptedAnswers []string, userAnswer string) (GradeVerdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.settings.Timeout)
+	defer cancel()
+
+	prompt := buildGradePrompt(japanese, acceptedAnswers, userAnswer)
+	contents := []*genai.Content{{Parts: []*genai.Part{{Text: prompt}}}}
+
+	config := &genai.GenerateContentConfig{
+		MaxOutputTokens:  g.settings.MaxOutputTokens,
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   gradeResponseSchema,
+	}
+	resp, err := g.models.GenerateContent(ctx, g.settings.Model, contents, config)
+	if err != nil {
+		return GradeVerdict{}, fmt.Errorf("generate content: %w", err)
+	}
+	var v GradeVerdict
+	if err := json.Unmarshal([]byte(resp.Text()), &v); err != nil {
+		return GradeVerdict{}, fmt.Errorf("decode grade verdict: %w", err)
+	}
+	v.Reason = strings.TrimSpace(v.Reason)
+	return v, nil
+}
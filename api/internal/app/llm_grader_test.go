@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func textResponse(text string) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []*genai.Part{{Text: text}}}},
+		},
+	}
+}
+
+func TestLLMGraderParsesVerdict(t *testing.T) {
+	fake := &fakeContentGenerator{resp: textResponse(`{"accepted": true, "reason": " Same meaning. "}`)}
+	g := NewLLMGrader(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: gradeTimeout, MaxOutputTokens: maxGradeOutputTokens})
+
+	v, err := g.Grade(context.Background(), "時間がありません。", []string{"I don't have time."}, "I have no time.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !v.Accepted || v.Reason != "Same meaning." {
+		t.Fatalf("unexpected verdict: %+v", v)
+	}
+	if fake.gotModel != "gemini-2.5-flash" {
+		t.Fatalf("unexpected model: %q", fake.gotModel)
+	}
+	if fake.gotConfig == nil || fake.gotConfig.MaxOutputTokens != maxGradeOutputTokens {
+		t.Fatalf("expected MaxOutputTokens=%d, got %+v", maxGradeOutputTokens, fake.gotConfig)
+	}
+	if fake.gotConfig.ResponseMIMEType != "application/json" || fake.gotConfig.ResponseSchema != gradeResponseSchema {
+		t.Fatalf("expected a JSON response schema, got %+v", fake.gotConfig)
+	}
+}
+
+func TestLLMGraderRejectsMalformedVerdict(t *testing.T) {
+	fake := &fakeContentGenerator{resp: textResponse("Looks good to me!")}
+	g := NewLLMGrader(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: gradeTimeout, MaxOutputTokens: maxGradeOutputTokens})
+	if _, err := g.Grade(context.Background(), "x", []string{"y"}, "z"); err == nil {
+		t.Fatal("expected an error for a non-JSON response")
+	}
+}
+
+func TestLLMGraderPropagatesError(t *testing.T) {
+	fake := &fakeContentGenerator{err: errors.New("network error")}
+	g := NewLLMGrader(fake, ModelSettings{Model: "gemini-2.5-flash", Timeout: gradeTimeout, MaxOutputTokens: maxGradeOutputTokens})
+	if _, err := g.Grade(context.Background(), "x", []string{"y"}, "z"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDefaultLLMConfigNeedsOnlyAnAPIKey(t *testing.T) {
+	cfg := DefaultLLMConfig()
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected Gemini without an API key to be rejected")
+	}
+	cfg.APIKey = "key"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLLMConfigValidateGrade(t *testing.T) {
+	cfg := DefaultLLMConfig()
+	if err := cfg.ValidateGrade(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Grade.Model = ""
+	if err := cfg.ValidateGrade(); err == nil || !strings.Contains(err.Error(), "grade: model is required") {
+		t.Fatalf("expected a missing grade model to be rejected, got %v", err)
+	}
+}
+
+func TestLLMConfigValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(*LLMConfig)
+		want   string
+	}{
+		{"unknown provider", func(c *LLMConfig) { c.Provider = "anthropic" }, "unknown LLM provider"},
+		{"openai without key", func(c *LLMConfig) { c.Provider, c.APIKey = ProviderOpenAI, "" }, ""},
+		{"missing model", func(c *LLMConfig) { c.Explain.Model = "" }, "explain: model is required"},
+		{"grade unset", func(c *LLMConfig) { c.Grade = ModelSettings{} }, ""},
+		{"zero timeout", func(c *LLMConfig) { c.Insight.Timeout = 0 }, "insight: timeout"},
+		{"zero max tokens", func(c *LLMConfig) { c.Explain.MaxOutputTokens = 0 }, "explain: max output tokens"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultLLMConfig()
+			cfg.APIKey = "key"
+			tc.mutate(&cfg)
+			err := cfg.Validate()
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestNewContentGeneratorOpenAI(t *testing.T) {
+	cfg := DefaultLLMConfig()
+	cfg.Provider = ProviderOpenAI
+	cfg.BaseURL = "http://localhost:11434/v1/"
+	gen, closeGen, err := NewContentGenerator(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, ok := gen.(*openAIChatClient)
+	if !ok {
+		t.Fatalf("expected an OpenAI-compatible client, got %T", gen)
+	}
+	if c.baseURL != "http://localhost:11434/v1" {
+		t.Fatalf("expected the trailing slash trimmed, got %q", c.baseURL)
+	}
+	if err := closeGen(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
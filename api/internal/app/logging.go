@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Log formats NewLogger accepts.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// NewLogger returns a logger writing to w in format: text for reading in a
+// terminal, or JSON in the shape Cloud Logging parses, with the level as
+// "severity" and the message as "message". Every record logged with a
+// request's context carries its request_id, its trace_id when it is traced
+// and, once authenticated, its uid.
+func NewLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case "", LogFormatText:
+		h = slog.NewTextHandler(w, opts)
+	case LogFormatJSON:
+		opts.ReplaceAttr = cloudLoggingAttr
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want %s or %s)", format, LogFormatText, LogFormatJSON)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// cloudLoggingAttr renames slog's built-in keys to the ones Cloud Logging
+// recognizes in a structured log line.
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		a.Key = "severity"
+		if a.Value.Any().(slog.Level) == slog.LevelWarn {
+			// Cloud Logging spells it out; DEBUG, INFO and ERROR match.
+			a.Value = slog.StringValue("WARNING")
+		}
+	}
+	return a
+}
+
+// contextHandler adds the request ID, uid and trace ID found in a record's
+// context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if uid := logUID(ctx); uid != "" {
+		r.AddAttrs(slog.String("uid", uid))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+const requestLogCtxKey ctxKey = "request_log"
+
+// requestLog collects what the access log reports about a request that is
+// only learned further down the middleware chain.
+type requestLog struct {
+	uid string
+}
+
+func requestLogFromContext(ctx context.Context) *requestLog {
+	l, _ := ctx.Value(requestLogCtxKey).(*requestLog)
+	return l
+}
+
+// logUID is the authenticated uid for ctx: from requireAuth's context inside
+// it, or as noted on the request's requestLog outside it.
+func logUID(ctx context.Context) string {
+	if uid, ok := uidFromContext(ctx); ok {
+		return uid
+	}
+	if l := requestLogFromContext(ctx); l != nil {
+		return l.uid
+	}
+	return ""
+}
+
+// withAccessLog logs one line per request once it completes: the method,
+// the route pattern it matched, the status, the latency and, when the
+// request authenticated, the uid. It must run inside withRequestID.
+func withAccessLog(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		l := &requestLog{}
+		rec := &statusRecorder{ResponseWriter: w}
+		ctx := context.WithValue(r.Context(), requestLogCtxKey, l)
+		next(rec, r.WithContext(ctx))
+		slog.InfoContext(ctx, "request",
+			"method", r.Method,
+			"route", route,
+			"status", rec.status(),
+			"latency_ms", float64(time.Since(start).Microseconds())/1000)
+	}
+}
+
+// statusRecorder remembers the status a handler responded with. Unwrap lets
+// http.ResponseController reach the underlying writer, so streaming
+// handlers can still flush.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// status is what the client received; a handler that wrote nothing sent
+// 200.
+func (s *statusRecorder) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+	return s.code
+}
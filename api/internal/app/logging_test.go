@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLogs routes the default logger to a JSON buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, LogFormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestRequestLogsCarryRequestIDAndUID(t *testing.T) {
+	buf := captureLogs(t)
+	srv := NewServer(&fakeRepo{randomErr: errors.New("firestore down")}, &fakeExplainer{}, &fakeAnalyzer{})
+	mux := NewMux(srv, fakeVerifier{uid: "u1", email: testAllowedEmail}, []string{testAllowedEmail}, nil, "")
+	req := httptest.NewRequest(http.MethodGet, "/api/sentence/random?levels=1", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set(requestIDHeader, "req-1")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected an error line and an access line, got %v", lines)
+	}
+	errLine, access := lines[0], lines[1]
+	if errLine["severity"] != "ERROR" || errLine["message"] != "random candidate error" || errLine["err"] != "firestore down" {
+		t.Errorf("unexpected error line %v", errLine)
+	}
+	for _, line := range lines {
+		if line["request_id"] != "req-1" || line["uid"] != "u1" {
+			t.Errorf("expected request_id and uid on %v", line)
+		}
+	}
+	if access["severity"] != "INFO" || access["method"] != "GET" || access["route"] != "/api/sentence/random" || access["status"] != float64(500) {
+		t.Errorf("unexpected access line %v", access)
+	}
+	if _, ok := access["latency_ms"].(float64); !ok {
+		t.Errorf("expected a latency on %v", access)
+	}
+}
+
+func TestAccessLogOmitsUIDWhenUnauthenticated(t *testing.T) {
+	buf := captureLogs(t)
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	mux := NewMux(srv, fakeVerifier{err: errors.New("bad token")}, []string{testAllowedEmail}, nil, "")
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/mistakes", nil))
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatalf("expected one access line, got %v", lines)
+	}
+	if _, ok := lines[0]["uid"]; ok || lines[0]["status"] != float64(http.StatusUnauthorized) {
+		t.Errorf("unexpected access line %v", lines[0])
+	}
+	if id, _ := lines[0]["request_id"].(string); id == "" {
+		t.Errorf("expected a generated request_id on %v", lines[0])
+	}
+}
+
+func TestAccessLogKeepsStreamsFlushable(t *testing.T) {
+	captureLogs(t)
+	h := withAccessLog("/stream", func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("expected the wrapped writer to flush, got %v", err)
+		}
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/stream", nil))
+}
+
+func TestNewLoggerRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,544 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryRepo is a SentenceRepository held entirely in process memory. It
+// exists so the server can run locally (and handler-level tests can use a
+// real repository) without a Firestore emulator; nothing is persisted across
+// restarts. It mirrors firestoreRepo's observable behavior exactly — the
+// shared conformance suite in repo_conformance_test.go runs against both.
+type memoryRepo struct {
+	mu           sync.RWMutex
+	now          func() time.Time
+	policy       ReviewPolicy
+	reportPolicy ReportPolicy
+	sentences    map[int]sentenceDoc
+	// stats is keyed by uid, then sentence ID, matching the
+	// users/{uid}/sentence_stats/{id} layout in Firestore.
+	stats map[string]map[int]*memoryStats
+	cache map[string]cacheDoc
+	rates map[string]RateState
+	// reports is keyed by sentence ID, oldest report first, matching the
+	// sentences/{id}/reports subcollection in Firestore.
+	reports map[int][]SentenceReport
+	// sessions is keyed by uid, then session ID, matching the
+	// users/{uid}/sessions/{id} layout in Firestore.
+	sessions map[string]map[string]PracticeSession
+}
+
+// memoryStats is one user's sentence_stats doc plus its histories
+// subcollection, oldest attempt first.
+type memoryStats struct {
+	statsDoc
+	updatedAt time.Time
+	histories []historyDoc
+}
+
+func NewMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		now:       time.Now,
+		policy:    DefaultReviewPolicy(),
+		sentences: map[int]sentenceDoc{},
+		stats:     map[string]map[int]*memoryStats{},
+		cache:     map[string]cacheDoc{},
+		rates:     map[string]RateState{},
+		reports:   map[int][]SentenceReport{},
+		sessions:  map[string]map[string]PracticeSession{},
+	}
+}
+
+// LoadNDJSON adds every sentence in an NDJSON export (the same format
+// cmd/seed reads) to the repository, overwriting any sentence with the same
+// ID. Rows are validated the same way cmd/seed validates them.
+func (r *memoryRepo) LoadNDJSON(rd io.Reader) error {
+	now := r.now().UTC().Format(time.RFC3339)
+	return ScanSeedRows(rd, func(rw SeedRow) error {
+		if err := rw.Validate(); err != nil {
+			return fmt.Errorf("invalid row: %w", err)
+		}
+		r.putSentence(rw.ID, sentenceDoc{
+			Japanese:        rw.Japanese,
+			English:         rw.English,
+			Page:            rw.Page.String(),
+			Level:           rw.Level,
+			IsReported:      rw.IsReported != 0,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			AcceptedAnswers: rw.AcceptedAnswers,
+		})
+		return nil
+	})
+}
+
+func (r *memoryRepo) SetReviewPolicy(p ReviewPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+}
+
+func (r *memoryRepo) SetReportPolicy(p ReportPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reportPolicy = p
+}
+
+func (r *memoryRepo) putSentence(id int, sd sentenceDoc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sentences[id] = sd
+}
+
+func (r *memoryRepo) deleteSentence(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sentences, id)
+}
+
+func (r *memoryRepo) RandomCandidate(_ context.Context, uid string, q CandidateQuery) (*Sentence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	var candidates []reviewCandidate
+	for id, sd := range r.sentences {
+		if sd.IsReported || slices.Contains(sd.ReportedBy, uid) {
+			continue
+		}
+		ms := r.stats[uid][id]
+		var st statsDoc
+		if ms != nil {
+			st = ms.statsDoc
+		}
+		c := reviewCandidate{
+			sentence: &Sentence{
+				ID:             id,
+				Japanese:       sd.Japanese,
+				English:        sd.English,
+				Page:           sd.Page,
+				Level:          sd.Level,
+				CorrectCount:   st.CorrectCount,
+				IncorrectCount: st.IncorrectCount,
+				CreatedAt:      sd.CreatedAt,
+				UpdatedAt:      sd.UpdatedAt,
+			},
+			state: st.reviewState(),
+			seen:  ms != nil,
+		}
+		if q.admits(c, r.policy, now) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	states := make([]ReviewState, 0, len(r.stats[uid]))
+	for _, ms := range r.stats[uid] {
+		states = append(states, ms.reviewState())
+	}
+	newToday, reviewsToday := r.policy.dailyUsage(states, now)
+	s := r.policy.pick(candidates, newToday, reviewsToday, now)
+	if s == nil {
+		return nil, ErrNoCandidate
+	}
+	return s, nil
+}
+
+func (r *memoryRepo) AcceptedAnswers(_ context.Context, id int) ([]string, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sd, ok := r.sentences[id]
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	return sd.acceptedAnswers(), sd.Level, nil
+}
+
+func (r *memoryRepo) GetSentence(_ context.Context, id int) (string, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sd, ok := r.sentences[id]
+	if !ok {
+		return "", "", ErrNotFound
+	}
+	return sd.Japanese, sd.English, nil
+}
+
+// incorrectHistories mirrors firestoreRepo.incorrectHistories: wrong answers
+// only, newest first, with limit > 0 capping the result. The caller must
+// hold r.mu.
+func (ms *memoryStats) incorrectHistories(limit int) []AnswerHistory {
+	histories := make([]AnswerHistory, 0)
+	if ms == nil {
+		return histories
+	}
+	for i := len(ms.histories) - 1; i >= 0; i-- {
+		if limit > 0 && len(histories) >= limit {
+			break
+		}
+		hd := ms.histories[i]
+		if hd.IsCorrect {
+			continue
+		}
+		histories = append(histories, hd.answerHistory())
+	}
+	return histories
+}
+
+// lastMissedAt returns when the newest wrong answer was given, and false
+// when there is none. The caller must hold r.mu.
+func (ms *memoryStats) lastMissedAt() (time.Time, bool) {
+	for i := len(ms.histories) - 1; i >= 0; i-- {
+		if !ms.histories[i].IsCorrect {
+			return ms.histories[i].CreatedAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r *memoryRepo) ListIncorrectHistories(_ context.Context, uid string, id int) ([]AnswerHistory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.stats[uid][id].incorrectHistories(0), nil
+}
+
+// listMistakes mirrors firestoreRepo.listMistakes, including the insight
+// path's bounds (see maxInsightStatsScan): with scanLimit > 0, stats are
+// examined most-recently-touched first, at most maxInsightStatsScanCeiling
+// of them, stopping once scanLimit mistaken sentences have been collected.
+func (r *memoryRepo) listMistakes(uid string, historyLimit, scanLimit int) []MistakeSentence {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userStats := r.stats[uid]
+	ids := make([]int, 0, len(userStats))
+	for id := range userStats {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	if scanLimit > 0 {
+		sort.SliceStable(ids, func(i, j int) bool {
+			return userStats[ids[i]].updatedAt.After(userStats[ids[j]].updatedAt)
+		})
+		if len(ids) > maxInsightStatsScanCeiling {
+			ids = ids[:maxInsightStatsScanCeiling]
+		}
+	}
+
+	mistakes := make([]MistakeSentence, 0)
+	for _, id := range ids {
+		if scanLimit > 0 && len(mistakes) >= scanLimit {
+			break
+		}
+		ms := userStats[id]
+		if ms.IncorrectCount == 0 {
+			continue
+		}
+		sd, ok := r.sentences[id]
+		if !ok {
+			continue
+		}
+		wrongAnswers := ms.incorrectHistories(historyLimit)
+		if len(wrongAnswers) == 0 {
+			continue
+		}
+		mistakes = append(mistakes, MistakeSentence{
+			SentenceID:     id,
+			Japanese:       sd.Japanese,
+			CorrectAnswer:  sd.English,
+			Level:          sd.Level,
+			CorrectCount:   ms.CorrectCount,
+			IncorrectCount: ms.IncorrectCount,
+			WrongAnswers:   wrongAnswers,
+		})
+	}
+
+	sort.Slice(mistakes, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, mistakes[i].WrongAnswers[0].CreatedAt)
+		tj, _ := time.Parse(time.RFC3339, mistakes[j].WrongAnswers[0].CreatedAt)
+		return ti.After(tj)
+	})
+	return mistakes
+}
+
+func (r *memoryRepo) ListMistakes(_ context.Context, uid string, q MistakeQuery) ([]MistakeSentence, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var all []mistakeSummary
+	for id, ms := range r.stats[uid] {
+		sd, ok := r.sentences[id]
+		if !ok || ms.IncorrectCount == 0 {
+			continue
+		}
+		last, ok := ms.lastMissedAt()
+		if !ok {
+			continue
+		}
+		all = append(all, mistakeSummary{id: id, level: sd.Level, correct: ms.CorrectCount, incorrect: ms.IncorrectCount, lastMissedAt: last})
+	}
+	page, next, err := q.page(all, r.policy)
+	if err != nil {
+		return nil, "", err
+	}
+	mistakes := make([]MistakeSentence, 0, len(page))
+	for _, m := range page {
+		sd := r.sentences[m.id]
+		mistakes = append(mistakes, m.mistakeSentence(sd.Japanese, sd.English, r.stats[uid][m.id].incorrectHistories(0)))
+	}
+	return mistakes, next, nil
+}
+
+func (r *memoryRepo) ListMistakesForInsight(_ context.Context, uid string) ([]MistakeSentence, error) {
+	return r.listMistakes(uid, maxWrongAnswersPerSentence, maxInsightStatsScan), nil
+}
+
+func (r *memoryRepo) RecordAnswer(_ context.Context, uid string, id int, rec AnswerRecord) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now().UTC()
+
+	userStats := r.stats[uid]
+	if userStats == nil {
+		userStats = map[int]*memoryStats{}
+		r.stats[uid] = userStats
+	}
+	ms := userStats[id]
+	if ms == nil {
+		ms = &memoryStats{}
+		userStats[id] = ms
+	}
+	if rec.Correct {
+		ms.CorrectCount++
+	} else {
+		ms.IncorrectCount++
+	}
+	ms.setReviewState(r.policy.advance(ms.reviewState(), rec.Correct, now))
+	ms.updatedAt = now
+	hd := newHistoryDoc(rec, now)
+	hd.ID = ms.nextHistoryID(now)
+	ms.histories = append(ms.histories, hd)
+	// Keep histories in created_at order even if the clock is moved
+	// backwards, matching the OrderBy("created_at") Firestore reads with.
+	sort.SliceStable(ms.histories, func(i, j int) bool {
+		return ms.histories[i].CreatedAt.Before(ms.histories[j].CreatedAt)
+	})
+	return hd.historyID(), nil
+}
+
+func (r *memoryRepo) OverrideAnswer(_ context.Context, uid string, id int, historyID int64, ov AnswerOverride) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ms := r.stats[uid][id]
+	if ms == nil {
+		return ErrHistoryNotFound
+	}
+	for i := range ms.histories {
+		hd := &ms.histories[i]
+		if hd.historyID() != historyID {
+			continue
+		}
+		if !hd.overridable(ov.Correct) {
+			return ErrNotOverridable
+		}
+		if ov.Promote && ov.Correct {
+			sd, ok := r.sentences[id]
+			if !ok {
+				return ErrNotFound
+			}
+			// Copy before appending so no earlier reader's slice is shared.
+			sd.AcceptedAnswers = append([]string(nil), sd.AcceptedAnswers...)
+			if sd.addAlternative(hd.IncorrectAnswer) {
+				r.sentences[id] = sd
+			}
+		}
+		if hd.IsCorrect == ov.Correct {
+			return nil
+		}
+		correctDelta, incorrectDelta := overrideDeltas(ov.Correct)
+		hd.IsCorrect = ov.Correct
+		hd.Overridden = true
+		ms.CorrectCount += correctDelta
+		ms.IncorrectCount += incorrectDelta
+		return nil
+	}
+	return ErrHistoryNotFound
+}
+
+// Report returns ErrNotFound for an unknown sentence, where Firestore's
+// Update would fail with a NotFound status.
+func (r *memoryRepo) Report(_ context.Context, uid string, id int, rec ReportRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sd, ok := r.sentences[id]
+	if !ok {
+		return ErrNotFound
+	}
+	sd.ReportedBy = sd.addReporter(uid)
+	sd.ReportCount = len(sd.ReportedBy)
+	if r.reportPolicy.hides(sd.ReportCount) {
+		sd.IsReported = true
+	}
+	r.sentences[id] = sd
+	reports := slices.DeleteFunc(slices.Clone(r.reports[id]), func(sr SentenceReport) bool { return sr.UID == uid })
+	r.reports[id] = append(reports, reportDoc{
+		UID:       uid,
+		Reason:    rec.Reason,
+		Comment:   rec.Comment,
+		CreatedAt: r.now().UTC(),
+	}.sentenceReport())
+	return nil
+}
+
+func (r *memoryRepo) ListReported(_ context.Context) ([]AdminSentence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sentences := make([]AdminSentence, 0)
+	for id, sd := range r.sentences {
+		if sd.IsReported || sd.ReportCount > 0 {
+			s := sd.adminSentence(id)
+			s.Reports = append(s.Reports, r.reports[id]...)
+			sentences = append(sentences, s)
+		}
+	}
+	sort.Slice(sentences, func(i, j int) bool { return sentences[i].ID < sentences[j].ID })
+	return sentences, nil
+}
+
+// updateSentence applies fn to an existing sentence, bumping its updated_at.
+func (r *memoryRepo) updateSentence(id int, fn func(*sentenceDoc)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sd, ok := r.sentences[id]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(&sd)
+	sd.UpdatedAt = r.now().UTC().Format(time.RFC3339)
+	r.sentences[id] = sd
+	return nil
+}
+
+func (r *memoryRepo) UpdateSentence(_ context.Context, id int, edit SentenceEdit) error {
+	return r.updateSentence(id, func(sd *sentenceDoc) {
+		sd.Japanese, sd.English, sd.Page, sd.Level = edit.Japanese, edit.English, edit.Page, edit.Level
+	})
+}
+
+func (r *memoryRepo) Unreport(_ context.Context, id int) error {
+	return r.updateSentence(id, func(sd *sentenceDoc) {
+		sd.IsReported, sd.ReportedBy, sd.ReportCount = false, nil, 0
+		delete(r.reports, id)
+	})
+}
+
+func (r *memoryRepo) DeleteSentence(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sentences[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.sentences, id)
+	delete(r.reports, id)
+	return nil
+}
+
+func (r *memoryRepo) CachedGeneration(_ context.Context, key string) (string, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cd, ok := r.cache[key]
+	if !ok || !cd.fresh(r.now()) {
+		return "", false, nil
+	}
+	return cd.Text, true, nil
+}
+
+func (r *memoryRepo) UpdateRateState(_ context.Context, key string, fn func(RateState) RateState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rates[key] = fn(r.rates[key])
+	return nil
+}
+
+// cloneSession copies s so the caller and the repository never share its
+// slices.
+func cloneSession(s PracticeSession) PracticeSession {
+	s.Levels = slices.Clone(s.Levels)
+	s.Questions = slices.Clone(s.Questions)
+	return s
+}
+
+func (r *memoryRepo) CreateSession(_ context.Context, uid string, s PracticeSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[uid] == nil {
+		r.sessions[uid] = map[string]PracticeSession{}
+	}
+	r.sessions[uid][s.ID] = cloneSession(s)
+	return nil
+}
+
+func (r *memoryRepo) GetSession(_ context.Context, uid, id string) (PracticeSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sessions[uid][id]
+	if !ok {
+		return PracticeSession{}, ErrSessionNotFound
+	}
+	return cloneSession(s), nil
+}
+
+func (r *memoryRepo) UpdateSession(_ context.Context, uid, id string, fn func(*PracticeSession) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.sessions[uid][id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	s := cloneSession(stored)
+	if err := fn(&s); err != nil {
+		return err
+	}
+	r.sessions[uid][id] = cloneSession(s)
+	return nil
+}
+
+func (r *memoryRepo) Ping(context.Context) error {
+	return nil
+}
+
+// CacheGeneration drops every expired entry as it writes, so the cache holds
+// at most what was written within one TTL.
+func (r *memoryRepo) CacheGeneration(_ context.Context, key, text string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for k, cd := range r.cache {
+		if !cd.fresh(now) {
+			delete(r.cache, k)
+		}
+	}
+	r.cache[key] = newCacheDoc(text, ttl, now)
+	return nil
+}
+
+// NewMemoryRepoFromFile returns an in-memory repository preloaded with the
+// NDJSON sentence export at path.
+func NewMemoryRepoFromFile(path string) (*memoryRepo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	repo := NewMemoryRepo()
+	if err := repo.LoadNDJSON(f); err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return repo, nil
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryRepoLoadNDJSON(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo()
+	ndjson := `{"id": 90001, "japanese": "彼女はコーヒーが好きです。", "english": "She likes coffee.", "page": 1, "level": 1, "is_reported": 0}
+
+{"id": 90002, "japanese": "今日は雨が降っています。", "english": "It is raining today.", "page": 2, "level": 3, "is_reported": 1}
+`
+	if err := repo.LoadNDJSON(strings.NewReader(ndjson)); err != nil {
+		t.Fatal(err)
+	}
+	jp, en, err := repo.GetSentence(ctx, 90001)
+	if err != nil || jp != "彼女はコーヒーが好きです。" || en != "She likes coffee." {
+		t.Fatalf("unexpected sentence 90001: %q/%q, %v", jp, en, err)
+	}
+	// 90002 is reported, so 90001 is the only candidate.
+	s, err := repo.RandomCandidate(ctx, "u1", CandidateQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 90001 || s.Page != "1" || s.Level != 1 || s.CreatedAt == "" {
+		t.Fatalf("unexpected candidate: %+v", s)
+	}
+}
+
+func TestMemoryRepoLoadNDJSONRejectsOutOfRangeLevel(t *testing.T) {
+	repo := NewMemoryRepo()
+	err := repo.LoadNDJSON(strings.NewReader(`{"id": 1, "japanese": "あ", "english": "a", "page": 1, "level": 6}`))
+	if err == nil {
+		t.Fatal("expected an error for level 6")
+	}
+}
+
+func TestMemoryRepoConcurrentRecordAnswer(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo()
+	repo.putSentence(1, sentenceDoc{Japanese: "A", English: "A", Level: 1})
+
+	const workers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.RecordAnswer(ctx, "u1", 1, AnswerRecord{Answer: "wrong"}); err != nil {
+				t.Error(err)
+			}
+			if _, _, err := repo.ListMistakes(ctx, "u1", MistakeQuery{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	hs, err := repo.ListIncorrectHistories(ctx, "u1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != workers {
+		t.Fatalf("expected %d recorded attempts, got %d", workers, len(hs))
+	}
+}
+
+func TestMemoryRepoCacheGenerationPrunesExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	if err := repo.CacheGeneration(ctx, "old", "stale", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CacheGeneration(ctx, "kept", "fresh", 3*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Hour)
+	if err := repo.CacheGeneration(ctx, "new", "fresh", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.cache["old"]; ok || len(repo.cache) != 2 {
+		t.Fatalf("expected the expired entry to be pruned, got %d entries", len(repo.cache))
+	}
+}
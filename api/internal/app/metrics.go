@@ -0,0 +1,156 @@
+package app
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/genai"
+)
+
+// Metrics are the Prometheus collectors the server, repository and LLM
+// client report to, on a registry of their own so that servers built in
+// tests never collide. NewMux serves them at /metrics (see serveMetrics).
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	repoDuration *prometheus.HistogramVec
+	llmCalls     *prometheus.CounterVec
+	llmDuration  *prometheus.HistogramVec
+	llmTokens    *prometheus.CounterVec
+	gradings     *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "eagle_http_requests_total",
+			Help: "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "eagle_http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "eagle_repository_operation_duration_seconds",
+			Help:    "SentenceRepository call latency by method and outcome (ok or error).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"op", "outcome"}),
+		llmCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "eagle_llm_calls_total",
+			Help: "LLM calls by model, call kind (generate or stream) and outcome (ok or error).",
+		}, []string{"model", "kind", "outcome"}),
+		llmDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "eagle_llm_call_duration_seconds",
+			Help: "LLM call latency by model and call kind; a stream is timed until its last chunk.",
+			// Generations take seconds, not the milliseconds DefBuckets
+			// resolve.
+			Buckets: []float64{.25, .5, 1, 2, 4, 8, 15, 30, 60},
+		}, []string{"model", "kind"}),
+		llmTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "eagle_llm_tokens_total",
+			Help: "Tokens the LLM reported using, by model and type (prompt, output, thoughts or cached).",
+		}, []string{"model", "type"}),
+		gradings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "eagle_grading_outcomes_total",
+			Help: "Checked answers by sentence level, grader (exact or ai) and result (correct or incorrect).",
+		}, []string{"level", "graded_by", "result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.repoDuration,
+		m.llmCalls, m.llmDuration, m.llmTokens, m.gradings,
+	)
+	return m
+}
+
+// serveMetrics serves the metrics to a scraper presenting the metrics token
+// (see Server.SetMetricsToken) as a bearer token. They show which models
+// the server calls, how much it uses them and its runtime internals, so
+// without a token they are not served at all.
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metricsToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.metricsToken)) != 1 {
+		writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+	s.metrics.handler().ServeHTTP(w, r)
+}
+
+// handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// instrument records each request to a route. It reports the route pattern
+// rather than the URL path, and folds nonstandard methods into "other", so
+// labels stay bounded whatever clients send.
+func (m *Metrics) instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next(rec, r)
+		status, method := strconv.Itoa(rec.status()), metricMethod(r.Method)
+		m.httpRequests.WithLabelValues(route, method, status).Inc()
+		m.httpDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// metricMethod is method as a metric label: net/http accepts any token as a
+// method, so only the standard ones are reported as themselves.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
+
+func (m *Metrics) observeRepo(op, outcome string, start time.Time) {
+	m.repoDuration.WithLabelValues(op, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) observeGrading(level int, rec AnswerRecord) {
+	result := "incorrect"
+	if rec.Correct {
+		result = "correct"
+	}
+	m.gradings.WithLabelValues(strconv.Itoa(level), rec.GradedBy, result).Inc()
+}
+
+func (m *Metrics) observeLLM(model, kind string, start time.Time, usage *genai.GenerateContentResponseUsageMetadata, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	m.llmCalls.WithLabelValues(model, kind, outcome).Inc()
+	m.llmDuration.WithLabelValues(model, kind).Observe(time.Since(start).Seconds())
+	if usage == nil {
+		return
+	}
+	for typ, n := range map[string]int32{
+		"prompt":   usage.PromptTokenCount,
+		"output":   usage.CandidatesTokenCount,
+		"thoughts": usage.ThoughtsTokenCount,
+		"cached":   usage.CachedContentTokenCount,
+	} {
+		if n > 0 {
+			m.llmTokens.WithLabelValues(model, typ).Add(float64(n))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// scrape returns m's exposition text.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func expectSeries(t *testing.T, body string, series ...string) {
+	t.Helper()
+	for _, s := range series {
+		if !strings.Contains(body, s+"\n") {
+			t.Errorf("expected %q in:\n%s", s, body)
+		}
+	}
+}
+
+func TestMetricsCountRequestsByRouteAndStatus(t *testing.T) {
+	srv := NewServer(&fakeRepo{randomErr: ErrNoCandidate}, &fakeExplainer{}, &fakeAnalyzer{})
+	srv.SetMetricsToken("scrape")
+	mux := NewMux(srv, fakeVerifier{uid: "u1", email: testAllowedEmail}, []string{testAllowedEmail}, nil, "")
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/api/sentence/random?levels=2", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape")
+	mux.ServeHTTP(rec, req)
+	expectSeries(t, rec.Body.String(),
+		`eagle_http_requests_total{method="GET",route="/api/sentence/random",status="404"} 2`,
+		`eagle_http_request_duration_seconds_count{method="GET",route="/api/sentence/random",status="404"} 2`,
+	)
+}
+
+func TestMetricsFoldNonstandardMethods(t *testing.T) {
+	m := NewMetrics()
+	h := m.instrument("/api/health", func(w http.ResponseWriter, r *http.Request) {})
+	for _, method := range []string{"BREW", "XYZZY", http.MethodPost} {
+		h(httptest.NewRecorder(), httptest.NewRequest(method, "/api/health", nil))
+	}
+	body := scrape(t, m)
+	expectSeries(t, body,
+		`eagle_http_requests_total{method="other",route="/api/health",status="200"} 2`,
+		`eagle_http_requests_total{method="POST",route="/api/health",status="200"} 1`,
+	)
+	if strings.Contains(body, "BREW") {
+		t.Errorf("expected no series labeled with a made-up method in:\n%s", body)
+	}
+}
+
+func TestMetricsRequireToken(t *testing.T) {
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	mux := NewMux(srv, fakeVerifier{}, nil, nil, "")
+	scrapeWith := func(authz string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := scrapeWith(""); code != http.StatusNotFound {
+		t.Fatalf("expected /metrics off without a token, got %d", code)
+	}
+	srv.SetMetricsToken("scrape")
+	for authz, want := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"scrape":        http.StatusUnauthorized,
+		"Bearer scrape": http.StatusOK,
+	} {
+		if code := scrapeWith(authz); code != want {
+			t.Errorf("Authorization %q: expected %d, got %d", authz, want, code)
+		}
+	}
+}
+
+func TestMetricsCountGradingOutcomesByLevel(t *testing.T) {
+	srv := NewServer(&fakeRepo{correct: "I have time.", level: 2}, &fakeExplainer{}, &fakeAnalyzer{})
+	for _, answer := range []string{"I have time.", "I had time."} {
+		body := strings.NewReader(`{"sentence_id": 1, "user_answer": "` + answer + `"}`)
+		srv.checkAnswer(httptest.NewRecorder(), authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", body), "u1"))
+	}
+	expectSeries(t, scrape(t, srv.metrics),
+		`eagle_grading_outcomes_total{graded_by="exact",level="2",result="correct"} 1`,
+		`eagle_grading_outcomes_total{graded_by="exact",level="2",result="incorrect"} 1`,
+	)
+}
+
+func TestInstrumentRepositoryTimesCallsByOutcome(t *testing.T) {
+	m := NewMetrics()
+	fake := &fakeRepo{randomErr: errors.New("boom")}
+	repo := InstrumentRepository(fake, m)
+	repo.RandomCandidate(context.Background(), "u1", CandidateQuery{})
+	fake.randomErr = ErrNoCandidate
+	repo.RandomCandidate(context.Background(), "u1", CandidateQuery{})
+	expectSeries(t, scrape(t, m),
+		`eagle_repository_operation_duration_seconds_count{op="RandomCandidate",outcome="error"} 1`,
+		`eagle_repository_operation_duration_seconds_count{op="RandomCandidate",outcome="ok"} 1`,
+	)
+}
+
+func TestInstrumentContentGeneratorCountsCallsAndTokens(t *testing.T) {
+	m := NewMetrics()
+	resp := textResponse("hi")
+	resp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 40, CandidatesTokenCount: 7}
+	gen := InstrumentContentGenerator(&fakeContentGenerator{resp: resp, stream: []string{"a", "b"}, err: errors.New("cut off")}, m)
+
+	gen.GenerateContent(context.Background(), "m1", nil, nil)
+	for range gen.GenerateContentStream(context.Background(), "m1", nil, nil) {
+	}
+	expectSeries(t, scrape(t, m),
+		`eagle_llm_calls_total{kind="generate",model="m1",outcome="error"} 1`,
+		`eagle_llm_calls_total{kind="stream",model="m1",outcome="error"} 1`,
+		`eagle_llm_tokens_total{model="m1",type="prompt"} 40`,
+		`eagle_llm_tokens_total{model="m1",type="output"} 7`,
+	)
+}
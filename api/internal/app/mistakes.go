@@ -0,0 +1,193 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// MistakeSort orders a mistakes list.
+type MistakeSort string
+
+const (
+	// MistakesByRecent lists the most recently missed sentence first.
+	MistakesByRecent MistakeSort = "recent"
+	// MistakesByMostMissed lists the sentence answered incorrectly most
+	// often first.
+	MistakesByMostMissed MistakeSort = "most_missed"
+	// MistakesByWorstAccuracy lists the sentence with the lowest share of
+	// correct answers first.
+	MistakesByWorstAccuracy MistakeSort = "worst_accuracy"
+)
+
+func (s MistakeSort) valid() bool {
+	switch s {
+	case MistakesByRecent, MistakesByMostMissed, MistakesByWorstAccuracy:
+		return true
+	}
+	return false
+}
+
+// MistakeQuery selects one page of a learner's mistakes. The zero value
+// lists every mistake, most recently missed first.
+type MistakeQuery struct {
+	// Levels keeps sentences whose level is in the set; empty keeps all.
+	Levels []int
+	// Since keeps sentences last missed at or after it; zero keeps all.
+	Since time.Time
+	// MinIncorrect keeps sentences answered incorrectly at least this often.
+	MinIncorrect int
+	// Unmastered keeps only sentences the learner has not mastered (see
+	// ReviewPolicy.MasteryThreshold).
+	Unmastered bool
+	// Sort is MistakesByRecent when empty.
+	Sort MistakeSort
+	// Limit caps the page; <= 0 returns the rest of the list.
+	Limit int
+	// Cursor continues the list after the page that returned it, for the
+	// same Sort. Filters may change between pages.
+	Cursor string
+}
+
+func (q MistakeQuery) sort() MistakeSort {
+	if q.Sort == "" {
+		return MistakesByRecent
+	}
+	return q.Sort
+}
+
+// mistakeSummary is what a mistake is filtered, ordered and paged by.
+type mistakeSummary struct {
+	id           int
+	level        int
+	correct      int
+	incorrect    int
+	lastMissedAt time.Time
+}
+
+func (q MistakeQuery) matches(m mistakeSummary, p ReviewPolicy) bool {
+	if len(q.Levels) > 0 && !slices.Contains(q.Levels, m.level) {
+		return false
+	}
+	if !q.Since.IsZero() && m.lastMissedAt.Before(q.Since) {
+		return false
+	}
+	if m.incorrect < q.MinIncorrect {
+		return false
+	}
+	return !q.Unmastered || !p.mastered(m.correct, m.incorrect)
+}
+
+// compare orders mistakes for q.Sort. Ties go to the most recently missed,
+// then the lowest ID, so the order is total and a cursor marks one place in
+// it.
+func (q MistakeQuery) compare(a, b mistakeSummary) int {
+	switch q.sort() {
+	case MistakesByMostMissed:
+		if a.incorrect != b.incorrect {
+			return b.incorrect - a.incorrect
+		}
+	case MistakesByWorstAccuracy:
+		// a.correct/a.total against b.correct/b.total, without division.
+		if x, y := a.correct*(b.correct+b.incorrect), b.correct*(a.correct+a.incorrect); x != y {
+			return x - y
+		}
+		if a.incorrect != b.incorrect {
+			return b.incorrect - a.incorrect
+		}
+	}
+	if c := b.lastMissedAt.Compare(a.lastMissedAt); c != 0 {
+		return c
+	}
+	return a.id - b.id
+}
+
+// mistakeCursor is the last mistake of a page, which the next page starts
+// after.
+type mistakeCursor struct {
+	Sort      MistakeSort `json:"s"`
+	ID        int         `json:"id"`
+	Correct   int         `json:"c"`
+	Incorrect int         `json:"i"`
+	// LastMissedAt is in Unix nanoseconds, which every repository's
+	// timestamps fit in exactly.
+	LastMissedAt int64 `json:"t"`
+}
+
+func (q MistakeQuery) cursorAt(m mistakeSummary) string {
+	b, err := json.Marshal(mistakeCursor{
+		Sort:         q.sort(),
+		ID:           m.id,
+		Correct:      m.correct,
+		Incorrect:    m.incorrect,
+		LastMissedAt: m.lastMissedAt.UnixNano(),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// start decodes q.Cursor into the mistake the page starts after; ok is false
+// on the first page. A cursor that is malformed or was issued for another
+// sort returns ErrInvalidCursor.
+func (q MistakeQuery) start() (after mistakeSummary, ok bool, err error) {
+	if q.Cursor == "" {
+		return mistakeSummary{}, false, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return mistakeSummary{}, false, ErrInvalidCursor
+	}
+	var c mistakeCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != q.sort() {
+		return mistakeSummary{}, false, ErrInvalidCursor
+	}
+	return mistakeSummary{
+		id:           c.ID,
+		correct:      c.Correct,
+		incorrect:    c.Incorrect,
+		lastMissedAt: time.Unix(0, c.LastMissedAt).UTC(),
+	}, true, nil
+}
+
+// page filters, orders and pages all of a learner's mistakes, returning the
+// page and the cursor of the next one, which is empty on the last page.
+func (q MistakeQuery) page(all []mistakeSummary, p ReviewPolicy) ([]mistakeSummary, string, error) {
+	after, ok, err := q.start()
+	if err != nil {
+		return nil, "", err
+	}
+	var page []mistakeSummary
+	for _, m := range all {
+		if q.matches(m, p) && (!ok || q.compare(after, m) < 0) {
+			page = append(page, m)
+		}
+	}
+	slices.SortFunc(page, q.compare)
+	page, next := q.cut(page)
+	return page, next, nil
+}
+
+// cut trims an ordered run of matching mistakes to q.Limit, returning the
+// next page's cursor when it had to.
+func (q MistakeQuery) cut(page []mistakeSummary) ([]mistakeSummary, string) {
+	if q.Limit <= 0 || len(page) <= q.Limit {
+		return page, ""
+	}
+	page = page[:q.Limit]
+	return page, q.cursorAt(page[len(page)-1])
+}
+
+func (m mistakeSummary) mistakeSentence(japanese, english string, wrongAnswers []AnswerHistory) MistakeSentence {
+	return MistakeSentence{
+		SentenceID:     m.id,
+		Japanese:       japanese,
+		CorrectAnswer:  english,
+		Level:          m.level,
+		CorrectCount:   m.correct,
+		IncorrectCount: m.incorrect,
+		WrongAnswers:   wrongAnswers,
+	}
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// openAIStreamDone is the data of the event that ends a streamed completion.
+const openAIStreamDone = "[DONE]"
+
+// maxOpenAIErrorBody caps how much of a failed response's body is read into
+// the returned error.
+const maxOpenAIErrorBody = 4 << 10
+
+// openAIChatClient adapts an OpenAI-compatible chat completions endpoint
+// (OpenAI, Ollama, llama.cpp's server, ...) to contentGenerator, translating
+// genai requests and responses so the LLM-backed components stay
+// provider-agnostic.
+type openAIChatClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	MaxTokens      int32                 `json:"max_tokens,omitempty"`
+	Temperature    *float32              `json:"temperature,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		// Delta replaces Message in the chunks of a streamed completion.
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+		TotalTokens      int32 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// provider names the endpoint too: two OpenAI-compatible servers may serve
+// different weights under the same model name.
+func (c *openAIChatClient) provider() string { return ProviderOpenAI + ":" + c.baseURL }
+
+func (c *openAIChatClient) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	resp, err := c.post(ctx, newOpenAIChatRequest(model, contents, config))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode chat response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("chat completions: response has no choices")
+	}
+	return out.genaiResponse(), nil
+}
+
+// GenerateContentStream requests a streamed completion, whose body is a
+// Server-Sent Events stream of chunks ending with a "[DONE]" event.
+func (c *openAIChatClient) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		req := newOpenAIChatRequest(model, contents, config)
+		req.Stream = true
+		resp, err := c.post(ctx, req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			data, ok := strings.CutPrefix(sc.Text(), "data:")
+			if !ok {
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == openAIStreamDone {
+				return
+			}
+			var chunk openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				yield(nil, fmt.Errorf("decode chat stream chunk: %w", err))
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			chunk.Choices[0].Message = chunk.Choices[0].Delta
+			if !yield(chunk.genaiResponse(), nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, fmt.Errorf("read chat stream: %w", err))
+			return
+		}
+		yield(nil, fmt.Errorf("chat completions: stream ended without %s", openAIStreamDone))
+	}
+}
+
+// post sends req to the chat completions endpoint.
+func (c *openAIChatClient) post(ctx context.Context, chatReq openAIChatRequest) (*http.Response, error) {
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("encode chat request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, "chat completions")
+}
+
+// listModels lists the server's models: the cheapest call that proves it is
+// reachable and accepts the key, for /api/readiness.
+func (c *openAIChatClient) listModels(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, "list models")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// do authorizes and sends req, turning a non-200 status into an error
+// carrying the server's message, prefixed with what.
+func (c *openAIChatClient) do(req *http.Request, what string) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxOpenAIErrorBody))
+		msg := strings.TrimSpace(string(raw))
+		var e openAIErrorResponse
+		if json.Unmarshal(raw, &e) == nil && e.Error.Message != "" {
+			msg = e.Error.Message
+		}
+		return nil, fmt.Errorf("%s: %s: %s", what, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// newOpenAIChatRequest maps the subset of genai's request the LLM-backed
+// components use: text parts, a system instruction, the output token bound,
+// temperature and JSON output. A response schema is reduced to JSON mode,
+// which every OpenAI-compatible server supports; the prompts spell out the
+// expected fields.
+func newOpenAIChatRequest(model string, contents []*genai.Content, config *genai.GenerateContentConfig) openAIChatRequest {
+	req := openAIChatRequest{Model: model}
+	if config != nil && config.SystemInstruction != nil {
+		req.Messages = append(req.Messages, openAIMessage{Role: "system", Content: contentText(config.SystemInstruction)})
+	}
+	for _, c := range contents {
+		role := "user"
+		if c.Role == genai.RoleModel {
+			role = "assistant"
+		}
+		req.Messages = append(req.Messages, openAIMessage{Role: role, Content: contentText(c)})
+	}
+	if config != nil {
+		req.MaxTokens = config.MaxOutputTokens
+		req.Temperature = config.Temperature
+		if config.ResponseMIMEType == "application/json" {
+			req.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+		}
+	}
+	return req
+}
+
+func contentText(c *genai.Content) string {
+	var b strings.Builder
+	for _, p := range c.Parts {
+		b.WriteString(p.Text)
+	}
+	return b.String()
+}
+
+func (r openAIChatResponse) genaiResponse() *genai.GenerateContentResponse {
+	choice := r.Choices[0]
+	out := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content: &genai.Content{
+				Role:  genai.RoleModel,
+				Parts: []*genai.Part{{Text: choice.Message.Content}},
+			},
+			FinishReason: openAIFinishReason(choice.FinishReason),
+		}},
+	}
+	if r.Usage != nil {
+		out.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     r.Usage.PromptTokens,
+			CandidatesTokenCount: r.Usage.CompletionTokens,
+			TotalTokenCount:      r.Usage.TotalTokens,
+		}
+	}
+	return out
+}
+
+func openAIFinishReason(reason string) genai.FinishReason {
+	switch reason {
+	case "stop":
+		return genai.FinishReasonStop
+	case "length":
+		return genai.FinishReasonMaxTokens
+	case "content_filter":
+		return genai.FinishReasonSafety
+	case "":
+		return genai.FinishReasonUnspecified
+	default:
+		return genai.FinishReasonOther
+	}
+}
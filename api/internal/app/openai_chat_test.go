@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func newTestOpenAIServer(t *testing.T, status int, body string, got *openAIChatRequest, gotAuth *string) *openAIChatClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		*gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return &openAIChatClient{baseURL: srv.URL + "/v1", apiKey: "sk-test", http: srv.Client()}
+}
+
+func TestOpenAIChatClientGenerateContent(t *testing.T) {
+	var got openAIChatRequest
+	var auth string
+	c := newTestOpenAIServer(t, http.StatusOK, `{
+		"choices": [{"message": {"role": "assistant", "content": "explanation text"}, "finish_reason": "length"}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 34, "total_tokens": 46}
+	}`, &got, &auth)
+
+	contents := []*genai.Content{{Parts: []*genai.Part{{Text: "prompt "}, {Text: "text"}}}}
+	config := &genai.GenerateContentConfig{
+		MaxOutputTokens:   256,
+		ResponseMIMEType:  "application/json",
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: "be brief"}}},
+	}
+	resp, err := c.GenerateContent(context.Background(), "llama3.2", contents, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if auth != "Bearer sk-test" {
+		t.Fatalf("unexpected Authorization header %q", auth)
+	}
+	if got.Model != "llama3.2" || got.MaxTokens != 256 {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	want := []openAIMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "prompt text"}}
+	if len(got.Messages) != 2 || got.Messages[0] != want[0] || got.Messages[1] != want[1] {
+		t.Fatalf("expected messages %+v, got %+v", want, got.Messages)
+	}
+	if got.ResponseFormat == nil || got.ResponseFormat.Type != "json_object" {
+		t.Fatalf("expected JSON mode, got %+v", got.ResponseFormat)
+	}
+
+	if resp.Text() != "explanation text" {
+		t.Fatalf("unexpected text %q", resp.Text())
+	}
+	if fr := resp.Candidates[0].FinishReason; fr != genai.FinishReasonMaxTokens {
+		t.Fatalf("expected MAX_TOKENS, got %q", fr)
+	}
+	if u := resp.UsageMetadata; u == nil || u.PromptTokenCount != 12 || u.CandidatesTokenCount != 34 || u.TotalTokenCount != 46 {
+		t.Fatalf("unexpected usage: %+v", resp.UsageMetadata)
+	}
+}
+
+func TestOpenAIChatClientOmitsAuthWithoutKey(t *testing.T) {
+	var got openAIChatRequest
+	var auth string
+	c := newTestOpenAIServer(t, http.StatusOK, `{"choices": [{"message": {"content": "ok"}}]}`, &got, &auth)
+	c.apiKey = ""
+	if _, err := c.GenerateContent(context.Background(), "m", []*genai.Content{{Parts: []*genai.Part{{Text: "x"}}}}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if auth != "" {
+		t.Fatalf("expected no Authorization header, got %q", auth)
+	}
+	if got.ResponseFormat != nil || got.MaxTokens != 0 {
+		t.Fatalf("expected no options for a nil config, got %+v", got)
+	}
+}
+
+func TestOpenAIChatClientErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"api error", http.StatusNotFound, `{"error": {"message": "model \"nope\" not found"}}`, `model "nope" not found`},
+		{"plain error", http.StatusBadGateway, `upstream down`, "upstream down"},
+		{"no choices", http.StatusOK, `{"choices": []}`, "no choices"},
+		{"bad json", http.StatusOK, `not json`, "decode chat response"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got openAIChatRequest
+			var auth string
+			c := newTestOpenAIServer(t, tc.status, tc.body, &got, &auth)
+			_, err := c.GenerateContent(context.Background(), "nope", []*genai.Content{{Parts: []*genai.Part{{Text: "x"}}}}, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestOpenAIChatClientGenerateContentStream(t *testing.T) {
+	var got openAIChatRequest
+	var auth string
+	c := newTestOpenAIServer(t, http.StatusOK, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"Your \"}}]}\n\n"+
+		": keep-alive\n\n"+
+		"data: {\"choices\":[{\"delta\":{\"content\":\"answer.\"},\"finish_reason\":\"stop\"}]}\n\n"+
+		"data: [DONE]\n\n", &got, &auth)
+
+	var texts []string
+	for resp, err := range c.GenerateContentStream(context.Background(), "llama3.2", []*genai.Content{{Parts: []*genai.Part{{Text: "x"}}}}, nil) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		texts = append(texts, resp.Text())
+	}
+	if !got.Stream {
+		t.Fatal("expected a streamed request")
+	}
+	if strings.Join(texts, "|") != "Your |answer." {
+		t.Fatalf("unexpected chunks %q", texts)
+	}
+}
+
+func TestOpenAIChatClientGenerateContentStreamErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"api error", http.StatusUnauthorized, `{"error": {"message": "bad key"}}`, "bad key"},
+		{"truncated", http.StatusOK, "data: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\n\n", "stream ended"},
+		{"bad chunk", http.StatusOK, "data: nope\n\n", "decode chat stream chunk"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got openAIChatRequest
+			var auth string
+			c := newTestOpenAIServer(t, tc.status, tc.body, &got, &auth)
+			var lastErr error
+			for _, err := range c.GenerateContentStream(context.Background(), "m", []*genai.Content{{Parts: []*genai.Part{{Text: "x"}}}}, nil) {
+				lastErr = err
+			}
+			if lastErr == nil || !strings.Contains(lastErr.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, lastErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiAccess is what NewMux wraps a route's handler in.
+type apiAccess int
+
+const (
+	accessPublic apiAccess = iota // no wrapper
+	accessUser                    // auth
+	accessAI                      // ai: auth plus the rate limiter
+	accessAdmin                   // admin: auth plus requireAdmin
+)
+
+// apiParam is a query parameter.
+type apiParam struct {
+	name        string
+	description string
+	schema      map[string]any
+}
+
+// apiOperation documents one route NewMux serves. The request and response
+// bodies are given as values of their Go types, whose JSON form the
+// document's schemas are reflected from, so a field added to a type is in
+// the document without anyone editing it here.
+type apiOperation struct {
+	path    string
+	method  string
+	summary string
+	access  apiAccess
+	query   []apiParam
+	// request is the JSON request body; nil for none.
+	request any
+	// response is the JSON response body; nil for none.
+	response any
+	// status is the success status; zero means 200.
+	status int
+	// events maps each Server-Sent Event a text/event-stream response sends
+	// to its JSON payload.
+	events map[string]any
+	// text marks a plain-text response.
+	text bool
+	// anyMethod marks a handler that does not check the method, so never
+	// answers 405.
+	anyMethod bool
+	// errors are the statuses the handler itself can fail with, besides
+	// the ones access, method and body imply.
+	errors []int
+	// degraded are further statuses sent with the response body rather than
+	// a Problem.
+	degraded []int
+}
+
+// apiOperations is the API's public surface. Keep it in step with NewMux;
+// TestOpenAPICoversEveryRoute fails when the two disagree.
+var apiOperations = []apiOperation{
+	{
+		path:    "/api/sentence/random",
+		method:  http.MethodGet,
+		summary: "Get the next sentence to practice",
+		access:  accessUser,
+		query: []apiParam{{
+			name:        "levels",
+			description: "Comma-separated difficulty levels (1-5) to choose from; all levels when omitted.",
+			schema:      map[string]any{"type": "string", "example": "1,2"},
+		}, {
+			name:        "source",
+			description: "Which sentences to choose from: all (default), new (never answered), seen (answered), review (answered and due) or mistakes (answered incorrectly and not yet mastered).",
+			schema: map[string]any{
+				"type": "string",
+				"enum": []CandidateSource{SourceAll, SourceNew, SourceSeen, SourceReview, SourceMistakes},
+			},
+		}},
+		response: Sentence{},
+		errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		path:     "/api/answer/check",
+		method:   http.MethodPost,
+		summary:  "Grade an answer and record it",
+		access:   accessAI,
+		request:  CheckAnswerRequest{},
+		response: CheckAnswerResponse{},
+		errors:   []int{http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		path:    "/api/answer/override",
+		method:  http.MethodPost,
+		summary: "Override a grading verdict",
+		access:  accessUser,
+		request: OverrideAnswerRequest{},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		path:    "/api/mistakes",
+		method:  http.MethodGet,
+		summary: "List sentences answered incorrectly",
+		access:  accessUser,
+		query: []apiParam{
+			{
+				name:        "limit",
+				description: "Page size.",
+				schema:      map[string]any{"type": "integer", "minimum": 1, "maximum": maxMistakesPageSize, "default": defaultMistakesPageSize},
+			},
+			{
+				name:        "cursor",
+				description: "next_cursor of the previous page, requested with the same sort.",
+				schema:      map[string]any{"type": "string"},
+			},
+			{
+				name:        "levels",
+				description: "Comma-separated difficulty levels (1-5) to include; all levels when omitted.",
+				schema:      map[string]any{"type": "string", "example": "1,2"},
+			},
+			{
+				name:        "since",
+				description: "Only sentences last missed at or after this RFC 3339 time or date (UTC).",
+				schema:      map[string]any{"type": "string", "example": "2026-01-01"},
+			},
+			{
+				name:        "min_incorrect",
+				description: "Only sentences answered incorrectly at least this many times.",
+				schema:      map[string]any{"type": "integer", "minimum": 0},
+			},
+			{
+				name:        "unmastered",
+				description: "Only sentences not yet mastered.",
+				schema:      map[string]any{"type": "boolean"},
+			},
+			{
+				name:        "sort",
+				description: "Most recently missed, most often missed, or lowest share correct first.",
+				schema: map[string]any{
+					"type":    "string",
+					"enum":    []MistakeSort{MistakesByRecent, MistakesByMostMissed, MistakesByWorstAccuracy},
+					"default": MistakesByRecent,
+				},
+			},
+		},
+		response: ListMistakesResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		path:    "/api/mistakes/insight",
+		method:  http.MethodGet,
+		summary: "Summarize the learner's weaknesses",
+		access:  accessAI,
+		query: []apiParam{
+			{
+				name:        "language",
+				description: "Language to write the insight in.",
+				schema:      map[string]any{"type": "string", "enum": []string{"en", "ja"}},
+			},
+			{
+				name:        "force_refresh",
+				description: "Bypass the generation cache.",
+				schema:      map[string]any{"type": "boolean"},
+			},
+		},
+		response: MistakesInsightResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		path:     "/api/answer/explain",
+		method:   http.MethodPost,
+		summary:  "Explain what was wrong with an answer",
+		access:   accessAI,
+		request:  ExplainRequest{},
+		response: ExplainResponse{},
+		errors:   []int{http.StatusNotFound},
+	},
+	{
+		path:    "/api/answer/explain/stream",
+		method:  http.MethodPost,
+		summary: "Explain an answer as Server-Sent Events",
+		access:  accessAI,
+		request: ExplainRequest{},
+		events: map[string]any{
+			"chunk": ExplainChunk{},
+			"done":  ExplainResponse{},
+			"error": ExplainStreamError{},
+		},
+		errors: []int{http.StatusNotFound},
+	},
+	{
+		path:    "/api/sentence/report",
+		method:  http.MethodPost,
+		summary: "Report a sentence",
+		access:  accessUser,
+		request: ReportSentenceRequest{},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusNotFound},
+	},
+	{
+		path:     "/api/session/create",
+		method:   http.MethodPost,
+		summary:  "Start a practice session",
+		access:   accessUser,
+		request:  CreateSessionRequest{},
+		response: SessionSummary{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		path:     "/api/session/next",
+		method:   http.MethodPost,
+		summary:  "Serve a practice session's next question",
+		access:   accessUser,
+		request:  SessionRequest{},
+		response: NextQuestionResponse{},
+		errors:   []int{http.StatusNotFound},
+	},
+	{
+		path:    "/api/session/summary",
+		method:  http.MethodGet,
+		summary: "Summarize a practice session",
+		access:  accessUser,
+		query: []apiParam{{
+			name:        "session_id",
+			description: "The session_id /api/session/create returned.",
+			schema:      map[string]any{"type": "string"},
+		}},
+		response: SessionSummary{},
+		errors:   []int{http.StatusNotFound},
+	},
+	{
+		path:     "/api/admin/sentences/reported",
+		method:   http.MethodGet,
+		summary:  "List reported sentences",
+		access:   accessAdmin,
+		response: []AdminSentence{},
+	},
+	{
+		path:    "/api/admin/sentence/update",
+		method:  http.MethodPost,
+		summary: "Edit a sentence",
+		access:  accessAdmin,
+		request: UpdateSentenceRequest{},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusNotFound},
+	},
+	{
+		path:    "/api/admin/sentence/unreport",
+		method:  http.MethodPost,
+		summary: "Clear a sentence's reports",
+		access:  accessAdmin,
+		request: AdminSentenceRequest{},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusNotFound},
+	},
+	{
+		path:    "/api/admin/sentence/delete",
+		method:  http.MethodPost,
+		summary: "Delete a sentence",
+		access:  accessAdmin,
+		request: AdminSentenceRequest{},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusNotFound},
+	},
+	{
+		path:      "/api/liveness",
+		method:    http.MethodGet,
+		summary:   "Report that the server is up",
+		access:    accessPublic,
+		text:      true,
+		anyMethod: true,
+	},
+	{
+		path:     "/api/readiness",
+		method:   http.MethodGet,
+		summary:  "Report whether the server's dependencies are up",
+		access:   accessPublic,
+		response: ReadinessResponse{},
+		degraded: []int{http.StatusServiceUnavailable},
+	},
+	{
+		path:     "/api/openapi.json",
+		method:   http.MethodGet,
+		summary:  "This document",
+		access:   accessPublic,
+		response: map[string]any{},
+	},
+	{
+		path:      "/metrics",
+		method:    http.MethodGet,
+		summary:   "Prometheus metrics; requires the METRICS_TOKEN as a bearer token",
+		access:    accessPublic,
+		text:      true,
+		anyMethod: true,
+		errors:    []int{http.StatusUnauthorized, http.StatusNotFound},
+	},
+}
+
+// openAPIDocument renders apiOperations as an OpenAPI 3.0 document, once.
+var openAPIDocument = sync.OnceValue(func() []byte {
+	b, err := json.Marshal(buildOpenAPI(apiOperations))
+	if err != nil {
+		// The document is built from maps, slices and strings only.
+		panic(err)
+	}
+	return b
+})
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPIDocument()); err != nil {
+		slog.ErrorContext(r.Context(), "write openapi error", "err", err)
+	}
+}
+
+func buildOpenAPI(ops []apiOperation) map[string]any {
+	s := schemaBuilder{schemas: map[string]any{}}
+	problem := s.schema(reflect.TypeFor[Problem]())
+	paths := map[string]any{}
+	for _, op := range ops {
+		item, _ := paths[op.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = s.operation(op, problem)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "eagle API",
+			"version": "1",
+			"description": "Errors are RFC 7807 problems (application/problem+json) " +
+				"with a stable code; see docs/spec.md for the list.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.schemas,
+			"securitySchemes": map[string]any{
+				"firebase": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "A Firebase Auth ID token for an allowed email.",
+				},
+			},
+		},
+	}
+}
+
+// schemaBuilder reflects Go types into JSON schemas, collecting each named
+// struct once under components/schemas.
+type schemaBuilder struct {
+	schemas map[string]any
+}
+
+func (s schemaBuilder) operation(op apiOperation, problem map[string]any) map[string]any {
+	out := map[string]any{"summary": op.summary}
+	if op.access == accessPublic {
+		out["security"] = []any{}
+	} else {
+		out["security"] = []any{map[string]any{"firebase": []string{}}}
+	}
+	if len(op.query) > 0 {
+		params := make([]any, 0, len(op.query))
+		for _, p := range op.query {
+			params = append(params, map[string]any{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"schema":      p.schema,
+			})
+		}
+		out["parameters"] = params
+	}
+	if op.request != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent(s.schema(reflect.TypeOf(op.request))),
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case op.events != nil:
+		names := make([]string, 0, len(op.events))
+		events := map[string]any{}
+		for name, payload := range op.events {
+			names = append(names, name)
+			events[name] = s.schema(reflect.TypeOf(payload))
+		}
+		sort.Strings(names)
+		success["description"] = "A stream of " + strings.Join(names, ", ") +
+			" events; x-events gives each event's JSON data."
+		success["content"] = map[string]any{
+			"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}},
+		}
+		success["x-events"] = events
+	case op.text:
+		success["content"] = map[string]any{
+			"text/plain": map[string]any{"schema": map[string]any{"type": "string"}},
+		}
+	case op.response != nil:
+		success["content"] = jsonContent(s.schema(reflect.TypeOf(op.response)))
+	}
+	responses := map[string]any{strconv.Itoa(status): success}
+	for _, code := range op.degraded {
+		resp := maps.Clone(success)
+		resp["description"] = http.StatusText(code)
+		responses[strconv.Itoa(code)] = resp
+	}
+	for _, code := range op.errorStatuses() {
+		resp := map[string]any{
+			"description": http.StatusText(code),
+			"content":     map[string]any{problemContentType: map[string]any{"schema": problem}},
+		}
+		if code == http.StatusTooManyRequests {
+			resp["headers"] = map[string]any{
+				"Retry-After": map[string]any{
+					"description": "Seconds until the request would be allowed.",
+					"schema":      map[string]any{"type": "integer"},
+				},
+			}
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	out["responses"] = responses
+	return out
+}
+
+// errorStatuses lists every error status op can respond with.
+func (op apiOperation) errorStatuses() []int {
+	codes := map[int]bool{}
+	for _, c := range op.errors {
+		codes[c] = true
+	}
+	if !op.anyMethod {
+		codes[http.StatusMethodNotAllowed] = true
+	}
+	if op.request != nil {
+		codes[http.StatusBadRequest] = true
+	}
+	if op.access != accessPublic {
+		codes[http.StatusUnauthorized] = true
+		codes[http.StatusInternalServerError] = true
+	}
+	if op.access == accessAI {
+		codes[http.StatusTooManyRequests] = true
+	}
+	if op.access == accessAdmin {
+		codes[http.StatusForbidden] = true
+	}
+	out := make([]int, 0, len(codes))
+	for c := range codes {
+		out = append(out, c)
+	}
+	sort.Ints(out)
+	return out
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (s schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if _, ok := s.schemas[t.Name()]; !ok {
+			// Claim the name before recursing, so a type that refers to
+			// itself terminates.
+			s.schemas[t.Name()] = nil
+			s.schemas[t.Name()] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+// object describes a struct by its JSON encoding: one property per exported
+// field, named by its json tag.
+func (s schemaBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
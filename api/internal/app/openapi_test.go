@@ -0,0 +1,202 @@
+package app
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func servedOpenAPI(t *testing.T) map[string]any {
+	t.Helper()
+	mux := NewMux(NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{}), fakeVerifier{}, nil, nil, "")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	doc := servedOpenAPI(t)
+	if doc["openapi"] != "3.0.3" {
+		t.Fatalf("expected an OpenAPI 3.0.3 document, got %v", doc["openapi"])
+	}
+	op := doc["paths"].(map[string]any)["/api/answer/check"].(map[string]any)["post"].(map[string]any)
+	want := map[string]bool{"200": true, "400": true, "401": true, "404": true, "405": true, "409": true, "429": true, "500": true}
+	for status := range op["responses"].(map[string]any) {
+		if !want[status] {
+			t.Errorf("unexpected %s response", status)
+		}
+		delete(want, status)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing responses %v", want)
+	}
+}
+
+// TestOpenAPICoversEveryRoute reads the routes NewMux registers from its
+// source, so a route added there without an apiOperations entry — or
+// wrapped differently from how it is documented — fails here.
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "router.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappers := map[string]apiAccess{"auth": accessUser, "ai": accessAI, "admin": accessAdmin}
+	routed := map[string]apiAccess{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "handle" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok {
+			t.Fatalf("handle called with a non-literal pattern at %v", call.Pos())
+		}
+		path, _ := strconv.Unquote(lit.Value)
+		access := accessPublic
+		if h, ok := call.Args[1].(*ast.CallExpr); ok {
+			if fn, ok := h.Fun.(*ast.Ident); ok {
+				if a, ok := wrappers[fn.Name]; ok {
+					access = a
+				}
+			}
+		}
+		routed[path] = access
+		return true
+	})
+	if len(routed) == 0 {
+		t.Fatal("found no handle calls in router.go")
+	}
+
+	documented := map[string]apiAccess{}
+	for _, op := range apiOperations {
+		documented[op.path] = op.access
+	}
+	for path, access := range routed {
+		got, ok := documented[path]
+		if !ok {
+			t.Errorf("%s is routed but missing from apiOperations", path)
+		} else if got != access {
+			t.Errorf("%s is routed with access %d but documented with %d", path, access, got)
+		}
+	}
+	for path := range documented {
+		if _, ok := routed[path]; !ok {
+			t.Errorf("%s is documented but not routed", path)
+		}
+	}
+}
+
+// jsonStructs returns the JSON property names of every struct type declared
+// in file that has at least one json tag.
+func jsonStructs(t *testing.T, file string) map[string][]string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string][]string{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		var names []string
+		tagged := false
+		for _, field := range st.Fields.List {
+			tag := ""
+			if field.Tag != nil {
+				raw, _ := strconv.Unquote(field.Tag.Value)
+				tag, tagged = reflect.StructTag(raw).Get("json"), true
+			}
+			for _, ident := range field.Names {
+				if !ident.IsExported() {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				if name == "-" {
+					continue
+				}
+				if name == "" {
+					name = ident.Name
+				}
+				names = append(names, name)
+			}
+		}
+		if tagged {
+			sort.Strings(names)
+			out[spec.Name.Name] = names
+		}
+		return true
+	})
+	return out
+}
+
+// TestOpenAPICoversEveryJSONType fails when a request or response type is
+// added without an operation that uses it, or when the document's schema
+// for a type disagrees with the type's declared fields.
+func TestOpenAPICoversEveryJSONType(t *testing.T) {
+	schemas := servedOpenAPI(t)["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, file := range []string{"sentence.go", "problem.go"} {
+		for name, fields := range jsonStructs(t, file) {
+			schema, ok := schemas[name].(map[string]any)
+			if !ok {
+				t.Errorf("%s (%s) is not in the document", name, file)
+				continue
+			}
+			var got []string
+			for prop := range schema["properties"].(map[string]any) {
+				got = append(got, prop)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, fields) {
+				t.Errorf("%s: document has properties %v, type declares %v", name, got, fields)
+			}
+		}
+	}
+}
+
+// TestOpenAPIMethodsMatchHandlers checks each documented method against its
+// handler: the other method must be refused.
+func TestOpenAPIMethodsMatchHandlers(t *testing.T) {
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	verifier := fakeVerifier{uid: "u1", email: testAllowedEmail, admin: true}
+	mux := NewMux(srv, verifier, []string{testAllowedEmail}, nil, "")
+	for _, op := range apiOperations {
+		if op.anyMethod {
+			continue
+		}
+		other := http.MethodPost
+		if op.method == http.MethodPost {
+			other = http.MethodGet
+		}
+		req := httptest.NewRequest(other, op.path, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected 405 for an undocumented method, got %d", other, op.path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Problem is the body of every error response: an RFC 7807 problem details
+// object, extended with a stable machine-readable Code clients can branch on
+// and the RequestID to quote when reporting it.
+type Problem struct {
+	// Type is always "about:blank": Code, not a URI, identifies the kind.
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail is a human-readable explanation, not meant to be parsed.
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Problem codes. Each is stable once published; add new ones rather than
+// changing what an existing code means.
+const (
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeInvalidBody         = "invalid_body"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeRateLimited         = "rate_limited"
+	CodeInternal            = "internal"
+	CodeInvalidLevels       = "invalid_levels"
+	CodeInvalidUserAnswer   = "invalid_user_answer"
+	CodeInvalidLanguage     = "invalid_language"
+	CodeInvalidForceRefresh = "invalid_force_refresh"
+	CodeInvalidPromote      = "invalid_promote"
+	CodeInvalidReason       = "invalid_reason"
+	CodeCommentTooLong      = "comment_too_long"
+	CodeInvalidSentence     = "invalid_sentence"
+	CodeInvalidLimit        = "invalid_limit"
+	CodeInvalidCursor       = "invalid_cursor"
+	CodeInvalidSince        = "invalid_since"
+	CodeInvalidMinIncorrect = "invalid_min_incorrect"
+	CodeInvalidUnmastered   = "invalid_unmastered"
+	CodeInvalidSort         = "invalid_sort"
+	CodeInvalidSize         = "invalid_size"
+	CodeInvalidSource       = "invalid_source"
+	CodeNoCandidate         = "no_candidate"
+	CodeSentenceNotFound    = "sentence_not_found"
+	CodeHistoryNotFound     = "history_not_found"
+	CodeNotOverridable      = "not_overridable"
+	CodeSessionNotFound     = "session_not_found"
+	CodeNotSessionQuestion  = "not_session_question"
+)
+
+const problemContentType = "application/problem+json"
+
+// writeError sends a Problem with the given status, code and detail, tagged
+// with the request's ID.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: requestIDFromContext(r.Context()),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "encode error", "err", err)
+	}
+}
+
+// Shorthands for the errors nearly every handler can return.
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
+
+func writeInvalidBody(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+}
+
+func writeInternalError(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeProblem checks rec is a problem+json response with the given status
+// and code, and returns it.
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) Problem {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("expected %d, got %d", status, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("expected %s, got %q", problemContentType, ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if p.Status != status || p.Code != code || p.Type != "about:blank" || p.Title != http.StatusText(status) {
+		t.Fatalf("expected status %d code %q, got %+v", status, code, p)
+	}
+	return p
+}
+
+func TestHandlerErrorsAreProblems(t *testing.T) {
+	srv := NewServer(&fakeRepo{randomErr: ErrNoCandidate, correctErr: ErrNotFound}, &fakeExplainer{}, &fakeAnalyzer{})
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		req     *http.Request
+		status  int
+		code    string
+	}{
+		{"method", srv.getRandomSentence, httptest.NewRequest(http.MethodPost, "/api/sentence/random", nil), http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"levels", srv.getRandomSentence, httptest.NewRequest(http.MethodGet, "/api/sentence/random?levels=x", nil), http.StatusBadRequest, CodeInvalidLevels},
+		{"no candidate", srv.getRandomSentence, httptest.NewRequest(http.MethodGet, "/api/sentence/random", nil), http.StatusNotFound, CodeNoCandidate},
+		{"body", srv.checkAnswer, httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader("{")), http.StatusBadRequest, CodeInvalidBody},
+		{"sentence", srv.checkAnswer, httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader(`{"sentence_id":1,"user_answer":"a"}`)), http.StatusNotFound, CodeSentenceNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.handler(rec, authed(tc.req, "u1"))
+			decodeProblem(t, rec, tc.status, tc.code)
+		})
+	}
+}
+
+func TestAuthErrorsAreProblemsWithRequestID(t *testing.T) {
+	h := withRequestID(requireAuth(fakeVerifier{uid: "u1", email: testAllowedEmail}, []string{testAllowedEmail}, func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/api/sentence/random", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	p := decodeProblem(t, rec, http.StatusUnauthorized, CodeUnauthorized)
+	if p.RequestID != "req-123" || rec.Header().Get("X-Request-ID") != "req-123" {
+		t.Fatalf("expected the caller's request ID echoed, got %q and header %q", p.RequestID, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestWithRequestIDAssignsIDs(t *testing.T) {
+	var got string
+	h := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		got = requestIDFromContext(r.Context())
+	})
+	for name, header := range map[string]string{
+		"missing":   "",
+		"too long":  strings.Repeat("a", maxRequestIDLength+1),
+		"malformed": "has space",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/liveness", nil)
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if len(got) != 32 || got == header || rec.Header().Get("X-Request-ID") != got {
+			t.Errorf("%s: expected a fresh ID in context and header, got %q and %q", name, got, rec.Header().Get("X-Request-ID"))
+		}
+	}
+}
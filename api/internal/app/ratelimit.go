@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds how often one user may call an AI-backed endpoint: a
+// token bucket absorbs short bursts, and a daily quota caps the total.
+type RateLimit struct {
+	// Burst is the bucket size: how many requests may be made back to back.
+	// Zero disables the bucket.
+	Burst int
+	// Refill is how long the bucket takes to regain one request.
+	Refill time.Duration
+	// DailyQuota caps requests per day; zero means no cap.
+	DailyQuota int
+	// Location is where the quota's day starts at midnight; nil means UTC.
+	Location *time.Location
+}
+
+// DefaultRateLimit allows bursts of 10 requests, refilled at 10 a minute, and
+// 200 requests a day — far above what a learner clicking Explain needs, but
+// a hard ceiling on what a runaway client or leaked token can spend.
+func DefaultRateLimit() RateLimit {
+	return RateLimit{Burst: 10, Refill: 6 * time.Second, DailyQuota: 200, Location: time.UTC}
+}
+
+// RateState is one user's usage of one rate-limited scope, as a RateCounter
+// stores it. The zero value is a user who has made no requests.
+type RateState struct {
+	// Tokens is what was left in the bucket at UpdatedAt.
+	Tokens    float64   `firestore:"tokens"`
+	UpdatedAt time.Time `firestore:"updated_at"`
+	// Day is the start of the quota day DayCount counts requests in.
+	Day      time.Time `firestore:"day"`
+	DayCount int       `firestore:"day_count"`
+}
+
+// RateCounter persists RateStates. UpdateRateState must apply fn to key's
+// current state (the zero RateState if there is none) and save the result
+// atomically, so concurrent requests cannot both spend the last token.
+type RateCounter interface {
+	UpdateRateState(ctx context.Context, key string, fn func(RateState) RateState) error
+}
+
+// take spends one request from st at now. It returns the new state and, when
+// the request is over the limit, how long until it would be allowed; a
+// refused request spends nothing.
+func (l RateLimit) take(st RateState, now time.Time) (RateState, time.Duration) {
+	loc := l.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := now.In(loc).Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	if !st.Day.Equal(day) {
+		st.Day, st.DayCount = day, 0
+	}
+	if l.DailyQuota > 0 && st.DayCount >= l.DailyQuota {
+		return st, day.AddDate(0, 0, 1).Sub(now)
+	}
+	if l.Burst > 0 {
+		tokens := float64(l.Burst)
+		if !st.UpdatedAt.IsZero() {
+			elapsed := now.Sub(st.UpdatedAt)
+			tokens = math.Min(tokens, st.Tokens+float64(elapsed)/float64(l.Refill))
+		}
+		st.Tokens, st.UpdatedAt = tokens, now
+		if tokens < 1 {
+			return st, time.Duration((1 - tokens) * float64(l.Refill))
+		}
+		st.Tokens--
+	}
+	st.DayCount++
+	return st, 0
+}
+
+// RateLimiter enforces a RateLimit per user and scope, keeping usage in a
+// RateCounter.
+type RateLimiter struct {
+	counter RateCounter
+	limit   RateLimit
+	now     func() time.Time
+}
+
+func NewRateLimiter(counter RateCounter, limit RateLimit) *RateLimiter {
+	return &RateLimiter{counter: counter, limit: limit, now: time.Now}
+}
+
+// allow spends one of uid's requests in scope, returning how long to wait
+// when none is left (zero when the request may proceed).
+func (l *RateLimiter) allow(ctx context.Context, uid, scope string) (time.Duration, error) {
+	var retryAfter time.Duration
+	err := l.counter.UpdateRateState(ctx, uid+":"+scope, func(st RateState) RateState {
+		st, retryAfter = l.limit.take(st, l.now())
+		return st
+	})
+	return retryAfter, err
+}
+
+// RateLimitError is returned by chargeRateLimit once the user has used up
+// the route's limit.
+type RateLimitError struct {
+	// RetryAfter is how long until the request would be allowed.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited; retry after %v", e.RetryAfter)
+}
+
+type rateChargeKey struct{}
+
+// withRateLimit puts the authenticated user's budget in scope into the
+// request's context, for the handler to spend with chargeRateLimit once the
+// request is valid and about to reach the model: a malformed request, a
+// cache hit or an exact-match grade costs nothing. It must run inside
+// requireAuth, which provides the uid. A nil limiter disables limiting, and
+// a counter failure lets the request through: the limit guards cost, and an
+// outage of its storage shouldn't take the AI features down with it.
+func withRateLimit(l *RateLimiter, scope string, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, _ := uidFromContext(r.Context())
+		charge := func(ctx context.Context) error {
+			retryAfter, err := l.allow(ctx, uid, scope)
+			if err != nil {
+				slog.ErrorContext(ctx, "rate limit error", "err", err)
+				return nil
+			}
+			if retryAfter > 0 {
+				return &RateLimitError{RetryAfter: retryAfter}
+			}
+			return nil
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), rateChargeKey{}, charge)))
+	}
+}
+
+// chargeRateLimit spends one request from the budget withRateLimit put in
+// ctx, returning a *RateLimitError when none is left. Without a budget it
+// allows everything.
+func chargeRateLimit(ctx context.Context) error {
+	charge, ok := ctx.Value(rateChargeKey{}).(func(context.Context) error)
+	if !ok {
+		return nil
+	}
+	return charge(ctx)
+}
+
+// writeRateLimited writes a 429 Too Many Requests with a Retry-After header
+// when err is a *RateLimitError, reporting whether it did.
+func writeRateLimited(w http.ResponseWriter, r *http.Request, err error) bool {
+	var rl *RateLimitError
+	if !errors.As(err, &rl) {
+		return false
+	}
+	secs := int(math.Ceil(rl.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+	writeError(w, r, http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+	return true
+}
+
+// memoryRateCounter keeps RateStates in process memory: limits reset on
+// restart and are per instance, which suits a single local or Cloud Run
+// instance. Use the repository as the counter to share limits across
+// instances.
+type memoryRateCounter struct {
+	mu     sync.Mutex
+	states map[string]RateState
+}
+
+func NewMemoryRateCounter() *memoryRateCounter {
+	return &memoryRateCounter{states: map[string]RateState{}}
+}
+
+func (c *memoryRateCounter) UpdateRateState(_ context.Context, key string, fn func(RateState) RateState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states[key] = fn(c.states[key])
+	return nil
+}
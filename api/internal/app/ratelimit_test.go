@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTakeSpendsBurstThenRefills(t *testing.T) {
+	l := RateLimit{Burst: 2, Refill: 10 * time.Second}
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	var st RateState
+	var wait time.Duration
+	for i := range 2 {
+		if st, wait = l.take(st, now); wait != 0 {
+			t.Fatalf("request %d: expected it to be allowed, got wait %v", i, wait)
+		}
+	}
+	if st, wait = l.take(st, now.Add(4*time.Second)); wait != 6*time.Second {
+		t.Fatalf("expected a 6s wait for the next token, got %v", wait)
+	}
+	if _, wait = l.take(st, now.Add(10*time.Second)); wait != 0 {
+		t.Fatalf("expected a refilled token to be spendable, got wait %v", wait)
+	}
+}
+
+func TestRateLimitTakeDailyQuotaResetsAtMidnight(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	l := RateLimit{DailyQuota: 2, Location: jst}
+	now := time.Date(2026, 1, 1, 23, 0, 0, 0, jst)
+	var st RateState
+	st, _ = l.take(st, now)
+	st, _ = l.take(st, now)
+	st, wait := l.take(st, now)
+	if wait != time.Hour {
+		t.Fatalf("expected to wait until midnight JST, got %v", wait)
+	}
+	if st.DayCount != 2 {
+		t.Fatalf("expected a refused request not to count, got %d", st.DayCount)
+	}
+	if st, wait = l.take(st, now.Add(time.Hour)); wait != 0 || st.DayCount != 1 {
+		t.Fatalf("expected a fresh quota after midnight, got wait %v count %d", wait, st.DayCount)
+	}
+}
+
+// rateLimitedHandler charges every request it gets, counting the ones
+// allowed through.
+func rateLimitedHandler(l *RateLimiter) (http.HandlerFunc, *int) {
+	calls := 0
+	return withRateLimit(l, "explain", func(w http.ResponseWriter, r *http.Request) {
+		if writeRateLimited(w, r, chargeRateLimit(r.Context())) {
+			return
+		}
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}), &calls
+}
+
+func TestWithRateLimitRefusesWithRetryAfter(t *testing.T) {
+	l := NewRateLimiter(NewMemoryRateCounter(), RateLimit{Burst: 1, Refill: 90 * time.Second})
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	h, calls := rateLimitedHandler(l)
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		h(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-a"))
+		if rec.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i, want, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	now = now.Add(500 * time.Millisecond)
+	h(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-a"))
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Fatalf("expected Retry-After rounded up to 90, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-b"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected another user's budget to be separate, got %d", rec.Code)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected the handler to run twice, ran %d times", *calls)
+	}
+}
+
+func TestWithRateLimitScopesAreIndependent(t *testing.T) {
+	l := NewRateLimiter(NewMemoryRateCounter(), RateLimit{DailyQuota: 1})
+	explain, _ := rateLimitedHandler(l)
+	insight := withRateLimit(l, "insight", func(w http.ResponseWriter, r *http.Request) {
+		writeRateLimited(w, r, chargeRateLimit(r.Context()))
+	})
+	rec := httptest.NewRecorder()
+	explain(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-a"))
+	rec = httptest.NewRecorder()
+	insight(rec, authed(httptest.NewRequest(http.MethodGet, "/api/mistakes/insight", nil), "user-a"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the insight quota to be unaffected by explain, got %d", rec.Code)
+	}
+}
+
+type failingRateCounter struct{}
+
+func (failingRateCounter) UpdateRateState(context.Context, string, func(RateState) RateState) error {
+	return errors.New("boom")
+}
+
+func TestWithRateLimitFailsOpen(t *testing.T) {
+	for name, l := range map[string]*RateLimiter{
+		"nil limiter":     nil,
+		"counter failure": NewRateLimiter(failingRateCounter{}, RateLimit{Burst: 1, Refill: time.Minute}),
+	} {
+		h, calls := rateLimitedHandler(l)
+		rec := httptest.NewRecorder()
+		h(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-a"))
+		if rec.Code != http.StatusOK || *calls != 1 {
+			t.Errorf("%s: expected the request through, got %d after %d calls", name, rec.Code, *calls)
+		}
+	}
+}
+
+func TestWithRateLimitChargesOnlyWhenCalled(t *testing.T) {
+	l := NewRateLimiter(NewMemoryRateCounter(), RateLimit{DailyQuota: 1})
+	free := withRateLimit(l, "explain", func(w http.ResponseWriter, r *http.Request) {})
+	for range 3 {
+		free(httptest.NewRecorder(), authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-a"))
+	}
+	h, calls := rateLimitedHandler(l)
+	rec := httptest.NewRecorder()
+	h(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", nil), "user-a"))
+	if rec.Code != http.StatusOK || *calls != 1 {
+		t.Fatalf("expected requests that never charged to leave the quota, got %d", rec.Code)
+	}
+}
+
+// limitedServer is a Server whose AI routes allow user u1 a single request
+// a day, wired as NewMux wires them.
+func limitedServer(repo *fakeRepo, explainer *fakeExplainer) (*Server, func(scope string, h http.HandlerFunc) http.HandlerFunc) {
+	srv := NewServer(repo, explainer, &fakeAnalyzer{})
+	srv.SetRateLimiter(NewRateLimiter(NewMemoryRateCounter(), RateLimit{DailyQuota: 1}))
+	return srv, func(scope string, h http.HandlerFunc) http.HandlerFunc {
+		return withRateLimit(srv.limiter, scope, h)
+	}
+}
+
+func TestExplainChargesOnlyCacheMisses(t *testing.T) {
+	explainer := &fakeExplainer{explanation: "Fine."}
+	srv, ai := limitedServer(&fakeRepo{sentenceJapanese: "x", sentenceEnglish: "y"}, explainer)
+	srv.SetGenerationCacheTTL(time.Hour)
+	explain := ai("explain", srv.explainAnswer)
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		explain(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain", strings.NewReader(body)), "u1"))
+		return rec.Code
+	}
+	if code := post(`{"sentence_id":1,"user_answer":"","language":"en"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a blank answer, got %d", code)
+	}
+	for i := range 3 {
+		if code := post(`{"sentence_id":1,"user_answer":"z","language":"en"}`); code != http.StatusOK {
+			t.Fatalf("request %d: expected the invalid request and cache hits to be free, got %d", i, code)
+		}
+	}
+	if code := post(`{"sentence_id":1,"user_answer":"other","language":"en"}`); code != http.StatusTooManyRequests {
+		t.Fatalf("expected a second cache miss to be refused, got %d", code)
+	}
+	if len(explainer.calledWith) != 1 {
+		t.Fatalf("expected one Explain call, got %d", len(explainer.calledWith))
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"sentence_id":1,"user_answer":"another","language":"en"}`
+	ai("explain", srv.explainAnswerStream)(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/explain/stream", strings.NewReader(body)), "u1"))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected the stream refused before it starts, got %d", rec.Code)
+	}
+}
+
+func TestCheckAnswerChargesOnlyAIGrading(t *testing.T) {
+	repo := &fakeRepo{correct: "I don't have time.", sentenceJapanese: "時間がありません。"}
+	grader := &fakeGrader{verdict: GradeVerdict{Accepted: false}}
+	srv, ai := limitedServer(repo, &fakeExplainer{})
+	srv.SetGrader(grader)
+	check := ai("grade", srv.checkAnswer)
+	post := func(answer string) int {
+		rec := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"sentence_id":1,"user_answer":%q}`, answer)
+		check(rec, authed(httptest.NewRequest(http.MethodPost, "/api/answer/check", strings.NewReader(body)), "u1"))
+		return rec.Code
+	}
+	for _, answer := range []string{"I don't have time.", "I don't have time", "I have no time."} {
+		if code := post(answer); code != http.StatusOK {
+			t.Fatalf("%q: expected exact matches to be free, got %d", answer, code)
+		}
+	}
+	if code := post("No time."); code != http.StatusTooManyRequests {
+		t.Fatalf("expected a second AI grade to be refused, got %d", code)
+	}
+	if len(grader.calledWith) != 1 || len(repo.recorded) != 3 {
+		t.Fatalf("expected the refused answer neither graded nor recorded, got %d grades, %d records", len(grader.calledWith), len(repo.recorded))
+	}
+}
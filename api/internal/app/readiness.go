@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReadinessCheck is one dependency /api/readiness reports on.
+type ReadinessCheck struct {
+	Name string
+	// Required dependencies make the server unready while they are down;
+	// the rest are only reported.
+	Required bool
+	Check    func(ctx context.Context) error
+}
+
+// ReadinessConfig bounds what /api/readiness costs. Probes may arrive every
+// few seconds from every load balancer; each check runs at most once per
+// CacheTTL and gives up after Timeout.
+type ReadinessConfig struct {
+	Timeout  time.Duration
+	CacheTTL time.Duration
+}
+
+func DefaultReadinessConfig() ReadinessConfig {
+	return ReadinessConfig{Timeout: 2 * time.Second, CacheTTL: 10 * time.Second}
+}
+
+// RepositoryReadinessCheck pings repo. Every route but liveness needs it, so
+// it is required.
+func RepositoryReadinessCheck(repo SentenceRepository) ReadinessCheck {
+	return ReadinessCheck{Name: "repository", Required: true, Check: repo.Ping}
+}
+
+// LLMReadinessCheck lists gen's models, which fails when the provider is
+// unreachable or rejects the key. gen must be what NewContentGenerator
+// returned, not an instrumented wrapper, so probes stay out of the LLM call
+// metrics. Explanations, insights and AI grading degrade on their own when
+// the model is down, so the check is usually not required.
+func LLMReadinessCheck(gen contentGenerator, required bool) ReadinessCheck {
+	check := func(context.Context) error { return errors.New("provider cannot list models") }
+	if l, ok := gen.(interface{ listModels(context.Context) error }); ok {
+		check = l.listModels
+	}
+	return ReadinessCheck{Name: "llm", Required: required, Check: check}
+}
+
+// readiness runs the checks and caches the combined result.
+type readiness struct {
+	cfg    ReadinessConfig
+	checks []ReadinessCheck
+	now    func() time.Time
+
+	// mu is held while checks run, so concurrent probes wait for one run
+	// instead of starting their own.
+	mu      sync.Mutex
+	last    ReadinessResponse
+	expires time.Time
+}
+
+func newReadiness(cfg ReadinessConfig, checks []ReadinessCheck) *readiness {
+	return &readiness{cfg: cfg, checks: checks, now: time.Now}
+}
+
+// status returns the cached result, rerunning the checks once it expires.
+func (rd *readiness) status(ctx context.Context) ReadinessResponse {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if now := rd.now(); now.Before(rd.expires) {
+		return rd.last
+	}
+	// The result is shared with later probes, so one probe hanging up must
+	// not cut the checks short.
+	ctx = context.WithoutCancel(ctx)
+	results := make([]DependencyStatus, len(rd.checks))
+	var wg sync.WaitGroup
+	for i, c := range rd.checks {
+		wg.Go(func() {
+			results[i] = rd.run(ctx, c)
+		})
+	}
+	wg.Wait()
+
+	resp := ReadinessResponse{Status: "ready", Checks: results, CheckedAt: rd.now().UTC().Format(time.RFC3339)}
+	for _, r := range results {
+		if r.Required && r.Status != "up" {
+			resp.Status = "unavailable"
+		}
+	}
+	rd.last, rd.expires = resp, rd.now().Add(rd.cfg.CacheTTL)
+	return resp
+}
+
+func (rd *readiness) run(ctx context.Context, c ReadinessCheck) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, rd.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.Check(ctx)
+	st := DependencyStatus{
+		Name:      c.Name,
+		Required:  c.Required,
+		Status:    "up",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		st.Status = "down"
+		slog.WarnContext(ctx, "readiness check failed", "dependency", c.Name, "required", c.Required, "err", err)
+	}
+	return st
+}
+
+func (s *Server) getReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	resp := s.readiness.status(r.Context())
+	if resp.Status != "ready" {
+		// Headers are fixed by WriteHeader, so set the one writeJSON would
+		// set too late.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, r, resp)
+}
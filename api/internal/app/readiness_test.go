@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getReadiness(t *testing.T, srv *Server) (int, ReadinessResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.getReadiness(rec, httptest.NewRequest(http.MethodGet, "/api/readiness", nil))
+	var resp ReadinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+	return rec.Code, resp
+}
+
+func TestReadinessReady(t *testing.T) {
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	code, resp := getReadiness(t, srv)
+	if code != http.StatusOK || resp.Status != "ready" {
+		t.Fatalf("expected 200 ready, got %d %+v", code, resp)
+	}
+	if len(resp.Checks) != 1 || resp.Checks[0].Name != "repository" || !resp.Checks[0].Required || resp.Checks[0].Status != "up" {
+		t.Fatalf("expected the repository check up, got %+v", resp.Checks)
+	}
+}
+
+func TestReadinessRequiredDependencyDown(t *testing.T) {
+	srv := NewServer(&fakeRepo{pingErr: errors.New("permission denied")}, &fakeExplainer{}, &fakeAnalyzer{})
+	code, resp := getReadiness(t, srv)
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" || resp.Checks[0].Status != "down" {
+		t.Fatalf("expected 503 with the repository down, got %d %+v", code, resp)
+	}
+}
+
+func TestReadinessOptionalDependencyDown(t *testing.T) {
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	srv.SetReadiness(DefaultReadinessConfig(), ReadinessCheck{
+		Name:  "llm",
+		Check: func(context.Context) error { return errors.New("key revoked") },
+	})
+	code, resp := getReadiness(t, srv)
+	if code != http.StatusOK || resp.Status != "ready" {
+		t.Fatalf("expected an optional dependency not to fail readiness, got %d %+v", code, resp)
+	}
+	if len(resp.Checks) != 2 || resp.Checks[1].Name != "llm" || resp.Checks[1].Status != "down" {
+		t.Fatalf("expected the llm check reported down, got %+v", resp.Checks)
+	}
+}
+
+func TestReadinessTimesOutSlowChecks(t *testing.T) {
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	srv.SetReadiness(ReadinessConfig{Timeout: 20 * time.Millisecond}, ReadinessCheck{
+		Name:     "slow",
+		Required: true,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	code, resp := getReadiness(t, srv)
+	if code != http.StatusServiceUnavailable || resp.Checks[1].Status != "down" {
+		t.Fatalf("expected the hung check to time out as down, got %d %+v", code, resp)
+	}
+}
+
+func TestReadinessCachesResults(t *testing.T) {
+	calls := 0
+	srv := NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{})
+	srv.SetReadiness(ReadinessConfig{Timeout: time.Second, CacheTTL: time.Minute}, ReadinessCheck{
+		Name:  "counted",
+		Check: func(context.Context) error { calls++; return nil },
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv.readiness.now = func() time.Time { return now }
+
+	getReadiness(t, srv)
+	getReadiness(t, srv)
+	if calls != 1 {
+		t.Fatalf("expected a second probe within the TTL to reuse the result, got %d runs", calls)
+	}
+	now = now.Add(time.Minute)
+	getReadiness(t, srv)
+	if calls != 2 {
+		t.Fatalf("expected the checks to rerun once the TTL passed, got %d runs", calls)
+	}
+}
+
+func TestLLMReadinessCheckListsOpenAIModels(t *testing.T) {
+	status := http.StatusOK
+	var gotPath, gotAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error": {"message": "invalid api key"}}`))
+	}))
+	t.Cleanup(ts.Close)
+	check := LLMReadinessCheck(&openAIChatClient{baseURL: ts.URL + "/v1", apiKey: "sk-test", http: ts.Client()}, false)
+
+	if err := check.Check(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPath != "/v1/models" || gotAuth != "Bearer sk-test" {
+		t.Fatalf("expected an authorized GET /v1/models, got %q %q", gotPath, gotAuth)
+	}
+	status = http.StatusUnauthorized
+	if err := check.Check(context.Background()); err == nil {
+		t.Fatal("expected a rejected key to fail the check")
+	}
+}
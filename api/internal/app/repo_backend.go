@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"cloud.google.com/go/firestore"
+)
+
+// RepoConfig selects the SentenceRepository implementation the server runs
+// against. Backend is one of "firestore" (the default when empty), "memory",
+// "sqlite" or "postgres".
+type RepoConfig struct {
+	Backend string
+	// ProjectID is the Google Cloud project for the firestore backend.
+	ProjectID string
+	// DatabaseURL is the SQLite file path or Postgres connection string for
+	// the SQL backends.
+	DatabaseURL string
+	// SeedFile optionally preloads the memory backend from an NDJSON export
+	// (the format cmd/seed reads). Ignored by the persistent backends, which
+	// are seeded once with cmd/seed instead of on every start.
+	SeedFile string
+	// ReviewPolicy overrides DefaultReviewPolicy when its Scheduler is set.
+	ReviewPolicy ReviewPolicy
+	// ReportPolicy decides when a reported sentence is hidden from everyone.
+	ReportPolicy ReportPolicy
+}
+
+// OpenRepository builds the configured repository. The returned close
+// function releases its underlying client or connection pool and must be
+// called on shutdown.
+func OpenRepository(ctx context.Context, cfg RepoConfig) (SentenceRepository, func() error, error) {
+	repo, closeRepo, err := openRepository(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.ReviewPolicy.Scheduler != nil {
+		repo.(interface{ SetReviewPolicy(ReviewPolicy) }).SetReviewPolicy(cfg.ReviewPolicy)
+	}
+	repo.(interface{ SetReportPolicy(ReportPolicy) }).SetReportPolicy(cfg.ReportPolicy)
+	return repo, closeRepo, nil
+}
+
+func openRepository(ctx context.Context, cfg RepoConfig) (SentenceRepository, func() error, error) {
+	switch cfg.Backend {
+	case "", "firestore":
+		client, err := firestore.NewClient(ctx, cfg.ProjectID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create Firestore client: %w", err)
+		}
+		return NewFirestoreRepo(client), client.Close, nil
+	case "memory":
+		repo := NewMemoryRepo()
+		if cfg.SeedFile != "" {
+			var err error
+			if repo, err = NewMemoryRepoFromFile(cfg.SeedFile); err != nil {
+				return nil, nil, err
+			}
+			slog.Info("using in-memory repository", "seed_file", cfg.SeedFile)
+		}
+		return repo, func() error { return nil }, nil
+	case "sqlite", "postgres":
+		repo, err := OpenSQLRepo(ctx, cfg.Backend, cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown REPO_BACKEND %q (want firestore, memory, sqlite or postgres)", cfg.Backend)
+	}
+}
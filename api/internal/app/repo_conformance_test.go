@@ -0,0 +1,1119 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// repoHarness adapts one SentenceRepository implementation to the
+// conformance suite below. put writes a sentence directly to the backing
+// store (bypassing the repository interface, which has no create method),
+// deleteSentence removes one, and setNow pins the clock RecordAnswer and
+// RandomCandidate use.
+type repoHarness struct {
+	repo           SentenceRepository
+	put            func(t *testing.T, id int, sd sentenceDoc)
+	deleteSentence func(t *testing.T, id int)
+	setNow         func(now time.Time)
+}
+
+// seed puts a sentence with the given fields and fixed page and timestamps.
+func (h repoHarness) seed(t *testing.T, id int, jp, en string, level int, reported bool) {
+	t.Helper()
+	h.put(t, id, sentenceDoc{
+		Japanese: jp, English: en, Page: "1", Level: level, IsReported: reported,
+		CreatedAt: "2026-01-01T00:00:00Z", UpdatedAt: "2026-01-01T00:00:00Z",
+	})
+}
+
+// runRepoConformance runs every behavior the HTTP handlers rely on against a
+// fresh repository from newHarness. Every SentenceRepository implementation
+// must pass it, so a backend swap can't silently change what a learner sees.
+func runRepoConformance(t *testing.T, newHarness func(t *testing.T) repoHarness) {
+	ctx := context.Background()
+
+	t.Run("AcceptedAnswersAndGetSentence", func(t *testing.T) {
+		h := newHarness(t)
+		h.seed(t, 101, "犬", "dog", 3, false)
+		answers, level, err := h.repo.AcceptedAnswers(ctx, 101)
+		if err != nil || len(answers) != 1 || answers[0] != "dog" || level != 3 {
+			t.Fatalf("expected [dog] at level 3, got %q at %d, %v", answers, level, err)
+		}
+		jp, en, err := h.repo.GetSentence(ctx, 101)
+		if err != nil || jp != "犬" || en != "dog" {
+			t.Fatalf("expected 犬/dog, got %q/%q, %v", jp, en, err)
+		}
+	})
+
+	t.Run("AcceptedAnswersIncludeAlternatives", func(t *testing.T) {
+		h := newHarness(t)
+		h.put(t, 102, sentenceDoc{
+			Japanese: "時間がありません。", English: "I don't have time.", Page: "1", Level: 1,
+			CreatedAt: "2026-01-01T00:00:00Z", UpdatedAt: "2026-01-01T00:00:00Z",
+			AcceptedAnswers: []string{"I have no time.", "i don’t have time", "There's no time."},
+		})
+		answers, _, err := h.repo.AcceptedAnswers(ctx, 102)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The curly-apostrophe duplicate of English normalizes away.
+		want := []string{"I don't have time.", "I have no time.", "There's no time."}
+		if fmt.Sprint(answers) != fmt.Sprint(want) {
+			t.Fatalf("expected %q, got %q", want, answers)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		h := newHarness(t)
+		if _, _, err := h.repo.AcceptedAnswers(ctx, 424242); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("AcceptedAnswers: expected ErrNotFound, got %v", err)
+		}
+		if _, _, err := h.repo.GetSentence(ctx, 424242); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetSentence: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("RecordListAndCount", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-record"
+		h.seed(t, 201, "こんにちは", "Hello", 1, false)
+		if _, err := h.repo.RecordAnswer(ctx, uid, 201, AnswerRecord{Answer: "Hi there"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.repo.RecordAnswer(ctx, uid, 201, AnswerRecord{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		hs, err := h.repo.ListIncorrectHistories(ctx, uid, 201)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 1 || hs[0].IncorrectAnswer != "Hi there" {
+			t.Fatalf("expected 1 incorrect history 'Hi there', got %+v", hs)
+		}
+		if hs[0].ID == 0 || hs[0].CreatedAt == "" {
+			t.Fatalf("history id/created_at should be populated, got %+v", hs[0])
+		}
+
+		s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.CorrectCount != 1 || s.IncorrectCount != 1 {
+			t.Fatalf("expected counts 1/1, got %d/%d", s.CorrectCount, s.IncorrectCount)
+		}
+	})
+
+	t.Run("RecordAnswerReturnsHistoryIDAndVerdict", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-verdict"
+		h.seed(t, 211, "時間がありません。", "I don't have time.", 1, false)
+		id, err := h.repo.RecordAnswer(ctx, uid, 211, AnswerRecord{
+			Answer: "I has no time.", GradedBy: GradedAI, GraderReason: "Subject-verb agreement.",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		exactID, err := h.repo.RecordAnswer(ctx, uid, 211, AnswerRecord{Answer: "No time."})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hs, err := h.repo.ListIncorrectHistories(ctx, uid, 211)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 2 {
+			t.Fatalf("expected 2 histories, got %+v", hs)
+		}
+		if hs[1].ID != id || hs[1].GradedBy != GradedAI || hs[1].GraderReason != "Subject-verb agreement." {
+			t.Fatalf("expected the AI verdict on history %d, got %+v", id, hs[1])
+		}
+		if hs[0].ID != exactID || hs[0].GradedBy != GradedExact || hs[0].GraderReason != "" {
+			t.Fatalf("expected an exact verdict on history %d, got %+v", exactID, hs[0])
+		}
+	})
+
+	t.Run("OverrideAnswerFlipsVerdictAndCounts", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-override"
+		h.seed(t, 221, "犬", "dog", 1, false)
+		h.setNow(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+		rejected, err := h.repo.RecordAnswer(ctx, uid, 221, AnswerRecord{Answer: "hound", GradedBy: GradedAI})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.repo.OverrideAnswer(ctx, uid, 221, rejected, AnswerOverride{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+		// Overriding to the verdict the entry already has changes nothing.
+		if err := h.repo.OverrideAnswer(ctx, uid, 221, rejected, AnswerOverride{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.CorrectCount != 1 || s.IncorrectCount != 0 {
+			t.Fatalf("expected counts 1/0 after the override, got %d/%d", s.CorrectCount, s.IncorrectCount)
+		}
+		hs, err := h.repo.ListIncorrectHistories(ctx, uid, 221)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 0 {
+			t.Fatalf("expected no incorrect histories after the override, got %+v", hs)
+		}
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != 0 {
+			t.Fatalf("expected no mistakes after the override, got %+v", mistakes)
+		}
+
+		// An AI acceptance can be overridden the other way.
+		h.setNow(time.Date(2026, 1, 1, 9, 1, 0, 0, time.UTC))
+		accepted, err := h.repo.RecordAnswer(ctx, uid, 221, AnswerRecord{Correct: true, Answer: "doggo", GradedBy: GradedAI})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.repo.OverrideAnswer(ctx, uid, 221, accepted, AnswerOverride{}); err != nil {
+			t.Fatal(err)
+		}
+		hs, err = h.repo.ListIncorrectHistories(ctx, uid, 221)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 1 || hs[0].ID != accepted || hs[0].IncorrectAnswer != "doggo" || !hs[0].Overridden {
+			t.Fatalf("expected the overridden acceptance as the only incorrect history, got %+v", hs)
+		}
+	})
+
+	t.Run("OverrideAnswerTellsApartSameInstantAnswers", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-override-same-instant"
+		h.seed(t, 251, "鳥", "bird", 1, false)
+		h.setNow(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+		first, err := h.repo.RecordAnswer(ctx, uid, 251, AnswerRecord{Answer: "birb"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := h.repo.RecordAnswer(ctx, uid, 251, AnswerRecord{Answer: "a bird"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == second {
+			t.Fatalf("expected distinct history IDs, got %d twice", first)
+		}
+		if err := h.repo.OverrideAnswer(ctx, uid, 251, second, AnswerOverride{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+		hs, err := h.repo.ListIncorrectHistories(ctx, uid, 251)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 1 || hs[0].ID != first || hs[0].IncorrectAnswer != "birb" {
+			t.Fatalf("expected only the first answer to remain incorrect, got %+v", hs)
+		}
+	})
+
+	t.Run("OverrideAnswerFlipsExactRejectionAndPromotes", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-override-promote"
+		h.seed(t, 241, "時間がない", "I don't have time.", 1, false)
+		h.setNow(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+		rejected, err := h.repo.RecordAnswer(ctx, uid, 241, AnswerRecord{Answer: "I have no time."})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(time.Date(2026, 1, 1, 9, 1, 0, 0, time.UTC))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 241, AnswerRecord{Answer: "No time."}); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.repo.OverrideAnswer(ctx, uid, 241, rejected, AnswerOverride{Correct: true, Promote: true}); err != nil {
+			t.Fatal(err)
+		}
+		// Promoting an answer that is already accepted adds nothing.
+		if err := h.repo.OverrideAnswer(ctx, uid, 241, rejected, AnswerOverride{Correct: true, Promote: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		answers, _, err := h.repo.AcceptedAnswers(ctx, 241)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"I don't have time.", "I have no time."}
+		if fmt.Sprint(answers) != fmt.Sprint(want) {
+			t.Fatalf("expected %q, got %q", want, answers)
+		}
+		s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.CorrectCount != 1 || s.IncorrectCount != 1 {
+			t.Fatalf("expected counts 1/1 after the override, got %d/%d", s.CorrectCount, s.IncorrectCount)
+		}
+		hs, err := h.repo.ListIncorrectHistories(ctx, uid, 241)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 1 || hs[0].IncorrectAnswer != "No time." {
+			t.Fatalf("expected only the other rejection to remain, got %+v", hs)
+		}
+	})
+
+	t.Run("OverrideAnswerRejectsExactAcceptanceAndUnknownEntries", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-override-errors"
+		h.seed(t, 231, "猫", "cat", 1, false)
+		exact, err := h.repo.RecordAnswer(ctx, uid, 231, AnswerRecord{Correct: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.repo.OverrideAnswer(ctx, uid, 231, exact, AnswerOverride{}); !errors.Is(err, ErrNotOverridable) {
+			t.Fatalf("expected ErrNotOverridable for an exact-match acceptance, got %v", err)
+		}
+		if err := h.repo.OverrideAnswer(ctx, uid, 231, exact+1, AnswerOverride{Correct: true}); !errors.Is(err, ErrHistoryNotFound) {
+			t.Fatalf("expected ErrHistoryNotFound for an unknown entry, got %v", err)
+		}
+		if err := h.repo.OverrideAnswer(ctx, "someone-else", 231, exact, AnswerOverride{Correct: true}); !errors.Is(err, ErrHistoryNotFound) {
+			t.Fatalf("expected ErrHistoryNotFound for another user's entry, got %v", err)
+		}
+	})
+
+	t.Run("GenerationCacheRoundTripsUntilExpiry", func(t *testing.T) {
+		h := newHarness(t)
+		base := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		h.setNow(base)
+		if _, ok, err := h.repo.CachedGeneration(ctx, "explain:missing"); err != nil || ok {
+			t.Fatalf("expected a miss, got ok=%v err=%v", ok, err)
+		}
+		if err := h.repo.CacheGeneration(ctx, "explain:k", "first", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.repo.CacheGeneration(ctx, "explain:k", "second", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(base.Add(59 * time.Minute))
+		text, ok, err := h.repo.CachedGeneration(ctx, "explain:k")
+		if err != nil || !ok || text != "second" {
+			t.Fatalf("expected the replaced entry, got %q ok=%v err=%v", text, ok, err)
+		}
+		h.setNow(base.Add(time.Hour))
+		if _, ok, err := h.repo.CachedGeneration(ctx, "explain:k"); err != nil || ok {
+			t.Fatalf("expected the entry to expire after its ttl, got ok=%v err=%v", ok, err)
+		}
+	})
+
+	t.Run("AdminTriagesReportedSentences", func(t *testing.T) {
+		h := newHarness(t)
+		h.seed(t, 901, "A", "A-en", 1, true)
+		h.seed(t, 902, "B", "B-en", 1, false)
+		h.seed(t, 903, "C", "C-en", 1, true)
+		h.seed(t, 904, "D", "D-en", 2, false)
+		h.setNow(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))
+		if err := h.repo.Report(ctx, "user-a", 904, ReportRecord{Reason: ReportWrongTranslation, Comment: "past tense"}); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC))
+		if err := h.repo.Report(ctx, "user-b", 904, ReportRecord{Reason: ReportTypo}); err != nil {
+			t.Fatal(err)
+		}
+		reported, err := h.repo.ListReported(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reported) != 3 || reported[0].ID != 901 || reported[1].ID != 903 || reported[2].ID != 904 || !reported[0].IsReported {
+			t.Fatalf("expected sentences 901, 903 and 904, got %+v", reported)
+		}
+		if reports := reported[0].Reports; reports == nil || len(reports) != 0 {
+			t.Fatalf("expected a seeded-as-reported sentence to have no reports, got %#v", reports)
+		}
+		want := []SentenceReport{
+			{UID: "user-a", Reason: ReportWrongTranslation, Comment: "past tense", CreatedAt: "2026-01-05T09:00:00Z"},
+			{UID: "user-b", Reason: ReportTypo, CreatedAt: "2026-01-06T09:00:00Z"},
+		}
+		if reported[2].IsReported || fmt.Sprint(reported[2].Reports) != fmt.Sprint(want) {
+			t.Fatalf("expected 904 to stay unhidden with reports %+v, got %+v", want, reported[2])
+		}
+		if err := h.repo.Unreport(ctx, 904); err != nil {
+			t.Fatal(err)
+		}
+		if s, err := h.repo.RandomCandidate(ctx, "user-a", CandidateQuery{Levels: []int{2}}); err != nil || s.ID != 904 {
+			t.Fatalf("expected un-reporting to return 904 to its reporter, got %+v, %v", s, err)
+		}
+
+		h.setNow(time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC))
+		edit := SentenceEdit{Japanese: "A2", English: "A2-en", Page: "7", Level: 3}
+		if err := h.repo.UpdateSentence(ctx, 901, edit); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.repo.Unreport(ctx, 901); err != nil {
+			t.Fatal(err)
+		}
+		reported, err = h.repo.ListReported(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reported) != 1 || reported[0].ID != 903 {
+			t.Fatalf("expected only 903 still reported, got %+v", reported)
+		}
+		s, err := h.repo.RandomCandidate(ctx, "user-admin", CandidateQuery{Levels: []int{3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != 901 || s.Japanese != "A2" || s.English != "A2-en" || s.Page != "7" || s.UpdatedAt != "2026-02-01T09:00:00Z" {
+			t.Fatalf("expected the edited, un-reported sentence back in practice, got %+v", s)
+		}
+
+		if err := h.repo.DeleteSentence(ctx, 903); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := h.repo.GetSentence(ctx, 903); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound after delete, got %v", err)
+		}
+		for name, err := range map[string]error{
+			"update":   h.repo.UpdateSentence(ctx, 999, edit),
+			"unreport": h.repo.Unreport(ctx, 999),
+			"delete":   h.repo.DeleteSentence(ctx, 903),
+		} {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("UpdateRateStateRoundTrips", func(t *testing.T) {
+		h := newHarness(t)
+		at := time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC)
+		day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		err := h.repo.UpdateRateState(ctx, "user-a:explain", func(st RateState) RateState {
+			if st != (RateState{}) {
+				t.Errorf("expected the zero state for a new key, got %+v", st)
+			}
+			return RateState{Tokens: 2.5, UpdatedAt: at, Day: day, DayCount: 3}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got RateState
+		err = h.repo.UpdateRateState(ctx, "user-a:explain", func(st RateState) RateState {
+			got = st
+			st.DayCount++
+			return st
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Tokens != 2.5 || !got.UpdatedAt.Equal(at) || !got.Day.Equal(day) || got.DayCount != 3 {
+			t.Fatalf("expected the saved state back, got %+v", got)
+		}
+		err = h.repo.UpdateRateState(ctx, "user-b:explain", func(st RateState) RateState {
+			if st.DayCount != 0 {
+				t.Errorf("expected keys to be independent, got %+v", st)
+			}
+			return st
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ListIncorrectHistoriesEmptyIsNotNil", func(t *testing.T) {
+		h := newHarness(t)
+		h.seed(t, 251, "A", "A", 1, false)
+		hs, err := h.repo.ListIncorrectHistories(ctx, "user-empty", 251)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hs == nil || len(hs) != 0 {
+			t.Fatalf("expected an empty, non-nil slice, got %#v", hs)
+		}
+	})
+
+	t.Run("StatsArePerUser", func(t *testing.T) {
+		h := newHarness(t)
+		h.seed(t, 271, "A", "A", 1, false)
+		if _, err := h.repo.RecordAnswer(ctx, "user-a", 271, AnswerRecord{Answer: "wrong"}); err != nil {
+			t.Fatal(err)
+		}
+		hs, err := h.repo.ListIncorrectHistories(ctx, "user-b", 271)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != 0 {
+			t.Fatalf("expected user-b to see none of user-a's history, got %+v", hs)
+		}
+	})
+
+	t.Run("RandomExcludesReported", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-filter"
+		h.seed(t, 302, "B", "B", 1, true)  // reported
+		h.seed(t, 303, "C", "C", 1, false) // remains a valid candidate
+		for i := 0; i < 8; i++ {
+			s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+			if err != nil {
+				t.Fatalf("iteration %d: %v", i, err)
+			}
+			if s.ID != 303 {
+				t.Fatalf("expected only sentence 303, got %d", s.ID)
+			}
+		}
+	})
+
+	t.Run("RandomServesMasteredSentenceWhenDue", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-mastery"
+		h.seed(t, 311, "A", "A", 1, false)
+		// Three correct answers, each when due, master 311 and space it 1,
+		// 6 and then 15 days out.
+		at := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		for _, days := range []int{0, 1, 6} {
+			at = at.AddDate(0, 0, days)
+			h.setNow(at)
+			if s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{}); err != nil || s.ID != 311 {
+				t.Fatalf("expected 311 served on %s, got %+v, %v", at, s, err)
+			}
+			if _, err := h.repo.RecordAnswer(ctx, uid, 311, AnswerRecord{Correct: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		h.seed(t, 312, "B", "B", 1, false)
+		h.setNow(at.AddDate(0, 0, 14))
+		if s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{}); err != nil || s.ID != 312 {
+			t.Fatalf("expected the new 312 while 311 is not due, got %+v, %v", s, err)
+		}
+		h.setNow(at.AddDate(0, 0, 15))
+		for _, q := range []CandidateQuery{{}, {Source: SourceReview}} {
+			if s, err := h.repo.RandomCandidate(ctx, uid, q); err != nil || s.ID != 311 {
+				t.Fatalf("%+v: expected the mastered 311 back once due, got %+v, %v", q, s, err)
+			}
+		}
+		if _, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{Source: SourceMistakes}); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected no mistakes to drill, got %v", err)
+		}
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		h := newHarness(t)
+		if err := h.repo.Ping(ctx); err != nil {
+			t.Fatalf("expected a reachable repository to answer a ping, got %v", err)
+		}
+	})
+
+	t.Run("RandomNoCandidate", func(t *testing.T) {
+		h := newHarness(t)
+		if _, err := h.repo.RandomCandidate(ctx, "user-none", CandidateQuery{}); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected ErrNoCandidate on an empty corpus, got %v", err)
+		}
+	})
+
+	t.Run("RandomFiltersByLevels", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-level"
+		h.seed(t, 401, "A", "A", 1, false)
+		h.seed(t, 402, "B", "B", 3, false)
+		h.seed(t, 403, "C", "C", 5, false)
+		for i := 0; i < 8; i++ {
+			s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{Levels: []int{1, 3}})
+			if err != nil {
+				t.Fatalf("iteration %d: %v", i, err)
+			}
+			if s.ID != 401 && s.ID != 402 {
+				t.Fatalf("expected level-1 or level-3 sentence, got %d", s.ID)
+			}
+		}
+		if _, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{Levels: []int{2}}); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected ErrNoCandidate for level 2, got %v", err)
+		}
+	})
+
+	t.Run("RandomFiltersBySourceAndExclusions", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-source"
+		h.seed(t, 411, "A", "A", 1, false)
+		h.seed(t, 412, "B", "B", 1, false)
+		h.seed(t, 413, "C", "C", 1, false)
+		h.seed(t, 414, "D", "D", 2, false)
+		base := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		h.setNow(base)
+		// 412 is missed, so due again shortly; 413 is answered correctly,
+		// so not due for a day. 411 and 414 are never seen.
+		if _, err := h.repo.RecordAnswer(ctx, uid, 412, AnswerRecord{Answer: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.repo.RecordAnswer(ctx, uid, 413, AnswerRecord{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(base.Add(time.Hour))
+
+		only := func(q CandidateQuery, want ...int) {
+			t.Helper()
+			for i := 0; i < 8; i++ {
+				s, err := h.repo.RandomCandidate(ctx, uid, q)
+				if err != nil {
+					t.Fatalf("%+v: %v", q, err)
+				}
+				if !slices.Contains(want, s.ID) {
+					t.Fatalf("%+v: expected one of %v, got %d", q, want, s.ID)
+				}
+			}
+		}
+		only(CandidateQuery{Source: SourceNew}, 411, 414)
+		only(CandidateQuery{Source: SourceNew, Levels: []int{1}}, 411)
+		only(CandidateQuery{Source: SourceSeen}, 412, 413)
+		only(CandidateQuery{Source: SourceReview}, 412)
+		only(CandidateQuery{Source: SourceMistakes}, 412)
+		only(CandidateQuery{Source: SourceAll, Exclude: []int{411, 412, 414}}, 413)
+		if _, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{Source: SourceMistakes, Exclude: []int{412}}); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected ErrNoCandidate once the only mistake is excluded, got %v", err)
+		}
+
+		// A day on, 413 is due too.
+		h.setNow(base.Add(25 * time.Hour))
+		only(CandidateQuery{Source: SourceReview, Exclude: []int{412}}, 413)
+	})
+
+	t.Run("RandomServesMistakesUntilMastered", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-drill"
+		policy := DefaultReviewPolicy()
+		policy.MasteryThreshold = 1
+		h.repo.(interface{ SetReviewPolicy(ReviewPolicy) }).SetReviewPolicy(policy)
+		h.seed(t, 421, "A", "A", 1, false)
+		h.seed(t, 422, "B", "B", 1, false)
+		if _, err := h.repo.RecordAnswer(ctx, uid, 421, AnswerRecord{Answer: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		q := CandidateQuery{Source: SourceMistakes}
+		for i := 0; i < 2; i++ {
+			s, err := h.repo.RandomCandidate(ctx, uid, q)
+			if err != nil || s.ID != 421 {
+				t.Fatalf("answer %d: expected the mistake 421, got %+v, %v", i, s, err)
+			}
+			if _, err := h.repo.RecordAnswer(ctx, uid, 421, AnswerRecord{Correct: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 2 correct against 1 incorrect masters it, leaving no mistake.
+		if _, err := h.repo.RandomCandidate(ctx, uid, q); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected ErrNoCandidate once 421 is mastered, got %v", err)
+		}
+	})
+
+	t.Run("RandomServesMostOverdueFirst", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-overdue"
+		h.seed(t, 451, "A", "A", 1, false)
+		h.seed(t, 452, "B", "B", 1, false)
+		h.seed(t, 453, "C", "C", 1, false)
+		base := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		// 452 is missed first, so it falls due first; 451 a minute later.
+		h.setNow(base)
+		if _, err := h.repo.RecordAnswer(ctx, uid, 452, AnswerRecord{Answer: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(base.Add(time.Minute))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 451, AnswerRecord{Answer: "x"}); err != nil {
+			t.Fatal(err)
+		}
+
+		h.setNow(base.Add(time.Hour))
+		for i := 0; i < 5; i++ {
+			s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.ID != 452 {
+				t.Fatalf("iteration %d: expected the most overdue sentence 452, got %d", i, s.ID)
+			}
+		}
+
+		// Reviewing 452 reschedules it a day out, leaving 451 most overdue.
+		if _, err := h.repo.RecordAnswer(ctx, uid, 452, AnswerRecord{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+		s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != 451 {
+			t.Fatalf("expected 451 after reviewing 452, got %d", s.ID)
+		}
+	})
+
+	t.Run("RandomRespectsNewCardLimit", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-new-limit"
+		policy := DefaultReviewPolicy()
+		policy.NewPerDay = 1
+		h.repo.(interface{ SetReviewPolicy(ReviewPolicy) }).SetReviewPolicy(policy)
+		h.seed(t, 461, "A", "A", 1, false)
+		h.seed(t, 462, "B", "B", 1, false)
+
+		day1 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		h.setNow(day1)
+		if _, err := h.repo.RecordAnswer(ctx, uid, 461, AnswerRecord{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Today's one new card is used up and 461 isn't due until tomorrow,
+		// so practice continues ahead of schedule on 461 rather than
+		// introducing 462.
+		h.setNow(day1.Add(time.Hour))
+		for i := 0; i < 5; i++ {
+			s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.ID != 461 {
+				t.Fatalf("iteration %d: expected 461 with the new-card limit reached, got %d", i, s.ID)
+			}
+		}
+
+		// The limit resets at midnight, before 461 falls due at 09:00.
+		h.setNow(time.Date(2026, 1, 2, 0, 30, 0, 0, time.UTC))
+		s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != 462 {
+			t.Fatalf("expected new sentence 462 the next day, got %d", s.ID)
+		}
+	})
+
+	t.Run("RandomRespectsReviewLimit", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-review-limit"
+		policy := DefaultReviewPolicy()
+		policy.ReviewsPerDay = 1
+		h.repo.(interface{ SetReviewPolicy(ReviewPolicy) }).SetReviewPolicy(policy)
+		h.seed(t, 471, "A", "A", 1, false)
+		h.seed(t, 472, "B", "B", 1, false)
+		h.seed(t, 473, "C", "C", 1, false)
+
+		h.setNow(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+		for _, id := range []int{471, 472} {
+			if _, err := h.repo.RecordAnswer(ctx, uid, id, AnswerRecord{Answer: "x"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		h.setNow(time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 471, AnswerRecord{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		// 472 is still due, but today's one review is spent, so the new
+		// sentence comes first.
+		s, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != 473 {
+			t.Fatalf("expected new sentence 473 with the review limit reached, got %d", s.ID)
+		}
+	})
+
+	t.Run("ReportHidesSentenceOnlyFromReporterByDefault", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-report"
+		h.seed(t, 451, "A", "A", 1, false)
+		if err := h.repo.Report(ctx, uid, 451, ReportRecord{Reason: ReportTypo}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.repo.RandomCandidate(ctx, uid, CandidateQuery{}); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected the reported sentence to be hidden from its reporter, got %v", err)
+		}
+		s, err := h.repo.RandomCandidate(ctx, "user-other", CandidateQuery{})
+		if err != nil || s.ID != 451 {
+			t.Fatalf("expected other learners to keep practicing 451, got %+v, %v", s, err)
+		}
+		if err := h.repo.Report(ctx, uid, 999, ReportRecord{Reason: ReportTypo}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown sentence, got %v", err)
+		}
+	})
+
+	t.Run("ReportHidesSentenceGloballyAfterThreshold", func(t *testing.T) {
+		h := newHarness(t)
+		h.repo.(interface{ SetReportPolicy(ReportPolicy) }).SetReportPolicy(ReportPolicy{HideAfter: 2})
+		h.seed(t, 461, "A", "A", 1, false)
+		for _, uid := range []string{"user-a", "user-a"} {
+			if err := h.repo.Report(ctx, uid, 461, ReportRecord{Reason: ReportOther}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := h.repo.RandomCandidate(ctx, "user-c", CandidateQuery{}); err != nil {
+			t.Fatalf("expected a repeat report by the same learner not to count twice, got %v", err)
+		}
+		if err := h.repo.Report(ctx, "user-b", 461, ReportRecord{Reason: ReportOther}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.repo.RandomCandidate(ctx, "user-c", CandidateQuery{}); !errors.Is(err, ErrNoCandidate) {
+			t.Fatalf("expected two reporters to hide 461 from everyone, got %v", err)
+		}
+	})
+
+	t.Run("ListMistakesGroupsWrongAnswersMostRecentSentenceFirst", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-mistakes"
+		h.seed(t, 701, "時間がありません。", "I don't have time.", 1, false)
+		h.seed(t, 702, "彼は毎朝走ります。", "He runs every morning.", 1, false)
+		h.setNow(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 701, AnswerRecord{Answer: "I have no time."}); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 702, AnswerRecord{Answer: "He run every morning."}); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 701, AnswerRecord{Answer: "There is no time."}); err != nil {
+			t.Fatal(err)
+		}
+
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != 2 || mistakes[0].SentenceID != 701 || mistakes[1].SentenceID != 702 {
+			t.Fatalf("expected sentences [701 702], got %+v", mistakes)
+		}
+		if mistakes[0].Japanese != "時間がありません。" || mistakes[0].CorrectAnswer != "I don't have time." {
+			t.Fatalf("unexpected sentence fields: %+v", mistakes[0])
+		}
+		if len(mistakes[0].WrongAnswers) != 2 || mistakes[0].WrongAnswers[0].IncorrectAnswer != "There is no time." {
+			t.Fatalf("expected 2 wrong answers for 701, newest first, got %+v", mistakes[0].WrongAnswers)
+		}
+	})
+
+	t.Run("ListMistakesOrdersBySubSecondPrecision", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-subsecond"
+		h.seed(t, 1001, "A", "A-en", 1, false)
+		h.seed(t, 1002, "B", "B-en", 1, false)
+		sameSecond := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+		h.setNow(sameSecond.Add(100 * time.Millisecond))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 1001, AnswerRecord{Answer: "wrong A"}); err != nil {
+			t.Fatal(err)
+		}
+		h.setNow(sameSecond.Add(900 * time.Millisecond))
+		if _, err := h.repo.RecordAnswer(ctx, uid, 1002, AnswerRecord{Answer: "wrong B"}); err != nil {
+			t.Fatal(err)
+		}
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != 2 || mistakes[0].SentenceID != 1002 {
+			t.Fatalf("expected sentence 1002 first, got %+v", mistakes)
+		}
+	})
+
+	t.Run("ListMistakesExcludesNeverMissedAndDeleted", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-clean"
+		h.seed(t, 801, "A", "A-en", 1, false)
+		h.seed(t, 802, "B", "B-en", 1, false)
+		if _, err := h.repo.RecordAnswer(ctx, uid, 801, AnswerRecord{Correct: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.repo.RecordAnswer(ctx, uid, 802, AnswerRecord{Answer: "wrong"}); err != nil {
+			t.Fatal(err)
+		}
+		h.deleteSentence(t, 802)
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != 0 {
+			t.Fatalf("expected no mistakes, got %+v", mistakes)
+		}
+	})
+
+	t.Run("ListMistakesFiltersSortsAndPages", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-query"
+		h.seed(t, 901, "一", "one", 1, false)
+		h.seed(t, 902, "二", "two", 2, false)
+		h.seed(t, 903, "三", "three", 3, false)
+		base := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+		// 901: 0/3 correct, last missed at 2m. 902: 3/4, and mastered, last
+		// missed at 3m. 903: 1/3, last missed at 8m.
+		for i, a := range []struct {
+			id      int
+			correct bool
+		}{
+			{901, false}, {901, false}, {901, false},
+			{902, false}, {902, true}, {902, true}, {902, true},
+			{903, false}, {903, false}, {903, true},
+		} {
+			h.setNow(base.Add(time.Duration(i) * time.Minute))
+			if _, err := h.repo.RecordAnswer(ctx, uid, a.id, AnswerRecord{Correct: a.correct, Answer: "x"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		list := func(q MistakeQuery) ([]int, string) {
+			t.Helper()
+			mistakes, next, err := h.repo.ListMistakes(ctx, uid, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []int{}
+			for _, m := range mistakes {
+				ids = append(ids, m.SentenceID)
+			}
+			return ids, next
+		}
+
+		for name, tc := range map[string]struct {
+			q    MistakeQuery
+			want []int
+		}{
+			"recent":         {MistakeQuery{}, []int{903, 902, 901}},
+			"most missed":    {MistakeQuery{Sort: MistakesByMostMissed}, []int{901, 903, 902}},
+			"worst accuracy": {MistakeQuery{Sort: MistakesByWorstAccuracy}, []int{901, 903, 902}},
+			"levels":         {MistakeQuery{Levels: []int{1, 3}}, []int{903, 901}},
+			"since":          {MistakeQuery{Since: base.Add(3 * time.Minute)}, []int{903, 902}},
+			"min incorrect":  {MistakeQuery{MinIncorrect: 2, Sort: MistakesByWorstAccuracy}, []int{901, 903}},
+			"unmastered":     {MistakeQuery{Unmastered: true}, []int{903, 901}},
+		} {
+			if got, _ := list(tc.q); !slices.Equal(got, tc.want) {
+				t.Errorf("%s: expected %v, got %v", name, tc.want, got)
+			}
+		}
+
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{Levels: []int{1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := mistakes[0]; m.Level != 1 || m.CorrectCount != 0 || m.IncorrectCount != 3 || len(m.WrongAnswers) != 3 {
+			t.Fatalf("expected 901's level, counts and every wrong answer, got %+v", m)
+		}
+
+		for _, sort := range []MistakeSort{MistakesByRecent, MistakesByMostMissed} {
+			var got []int
+			q := MistakeQuery{Sort: sort, Limit: 2}
+			for range 3 {
+				page, next := list(q)
+				got = append(got, page...)
+				if next == "" {
+					break
+				}
+				q.Cursor = next
+			}
+			want, _ := list(MistakeQuery{Sort: sort})
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: expected pages of 2 to cover %v, got %v", sort, want, got)
+			}
+		}
+
+		_, next := list(MistakeQuery{Sort: MistakesByMostMissed, Limit: 1})
+		for _, q := range []MistakeQuery{{Cursor: "not a cursor"}, {Cursor: next}} {
+			if _, _, err := h.repo.ListMistakes(ctx, uid, q); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("expected ErrInvalidCursor for %+v, got %v", q, err)
+			}
+		}
+	})
+
+	t.Run("InsightCapsWrongAnswersPerSentence", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-many-wrong"
+		h.seed(t, 301, "多い間違い", "Many mistakes", 1, false)
+		base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		const attempts = maxWrongAnswersPerSentence + 3
+		for i := 0; i < attempts; i++ {
+			h.setNow(base.Add(time.Duration(i) * time.Minute))
+			if _, err := h.repo.RecordAnswer(ctx, uid, 301, AnswerRecord{Answer: fmt.Sprintf("wrong-%d", i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		insight, err := h.repo.ListMistakesForInsight(ctx, uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(insight) != 1 || len(insight[0].WrongAnswers) != maxWrongAnswersPerSentence {
+			t.Fatalf("expected 1 sentence with %d wrong answers, got %+v", maxWrongAnswersPerSentence, insight)
+		}
+		if got := insight[0].WrongAnswers[0].IncorrectAnswer; got != fmt.Sprintf("wrong-%d", attempts-1) {
+			t.Fatalf("expected the newest attempt first, got %q", got)
+		}
+
+		// The raw list and the check-answer panel stay unbounded.
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != 1 || len(mistakes[0].WrongAnswers) != attempts {
+			t.Fatalf("expected all %d attempts on the raw list, got %+v", attempts, mistakes)
+		}
+		hs, err := h.repo.ListIncorrectHistories(ctx, uid, 301)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hs) != attempts {
+			t.Fatalf("expected all %d attempts from ListIncorrectHistories, got %d", attempts, len(hs))
+		}
+	})
+
+	t.Run("InsightCapsSentenceScan", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-many-sentences"
+		total := maxInsightStatsScan + 5
+		base := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+		for i := 0; i < total; i++ {
+			id := 2000 + i
+			h.seed(t, id, fmt.Sprintf("文%d", i), fmt.Sprintf("sentence %d", i), 1, false)
+			h.setNow(base.Add(time.Duration(i) * time.Minute))
+			if _, err := h.repo.RecordAnswer(ctx, uid, id, AnswerRecord{Answer: "wrong"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		insight, err := h.repo.ListMistakesForInsight(ctx, uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(insight) != maxInsightStatsScan {
+			t.Fatalf("expected the insight scan capped at %d, got %d", maxInsightStatsScan, len(insight))
+		}
+		mistakes, _, err := h.repo.ListMistakes(ctx, uid, MistakeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mistakes) != total {
+			t.Fatalf("expected all %d mistakes on the raw list, got %d", total, len(mistakes))
+		}
+	})
+
+	t.Run("InsightSkipsCorrectOnlyStatsWhenScanning", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-crowded-out"
+		h.seed(t, 3000, "古い間違い", "old mistake", 1, false)
+		base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+		h.setNow(base)
+		if _, err := h.repo.RecordAnswer(ctx, uid, 3000, AnswerRecord{Answer: "wrong"}); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < maxInsightStatsScan+10; i++ {
+			id := 3001 + i
+			h.seed(t, id, "正解"+strconv.Itoa(i), "correct "+strconv.Itoa(i), 1, false)
+			h.setNow(base.Add(time.Duration(i+1) * time.Minute))
+			if _, err := h.repo.RecordAnswer(ctx, uid, id, AnswerRecord{Correct: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		insight, err := h.repo.ListMistakesForInsight(ctx, uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(insight) != 1 || insight[0].SentenceID != 3000 {
+			t.Fatalf("expected the older mistake 3000 to still be found, got %+v", insight)
+		}
+	})
+
+	t.Run("PracticeSessions", func(t *testing.T) {
+		h := newHarness(t)
+		uid := "user-session"
+		created := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+		sess := PracticeSession{ID: "0123456789abcdef0123456789abcdef", Size: 2, Levels: []int{1, 3}, Source: SourceMistakes, CreatedAt: created}
+		if err := h.repo.CreateSession(ctx, uid, sess); err != nil {
+			t.Fatal(err)
+		}
+		got, err := h.repo.GetSession(ctx, uid, sess.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != sess.ID || got.Size != 2 || fmt.Sprint(got.Levels) != "[1 3]" || got.Source != SourceMistakes ||
+			!got.CreatedAt.Equal(created) || !got.CompletedAt.IsZero() || len(got.Questions) != 0 {
+			t.Fatalf("expected the session as created, got %+v", got)
+		}
+
+		served := created.Add(time.Minute)
+		err = h.repo.UpdateSession(ctx, uid, sess.ID, func(s *PracticeSession) error {
+			s.Questions = append(s.Questions, SessionQuestion{SentenceID: 7, Japanese: "犬", English: "dog", ServedAt: served})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		boom := errors.New("boom")
+		err = h.repo.UpdateSession(ctx, uid, sess.ID, func(s *PracticeSession) error {
+			s.CompletedAt = served
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected fn's error back, got %v", err)
+		}
+		got, err = h.repo.GetSession(ctx, uid, sess.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Questions) != 1 || got.Questions[0].SentenceID != 7 || got.Questions[0].English != "dog" ||
+			!got.Questions[0].ServedAt.Equal(served) || !got.Questions[0].AnsweredAt.IsZero() {
+			t.Fatalf("expected the served question saved, got %+v", got.Questions)
+		}
+		if !got.CompletedAt.IsZero() {
+			t.Fatalf("expected a failed update not to be saved, got %+v", got)
+		}
+
+		if _, err := h.repo.GetSession(ctx, "user-other", sess.ID); !errors.Is(err, ErrSessionNotFound) {
+			t.Fatalf("expected another learner's session not found, got %v", err)
+		}
+		err = h.repo.UpdateSession(ctx, uid, "fedcba9876543210fedcba9876543210", func(*PracticeSession) error { return nil })
+		if !errors.Is(err, ErrSessionNotFound) {
+			t.Fatalf("expected ErrSessionNotFound, got %v", err)
+		}
+	})
+}
+
+func TestMemoryRepoConformance(t *testing.T) {
+	runRepoConformance(t, func(t *testing.T) repoHarness {
+		repo := NewMemoryRepo()
+		return repoHarness{
+			repo:           repo,
+			put:            func(_ *testing.T, id int, sd sentenceDoc) { repo.putSentence(id, sd) },
+			deleteSentence: func(_ *testing.T, id int) { repo.deleteSentence(id) },
+			setNow:         func(now time.Time) { repo.now = func() time.Time { return now } },
+		}
+	})
+}
+
+func TestFirestoreRepoConformance(t *testing.T) {
+	runRepoConformance(t, func(t *testing.T) repoHarness {
+		client := newEmulatorClient(t)
+		repo := NewFirestoreRepo(client)
+		return repoHarness{
+			repo: repo,
+			put: func(t *testing.T, id int, sd sentenceDoc) {
+				if _, err := client.Collection("sentences").Doc(strconv.Itoa(id)).Set(context.Background(), sd); err != nil {
+					t.Fatalf("seed sentence %d: %v", id, err)
+				}
+				if err := InvalidateFirestoreCatalog(context.Background(), client); err != nil {
+					t.Fatal(err)
+				}
+			},
+			deleteSentence: func(t *testing.T, id int) {
+				if _, err := client.Collection("sentences").Doc(strconv.Itoa(id)).Delete(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				// Learners' copies are removed by DeleteSentence, which a
+				// direct delete must do too.
+				if err := repo.rewriteMistakeCopies(context.Background(), id, nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := InvalidateFirestoreCatalog(context.Background(), client); err != nil {
+					t.Fatal(err)
+				}
+			},
+			setNow: func(now time.Time) { repo.now = func() time.Time { return now } },
+		}
+	})
+}
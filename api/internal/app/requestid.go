@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds a caller-supplied ID, which is echoed back
+	// and written to logs.
+	maxRequestIDLength        = 128
+	requestIDCtxKey    ctxKey = "request_id"
+)
+
+// withRequestID gives every request an ID: the caller's X-Request-ID when it
+// sends a well-formed one, so a client or proxy can correlate its own logs,
+// or else a fresh random one. The ID is echoed in the X-Request-ID response
+// header and carried in the context for error responses.
+func withRequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next(w, r.WithContext(context.WithValue(r.Context(), requestIDCtxKey, id)))
+	}
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, the shape of
+// UUIDs, hex trace IDs and the like.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -3,19 +3,49 @@ package app
 import "net/http"
 
 // NewMux wires all HTTP routes with CORS and auth, matching the API's
-// public surface exactly.
-func NewMux(srv *Server, verifier TokenVerifier, allowedEmails []string, frontendURL string) *http.ServeMux {
+// public surface exactly; apiOperations documents each of them for
+// /api/openapi.json. Admin routes additionally require a caller in
+// adminEmails or with the admin custom claim (see requireAdmin); other
+// routes may check the same with isAdminRequest.
+func NewMux(srv *Server, verifier TokenVerifier, allowedEmails, adminEmails []string, frontendURL string) *http.ServeMux {
 	auth := func(h http.HandlerFunc) http.HandlerFunc {
-		return withCORS(frontendURL, requireAuth(verifier, allowedEmails, h))
+		return withCORS(frontendURL, requireAuth(verifier, allowedEmails, withAdminStatus(adminEmails, h)))
+	}
+	// ai additionally rate-limits an LLM-backed route per user (see
+	// Server.SetRateLimiter); routes sharing a scope share a budget. Only
+	// requests that reach the model are charged (see withRateLimit).
+	ai := func(scope string, h http.HandlerFunc) http.HandlerFunc {
+		return auth(withRateLimit(srv.limiter, scope, h))
+	}
+	admin := func(h http.HandlerFunc) http.HandlerFunc {
+		return auth(requireAdmin(adminEmails, h))
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/sentence/random", auth(srv.getRandomSentence))
-	mux.HandleFunc("/api/answer/check", auth(srv.checkAnswer))
-	mux.HandleFunc("/api/mistakes", auth(srv.getMistakes))
-	mux.HandleFunc("/api/mistakes/insight", auth(srv.getMistakesInsight))
-	mux.HandleFunc("/api/answer/explain", auth(srv.explainAnswer))
-	mux.HandleFunc("/api/sentence/report", auth(srv.reportSentence))
-	mux.HandleFunc("/api/liveness", livenessHandler)
+	// handle gives every route a request ID, an access log entry and
+	// metrics first, so even CORS and auth failures carry one and are
+	// counted.
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, withRequestID(withAccessLog(pattern, srv.metrics.instrument(pattern, h))))
+	}
+	handle("/api/sentence/random", auth(srv.getRandomSentence))
+	handle("/api/answer/check", ai("grade", srv.checkAnswer))
+	handle("/api/answer/override", auth(srv.overrideAnswer))
+	handle("/api/mistakes", auth(srv.getMistakes))
+	handle("/api/mistakes/insight", ai("insight", srv.getMistakesInsight))
+	handle("/api/answer/explain", ai("explain", srv.explainAnswer))
+	handle("/api/answer/explain/stream", ai("explain", srv.explainAnswerStream))
+	handle("/api/sentence/report", auth(srv.reportSentence))
+	handle("/api/session/create", auth(srv.createSession))
+	handle("/api/session/next", auth(srv.nextQuestion))
+	handle("/api/session/summary", auth(srv.getSessionSummary))
+	handle("/api/admin/sentences/reported", admin(srv.listReportedSentences))
+	handle("/api/admin/sentence/update", admin(srv.updateSentence))
+	handle("/api/admin/sentence/unreport", admin(srv.unreportSentence))
+	handle("/api/admin/sentence/delete", admin(srv.deleteSentence))
+	handle("/api/liveness", livenessHandler)
+	handle("/api/readiness", srv.getReadiness)
+	handle("/api/openapi.json", openAPIHandler)
+	handle("/metrics", srv.serveMetrics)
 	return mux
 }
@@ -0,0 +1,217 @@
+package app
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ReviewState is a learner's spaced-repetition state for one sentence,
+// stored alongside the correct/incorrect counters in sentence_stats.
+// The zero value is a sentence that has never been answered.
+type ReviewState struct {
+	// DueAt is when the sentence should next be reviewed. A seen sentence
+	// with a zero DueAt (stats written before scheduling existed) counts as
+	// overdue since forever, so it surfaces first.
+	DueAt        time.Time
+	Ease         float64
+	IntervalDays int
+	// Reps is the current streak of correct answers; a miss resets it.
+	Reps            int
+	FirstReviewedAt time.Time
+	LastReviewedAt  time.Time
+}
+
+// Scheduler computes a sentence's next review state from the previous one
+// and the outcome of the learner's latest attempt.
+type Scheduler interface {
+	Schedule(prev ReviewState, correct bool, now time.Time) ReviewState
+}
+
+// SM2Scheduler is the SuperMemo-2 algorithm adapted to Eagle's pass/fail
+// grading: a correct answer is graded as quality 4 and a miss as quality 2.
+// A missed sentence comes back after RelearnDelay rather than SM-2's one
+// day, so it is drilled again within the same session.
+type SM2Scheduler struct {
+	InitialEase  float64
+	MinEase      float64
+	RelearnDelay time.Duration
+}
+
+func NewSM2Scheduler() SM2Scheduler {
+	return SM2Scheduler{InitialEase: 2.5, MinEase: 1.3, RelearnDelay: 10 * time.Minute}
+}
+
+const (
+	sm2QualityCorrect = 4
+	sm2QualityMissed  = 2
+)
+
+func (s SM2Scheduler) Schedule(prev ReviewState, correct bool, now time.Time) ReviewState {
+	next := prev
+	if next.Ease == 0 {
+		next.Ease = s.InitialEase
+	}
+	q := float64(sm2QualityMissed)
+	if correct {
+		q = sm2QualityCorrect
+	}
+	next.Ease = math.Max(s.MinEase, next.Ease+0.1-(5-q)*(0.08+(5-q)*0.02))
+
+	if !correct {
+		next.Reps = 0
+		next.IntervalDays = 0
+		next.DueAt = now.Add(s.RelearnDelay)
+		return next
+	}
+	switch next.Reps {
+	case 0:
+		next.IntervalDays = 1
+	case 1:
+		next.IntervalDays = 6
+	default:
+		next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.Ease))
+	}
+	next.Reps++
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	return next
+}
+
+// ReviewPolicy is how RandomCandidate chooses among a learner's eligible
+// sentences: the Scheduler that RecordAnswer advances, plus per-day caps on
+// how many due reviews and how many never-seen sentences are prioritized.
+type ReviewPolicy struct {
+	Scheduler Scheduler
+	// NewPerDay and ReviewsPerDay count distinct sentences per calendar day
+	// in Location. A limit <= 0 means no limit.
+	NewPerDay     int
+	ReviewsPerDay int
+	Location      *time.Location
+	// MasteryThreshold is the net score (correct_count - incorrect_count) at
+	// which a sentence is mastered and leaves the mistakes to drill. <= 0
+	// means defaultMasteryThreshold.
+	MasteryThreshold int
+}
+
+func DefaultReviewPolicy() ReviewPolicy {
+	return ReviewPolicy{
+		Scheduler:        NewSM2Scheduler(),
+		NewPerDay:        20,
+		ReviewsPerDay:    200,
+		Location:         time.UTC,
+		MasteryThreshold: defaultMasteryThreshold,
+	}
+}
+
+// mastered reports whether a learner's counts for a sentence reach the
+// mastery threshold.
+func (p ReviewPolicy) mastered(correct, incorrect int) bool {
+	return correct-incorrect >= p.masteryThreshold()
+}
+
+func (p ReviewPolicy) masteryThreshold() int {
+	if p.MasteryThreshold <= 0 {
+		return defaultMasteryThreshold
+	}
+	return p.MasteryThreshold
+}
+
+// advance records one attempt: it runs the scheduler and stamps the
+// first/last review times the daily limits are counted from.
+func (p ReviewPolicy) advance(prev ReviewState, correct bool, now time.Time) ReviewState {
+	next := p.Scheduler.Schedule(prev, correct, now)
+	if next.FirstReviewedAt.IsZero() {
+		next.FirstReviewedAt = now
+	}
+	next.LastReviewedAt = now
+	return next
+}
+
+func (p ReviewPolicy) dayStart(now time.Time) time.Time {
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := now.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// dailyUsage counts, from every sentence a learner has answered, how many
+// were first seen today (new) and how many earlier-seen ones were reviewed
+// today.
+func (p ReviewPolicy) dailyUsage(states []ReviewState, now time.Time) (newToday, reviewsToday int) {
+	start := p.dayStart(now)
+	for _, st := range states {
+		if st.LastReviewedAt.Before(start) {
+			continue
+		}
+		if !st.FirstReviewedAt.Before(start) {
+			newToday++
+		} else {
+			reviewsToday++
+		}
+	}
+	return newToday, reviewsToday
+}
+
+// reviewCandidate is an eligible (unreported, filter-matching) sentence
+// together with the learner's state for it.
+type reviewCandidate struct {
+	sentence *Sentence
+	state    ReviewState
+	seen     bool
+}
+
+func underLimit(used, limit int) bool {
+	return limit <= 0 || used < limit
+}
+
+// pick chooses the next sentence to serve, in priority order:
+//
+//  1. the most overdue due review, while today's review limit allows;
+//  2. a random never-seen sentence, while today's new-card limit allows;
+//  3. otherwise, the seen sentence due soonest (including over-limit due
+//     reviews), so practice can continue ahead of schedule;
+//  4. and finally any remaining never-seen sentence.
+//
+// Limits only change what is prioritized; pick returns nil only when cands
+// is empty, so the learner never hits a dead end while eligible sentences
+// remain.
+func (p ReviewPolicy) pick(cands []reviewCandidate, newToday, reviewsToday int, now time.Time) *Sentence {
+	var due, ahead, fresh []reviewCandidate
+	for _, c := range cands {
+		switch {
+		case !c.seen:
+			fresh = append(fresh, c)
+		case !c.state.DueAt.After(now):
+			due = append(due, c)
+		default:
+			ahead = append(ahead, c)
+		}
+	}
+	byDue := func(cs []reviewCandidate) {
+		sort.Slice(cs, func(i, j int) bool {
+			if !cs[i].state.DueAt.Equal(cs[j].state.DueAt) {
+				return cs[i].state.DueAt.Before(cs[j].state.DueAt)
+			}
+			return cs[i].sentence.ID < cs[j].sentence.ID
+		})
+	}
+
+	if len(due) > 0 && underLimit(reviewsToday, p.ReviewsPerDay) {
+		byDue(due)
+		return due[0].sentence
+	}
+	if len(fresh) > 0 && underLimit(newToday, p.NewPerDay) {
+		return fresh[rand.Intn(len(fresh))].sentence
+	}
+	if rest := append(due, ahead...); len(rest) > 0 {
+		byDue(rest)
+		return rest[0].sentence
+	}
+	if len(fresh) > 0 {
+		return fresh[rand.Intn(len(fresh))].sentence
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSM2SchedulerIntervals(t *testing.T) {
+	s := NewSM2Scheduler()
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	var st ReviewState
+	for i, want := range []int{1, 6, 15, 38} {
+		st = s.Schedule(st, true, now)
+		if st.IntervalDays != want {
+			t.Fatalf("review %d: expected interval %d, got %d", i+1, want, st.IntervalDays)
+		}
+		if st.Ease != 2.5 {
+			t.Fatalf("review %d: a correct answer should keep ease at 2.5, got %v", i+1, st.Ease)
+		}
+		if !st.DueAt.Equal(now.AddDate(0, 0, want)) {
+			t.Fatalf("review %d: expected due %v, got %v", i+1, now.AddDate(0, 0, want), st.DueAt)
+		}
+	}
+	if st.Reps != 4 {
+		t.Fatalf("expected 4 reps, got %d", st.Reps)
+	}
+}
+
+func TestSM2SchedulerMissResetsAndLowersEase(t *testing.T) {
+	s := NewSM2Scheduler()
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	st := ReviewState{Ease: 2.5, IntervalDays: 15, Reps: 3}
+
+	st = s.Schedule(st, false, now)
+	if st.Reps != 0 || st.IntervalDays != 0 {
+		t.Fatalf("a miss should reset reps and interval, got %+v", st)
+	}
+	if !st.DueAt.Equal(now.Add(s.RelearnDelay)) {
+		t.Fatalf("expected relearn at %v, got %v", now.Add(s.RelearnDelay), st.DueAt)
+	}
+	if st.Ease >= 2.5 {
+		t.Fatalf("a miss should lower ease, got %v", st.Ease)
+	}
+
+	for i := 0; i < 10; i++ {
+		st = s.Schedule(st, false, now)
+	}
+	if st.Ease != s.MinEase {
+		t.Fatalf("ease should floor at %v, got %v", s.MinEase, st.Ease)
+	}
+}
+
+func TestReviewPolicyDailyUsage(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	p := ReviewPolicy{Location: tokyo}
+	// 2026-01-02 08:00 JST; the JST day began at 2026-01-01 15:00 UTC.
+	now := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+	dayStart := time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	states := []ReviewState{
+		{FirstReviewedAt: dayStart, LastReviewedAt: dayStart},                                     // new today
+		{FirstReviewedAt: dayStart.Add(-time.Hour), LastReviewedAt: dayStart.Add(time.Hour)},      // reviewed today
+		{LastReviewedAt: dayStart.Add(time.Hour)},                                                 // legacy stats, reviewed today
+		{FirstReviewedAt: dayStart.Add(-2 * time.Hour), LastReviewedAt: dayStart.Add(-time.Hour)}, // yesterday
+	}
+	newToday, reviewsToday := p.dailyUsage(states, now)
+	if newToday != 1 || reviewsToday != 2 {
+		t.Fatalf("expected 1 new and 2 reviews today, got %d and %d", newToday, reviewsToday)
+	}
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SeedRow is one line of the NDJSON sentence export read by cmd/seed and by
+// the in-memory repository, so both load exactly the same fixture files.
+type SeedRow struct {
+	ID         int         `json:"id"`
+	Japanese   string      `json:"japanese"`
+	English    string      `json:"english"`
+	Page       json.Number `json:"page"`
+	Level      int         `json:"level"`
+	IsReported int         `json:"is_reported"`
+	// AcceptedAnswers optionally lists alternative translations graded as
+	// correct alongside English.
+	AcceptedAnswers []string `json:"accepted_answers"`
+}
+
+// Validate checks that level falls in the supported 1-5 difficulty range
+// and that no accepted answer is blank.
+func (rw SeedRow) Validate() error {
+	if rw.Level < 1 || rw.Level > 5 {
+		return fmt.Errorf("sentence %d: level must be 1-5, got %d", rw.ID, rw.Level)
+	}
+	for i, a := range rw.AcceptedAnswers {
+		if strings.TrimSpace(a) == "" {
+			return fmt.Errorf("sentence %d: accepted_answers[%d] is blank", rw.ID, i)
+		}
+	}
+	return nil
+}
+
+// ScanSeedRows decodes NDJSON rows from r, skipping blank lines, and calls fn
+// for each one in file order. It stops at the first parse error or the first
+// error returned by fn. Rows are not validated here; callers decide whether
+// an invalid row is fatal.
+func ScanSeedRows(r io.Reader, fn func(SeedRow) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rw SeedRow
+		if err := json.Unmarshal(line, &rw); err != nil {
+			return fmt.Errorf("parse line: %w", err)
+		}
+		if err := fn(rw); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+	return nil
+}
@@ -2,7 +2,12 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type Sentence struct {
@@ -21,67 +26,367 @@ type AnswerHistory struct {
 	ID              int64  `json:"id"`
 	IncorrectAnswer string `json:"incorrect_answer"`
 	CreatedAt       string `json:"created_at"`
+	// GradedBy and GraderReason record how the verdict was reached; see
+	// AnswerRecord. Overridden is set once the learner has changed it.
+	GradedBy     string `json:"graded_by,omitempty"`
+	GraderReason string `json:"grader_reason,omitempty"`
+	Overridden   bool   `json:"overridden,omitempty"`
+}
+
+// Values of AnswerRecord.GradedBy. Entries written before graders existed
+// have no grading method and count as GradedExact.
+const (
+	GradedExact = "exact"
+	GradedAI    = "ai"
+)
+
+// AnswerRecord is one graded attempt, as RecordAnswer stores it.
+type AnswerRecord struct {
+	Correct bool
+	// Answer is the learner's text, stored as the history entry's
+	// incorrect_answer. checkAnswer leaves it empty for an exact match, but
+	// keeps it whenever a Grader judged the answer, so the verdict can be
+	// reviewed and overridden later.
+	Answer       string
+	GradedBy     string
+	GraderReason string
+}
+
+// AnswerOverride is a learner's correction of one history entry's verdict,
+// as OverrideAnswer applies it.
+type AnswerOverride struct {
+	Correct bool
+	// Promote also adds the entry's answer to the sentence's accepted
+	// answers, so the same translation is graded correct from then on. It
+	// only applies when Correct is set.
+	Promote bool
 }
 
 type MistakeSentence struct {
-	SentenceID    int             `json:"sentence_id"`
-	Japanese      string          `json:"japanese"`
-	CorrectAnswer string          `json:"correct_answer"`
-	WrongAnswers  []AnswerHistory `json:"wrong_answers"`
+	SentenceID     int             `json:"sentence_id"`
+	Japanese       string          `json:"japanese"`
+	CorrectAnswer  string          `json:"correct_answer"`
+	Level          int             `json:"level"`
+	CorrectCount   int             `json:"correct_count"`
+	IncorrectCount int             `json:"incorrect_count"`
+	WrongAnswers   []AnswerHistory `json:"wrong_answers"`
 }
 
 type ListMistakesResponse struct {
 	Mistakes []MistakeSentence `json:"mistakes"`
+	// NextCursor fetches the next page when passed back as cursor; it is
+	// omitted on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type MistakesInsightResponse struct {
 	Insight string `json:"insight"`
+	// Cached reports the insight was served from the generation cache
+	// rather than generated for this request.
+	Cached bool `json:"cached"`
 }
 
 type CheckAnswerRequest struct {
 	SentenceID int    `json:"sentence_id"`
 	UserAnswer string `json:"user_answer"`
+	// SessionID also records the answer against a practice session, whose
+	// current question the sentence must be.
+	SessionID string `json:"session_id,omitempty"`
 }
 
 type CheckAnswerResponse struct {
-	IsCorrect     bool            `json:"is_correct"`
-	CorrectAnswer string          `json:"correct_answer"`
-	Histories     []AnswerHistory `json:"histories"`
+	IsCorrect     bool   `json:"is_correct"`
+	CorrectAnswer string `json:"correct_answer"`
+	// HistoryID identifies the recorded attempt, for /api/answer/override.
+	// It is 0 if the attempt could not be recorded.
+	HistoryID    int64  `json:"history_id"`
+	GradedBy     string `json:"graded_by"`
+	GraderReason string `json:"grader_reason,omitempty"`
+	// MatchedAnswer is the accepted answer the learner's translation
+	// matched — CorrectAnswer or one of the alternatives — or "" when it
+	// matched none, whether it was then rejected or accepted by a Grader.
+	MatchedAnswer   string          `json:"matched_answer"`
+	AcceptedAnswers []string        `json:"accepted_answers"`
+	Histories       []AnswerHistory `json:"histories"`
+}
+
+type OverrideAnswerRequest struct {
+	SentenceID int   `json:"sentence_id"`
+	HistoryID  int64 `json:"history_id"`
+	IsCorrect  bool  `json:"is_correct"`
+	// Promote adds the answer to the sentence's accepted alternatives. It
+	// requires IsCorrect, and only an admin may set it.
+	Promote bool `json:"promote"`
+	// SessionID also corrects the verdict in the practice session the
+	// answer was given in.
+	SessionID string `json:"session_id,omitempty"`
+}
+
+type CreateSessionRequest struct {
+	// Size is the number of questions; 0 means defaultSessionSize.
+	Size int `json:"size"`
+	// Levels restricts questions to these levels; empty means any level.
+	Levels []int           `json:"levels"`
+	Source CandidateSource `json:"source"`
+}
+
+// SessionRequest names the practice session to serve a question from.
+type SessionRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// NextQuestionResponse is the body of /api/session/next.
+type NextQuestionResponse struct {
+	SessionID string `json:"session_id"`
+	// Position is the question's place in the session, from 1 to Size.
+	Position int `json:"position"`
+	Size     int `json:"size"`
+	// Done is set, and Sentence null, once the session is over.
+	Done     bool             `json:"done"`
+	Sentence *SessionSentence `json:"sentence"`
+}
+
+// SessionSentence is a practice session's question.
+type SessionSentence struct {
+	ID       int    `json:"id"`
+	Japanese string `json:"japanese"`
+	English  string `json:"english"`
+	Page     string `json:"page"`
+	Level    int    `json:"level"`
+}
+
+// SessionSummary is how a practice session went so far, and once Completed,
+// how it went.
+type SessionSummary struct {
+	SessionID string          `json:"session_id"`
+	Size      int             `json:"size"`
+	Levels    []int           `json:"levels"`
+	Source    CandidateSource `json:"source"`
+	Completed bool            `json:"completed"`
+	Answered  int             `json:"answered"`
+	// CorrectCount and IncorrectCount follow overridden verdicts.
+	CorrectCount   int `json:"correct_count"`
+	IncorrectCount int `json:"incorrect_count"`
+	// Accuracy is CorrectCount / Answered, 0 before the first answer.
+	Accuracy float64 `json:"accuracy"`
+	// TimeSpentMS adds up the time from serving each question to its
+	// answer.
+	TimeSpentMS int64         `json:"time_spent_ms"`
+	Missed      []SessionMiss `json:"missed"`
+	CreatedAt   string        `json:"created_at"`
+	CompletedAt string        `json:"completed_at,omitempty"`
+}
+
+// SessionMiss is a question answered incorrectly in a practice session.
+type SessionMiss struct {
+	SentenceID    int    `json:"sentence_id"`
+	Japanese      string `json:"japanese"`
+	CorrectAnswer string `json:"correct_answer"`
+	Answer        string `json:"answer"`
+	// HistoryID is the answer's history entry, for /api/answer/override.
+	HistoryID int64 `json:"history_id"`
 }
 
 type ReportSentenceRequest struct {
 	SentenceID int `json:"sentence_id"`
+	// Reason is one of the Report* categories; empty means ReportOther.
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+// Report reason categories.
+const (
+	ReportWrongTranslation  = "wrong_translation"
+	ReportUnnaturalJapanese = "unnatural_japanese"
+	ReportTypo              = "typo"
+	ReportOther             = "other"
+)
+
+// validReportReason reports whether reason is a known category.
+func validReportReason(reason string) bool {
+	switch reason {
+	case ReportWrongTranslation, ReportUnnaturalJapanese, ReportTypo, ReportOther:
+		return true
+	}
+	return false
+}
+
+// ReportRecord is one learner's report, as Report stores it.
+type ReportRecord struct {
+	Reason  string
+	Comment string
+}
+
+// SentenceReport is a stored report, as the admin API lists it.
+type SentenceReport struct {
+	UID       string `json:"uid"`
+	Reason    string `json:"reason"`
+	Comment   string `json:"comment,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ReportPolicy decides who stops seeing a reported sentence. Its reporters
+// never see it again; everyone else keeps practicing it until HideAfter
+// distinct learners have reported it. The zero value hides a sentence only
+// from its reporters.
+type ReportPolicy struct {
+	HideAfter int
+}
+
+// hides reports whether a sentence reported by this many distinct learners
+// is hidden from everyone.
+func (p ReportPolicy) hides(reporters int) bool {
+	return p.HideAfter > 0 && reporters >= p.HideAfter
+}
+
+// AdminSentence is a sentence as the admin API lists it, with its reports.
+type AdminSentence struct {
+	ID         int    `json:"id"`
+	Japanese   string `json:"japanese"`
+	English    string `json:"english"`
+	Page       string `json:"page"`
+	Level      int    `json:"level"`
+	IsReported bool   `json:"is_reported"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+	// Reports are oldest first. A sentence seeded as reported has none.
+	Reports []SentenceReport `json:"reports"`
+}
+
+// SentenceEdit is the content an admin may change on a sentence.
+type SentenceEdit struct {
+	Japanese string
+	English  string
+	Page     string
+	Level    int
+}
+
+// jsonNumber matches a JSON number, the only page cmd/seed's decoding of
+// SeedRow.Page accepts.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// Validate applies the checks cmd/seed applies to an imported row, so an
+// edit can never store a sentence the seeder would have rejected, and also
+// rejects blanking either text.
+func (e SentenceEdit) Validate(id int) error {
+	if strings.TrimSpace(e.Japanese) == "" || strings.TrimSpace(e.English) == "" {
+		return fmt.Errorf("sentence %d: japanese and english are required", id)
+	}
+	if e.Page != "" && !jsonNumber.MatchString(e.Page) {
+		return fmt.Errorf("sentence %d: page must be a number, got %q", id, e.Page)
+	}
+	return SeedRow{ID: id, Japanese: e.Japanese, English: e.English, Page: json.Number(e.Page), Level: e.Level}.Validate()
+}
+
+type UpdateSentenceRequest struct {
+	SentenceID int    `json:"sentence_id"`
+	Japanese   string `json:"japanese"`
+	English    string `json:"english"`
+	Page       string `json:"page"`
+	Level      int    `json:"level"`
+}
+
+// AdminSentenceRequest names the sentence an admin un-reports or deletes.
+type AdminSentenceRequest struct {
+	SentenceID int `json:"sentence_id"`
 }
 
 type ExplainRequest struct {
 	SentenceID int    `json:"sentence_id"`
 	UserAnswer string `json:"user_answer"`
 	Language   string `json:"language"`
+	// ForceRefresh skips the generation cache and replaces its entry.
+	ForceRefresh bool `json:"force_refresh"`
 }
 
 type ExplainResponse struct {
 	Explanation string `json:"explanation"`
+	// Cached reports the explanation was served from the generation cache
+	// rather than generated for this request.
+	Cached bool `json:"cached"`
+}
+
+// ExplainChunk is the data of a "chunk" event on /api/answer/explain/stream.
+type ExplainChunk struct {
+	Text string `json:"text"`
+}
+
+// ExplainStreamError is the data of an "error" event on
+// /api/answer/explain/stream. Code and RequestID mean what they do in a
+// Problem, which the status line has already been sent too early to carry.
+type ExplainStreamError struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ReadinessResponse is the body of /api/readiness, sent with 200 when every
+// required dependency is up and 503 when one is down.
+type ReadinessResponse struct {
+	// Status is "ready" or "unavailable".
+	Status string             `json:"status"`
+	Checks []DependencyStatus `json:"checks"`
+	// CheckedAt is when the checks ran; results are reused for a while.
+	CheckedAt string `json:"checked_at"`
 }
 
+// DependencyStatus is one dependency's result in a ReadinessResponse. Why a
+// check failed is logged, not returned, since the endpoint is public.
+type DependencyStatus struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	// Status is "up" or "down".
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// defaultMasteryThreshold is the net score (correct_count -
+// incorrect_count) at which a learner is considered to have mastered a
+// sentence unless ReviewPolicy.MasteryThreshold says otherwise; it then
+// drops out of SourceMistakes and the unmastered mistakes filter.
+const defaultMasteryThreshold = 2
+
 // ErrNotFound is returned when a sentence document does not exist.
 var ErrNotFound = errors.New("sentence not found")
 
 // ErrNoCandidate is returned when no sentence passes the random filter.
 var ErrNoCandidate = errors.New("no candidate sentence")
 
+// ErrHistoryNotFound is returned when an answer history entry does not exist.
+var ErrHistoryNotFound = errors.New("answer history not found")
+
+// ErrInvalidCursor is returned by ListMistakes for a cursor it did not issue
+// for the query's sort.
+var ErrInvalidCursor = errors.New("invalid mistakes cursor")
+
+// ErrSessionNotFound is returned when a learner has no practice session with
+// the given ID.
+var ErrSessionNotFound = errors.New("practice session not found")
+
+// ErrNotOverridable is returned by OverrideAnswer for an entry whose verdict
+// the learner may not change.
+var ErrNotOverridable = errors.New("answer verdict cannot be overridden")
+
 // SentenceRepository is the data-access seam behind the HTTP handlers.
 type SentenceRepository interface {
-	// RandomCandidate returns a random non-mastered, non-reported sentence.
-	// levels restricts candidates to sentences whose Level is in the set;
-	// an empty levels means "any level" (no filtering), including sentences
-	// with no level set.
-	RandomCandidate(ctx context.Context, uid string, levels []int) (*Sentence, error)
-	CorrectAnswer(ctx context.Context, id int) (string, error)
+	// RandomCandidate returns the next sentence to practice, chosen by the
+	// repository's ReviewPolicy (see ReviewPolicy.pick): most overdue review
+	// first, then a random new one. q narrows the candidates by level and
+	// source and excludes sentences (see CandidateQuery.admits); the zero
+	// CandidateQuery keeps them all. Reported sentences, and sentences uid
+	// has reported, are never candidates.
+	RandomCandidate(ctx context.Context, uid string, q CandidateQuery) (*Sentence, error)
+	// AcceptedAnswers returns every translation graded as correct for the
+	// sentence: its reference English first, then any alternatives. It also
+	// returns the sentence's level, which grading is reported by.
+	AcceptedAnswers(ctx context.Context, id int) (answers []string, level int, err error)
 	GetSentence(ctx context.Context, id int) (japanese, english string, err error)
 	ListIncorrectHistories(ctx context.Context, uid string, id int) ([]AnswerHistory, error)
-	// ListMistakes returns every sentence the user has ever answered
-	// incorrectly, most recently missed sentence first.
-	ListMistakes(ctx context.Context, uid string) ([]MistakeSentence, error)
+	// ListMistakes returns a page of the sentences the user has answered
+	// incorrectly, each with its complete wrong-answer history, as q
+	// filters and orders them, and the cursor of the next page, empty on
+	// the last one.
+	ListMistakes(ctx context.Context, uid string, q MistakeQuery) (mistakes []MistakeSentence, next string, err error)
 	// ListMistakesForInsight returns the same shape as ListMistakes but caps
 	// each sentence's wrong-answer history to maxWrongAnswersPerSentence at
 	// the query level, bounding Firestore read cost for the weakness-insight
@@ -90,6 +395,55 @@ type SentenceRepository interface {
 	// which have always shown a learner's complete history and are unrelated
 	// to Gemini's cost bound.
 	ListMistakesForInsight(ctx context.Context, uid string) ([]MistakeSentence, error)
-	RecordAnswer(ctx context.Context, uid string, id int, correct bool, answer string) error
-	Report(ctx context.Context, id int) error
+	// RecordAnswer bumps the sentence's counters, advances its review
+	// schedule and appends a history entry, atomically. It returns the new
+	// entry's AnswerHistory.ID.
+	RecordAnswer(ctx context.Context, uid string, id int, rec AnswerRecord) (int64, error)
+	// OverrideAnswer sets the verdict of one history entry and moves one
+	// count between correct_count and incorrect_count to match, atomically
+	// with promoting the entry's answer when ov.Promote is set; setting the
+	// verdict an entry already has leaves the counts alone. Any rejection
+	// can be flipped to correct, but only a Grader's acceptance (GradedAI)
+	// can be flipped to incorrect — an exact match returns
+	// ErrNotOverridable. A missing entry returns ErrHistoryNotFound.
+	OverrideAnswer(ctx context.Context, uid string, id int, historyID int64, ov AnswerOverride) error
+	// Report records uid's report of a sentence, replacing any earlier one
+	// by the same learner, and hides the sentence as the repository's
+	// ReportPolicy says, atomically. A missing sentence returns ErrNotFound.
+	Report(ctx context.Context, uid string, id int, rec ReportRecord) error
+	// ListReported returns every sentence that is reported or has reports,
+	// by ID, for admin triage.
+	ListReported(ctx context.Context) ([]AdminSentence, error)
+	// UpdateSentence replaces a sentence's content, which the caller has
+	// validated. A missing sentence returns ErrNotFound.
+	UpdateSentence(ctx context.Context, id int, edit SentenceEdit) error
+	// Unreport clears a sentence's reported flag and deletes its reports,
+	// returning it to practice for everyone, reporters included. A missing
+	// sentence returns ErrNotFound.
+	Unreport(ctx context.Context, id int) error
+	// DeleteSentence removes a sentence. Learners' stats and histories for
+	// it are kept but no longer listed, as for any sentence that has gone
+	// missing. A missing sentence returns ErrNotFound.
+	DeleteSentence(ctx context.Context, id int) error
+	// CachedGeneration returns LLM output stored under key by
+	// CacheGeneration, and false once it has expired or was never stored.
+	CachedGeneration(ctx context.Context, key string) (string, bool, error)
+	// CacheGeneration stores text under key for ttl, replacing any previous
+	// entry.
+	CacheGeneration(ctx context.Context, key, text string, ttl time.Duration) error
+	// UpdateRateState makes the repository a RateCounter, sharing rate
+	// limits across server instances.
+	UpdateRateState(ctx context.Context, key string, fn func(RateState) RateState) error
+	// CreateSession stores a new practice session of uid's.
+	CreateSession(ctx context.Context, uid string, s PracticeSession) error
+	// GetSession returns one of uid's practice sessions. A missing session
+	// returns ErrSessionNotFound.
+	GetSession(ctx context.Context, uid, id string) (PracticeSession, error)
+	// UpdateSession applies fn to one of uid's practice sessions and saves
+	// the result, atomically; when fn fails, its error is returned and
+	// nothing is saved. A missing session returns ErrSessionNotFound.
+	UpdateSession(ctx context.Context, uid, id string, fn func(*PracticeSession) error) error
+	// Ping makes the cheapest round trip the backing store allows, for
+	// /api/readiness.
+	Ping(ctx context.Context) error
 }
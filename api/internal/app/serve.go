@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ServeConfig configures the HTTP server Serve runs.
+type ServeConfig struct {
+	// Addr is the address to listen on, e.g. ":8080".
+	Addr string
+	// ReadHeaderTimeout and ReadTimeout bound how long a client may take to
+	// send its request headers and its whole request.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout bounds a whole response. It must leave room for the
+	// slowest LLM call, whose explanation may stream for its full timeout.
+	WriteTimeout time.Duration
+	// IdleTimeout closes keep-alive connections left idle this long.
+	IdleTimeout time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once the server is asked to stop.
+	ShutdownTimeout time.Duration
+}
+
+// DefaultServeConfig suits Cloud Run, which allows 10 seconds between
+// SIGTERM and SIGKILL: requests get 8 of them to finish.
+func DefaultServeConfig(addr string) ServeConfig {
+	return ServeConfig{
+		Addr:              addr,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   8 * time.Second,
+	}
+}
+
+// Serve runs handler until ctx is done or the process receives SIGTERM or
+// SIGINT, then stops accepting connections and waits up to
+// cfg.ShutdownTimeout for in-flight requests — an answer being recorded,
+// an explanation streaming — to complete. It returns nil once they have;
+// the caller then closes the clients the handler used.
+func Serve(ctx context.Context, cfg ServeConfig, handler http.Handler) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, cfg, handler, ln)
+}
+
+func serve(ctx context.Context, cfg ServeConfig, handler http.Handler, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("drain in-flight requests: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
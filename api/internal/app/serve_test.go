@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// serveSlow serves a handler that signals when a request arrives and then
+// takes hold to respond, and returns the URL to request and serve's result.
+func serveSlow(t *testing.T, ctx context.Context, cfg ServeConfig, hold time.Duration) (string, <-chan struct{}, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(hold)
+		w.Write([]byte("done"))
+	})
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, cfg, handler, ln) }()
+	return "http://" + ln.Addr().String(), started, done
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	url, started, done := serveSlow(t, ctx, DefaultServeConfig(""), 200*time.Millisecond)
+
+	resc := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err != nil {
+			t.Error(err)
+		}
+		resc <- res
+	}()
+	<-started
+	cancel()
+
+	if res := <-resc; res == nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the in-flight request to complete, got %v", res)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestServeGivesUpAfterShutdownTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := DefaultServeConfig("")
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	url, started, done := serveSlow(t, ctx, cfg, time.Second)
+
+	go http.Get(url)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error for the request still in flight")
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("serve did not return after its shutdown timeout")
+	}
+}
@@ -0,0 +1,348 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Practice sessions turn the open-ended stream of questions into a run of a
+// fixed size: /api/session/next serves each question once, /api/answer/check
+// records answers against the session when given its ID, and
+// /api/session/summary reports how it went. Sessions are stored by the
+// repository, so a learner can reload the page and carry on.
+
+// Sizes of a practice session.
+const (
+	defaultSessionSize = 20
+	maxSessionSize     = 100
+)
+
+// PracticeSession is a learner's practice session, as the repository stores
+// it.
+type PracticeSession struct {
+	ID     string          `firestore:"id"`
+	Size   int             `firestore:"size"`
+	Levels []int           `firestore:"levels"`
+	Source CandidateSource `firestore:"source"`
+	// Questions are the ones served so far, in order. Only the last may
+	// still be unanswered.
+	Questions []SessionQuestion `firestore:"questions"`
+	CreatedAt time.Time         `firestore:"created_at"`
+	// CompletedAt is set once Size questions are answered, or earlier when
+	// no candidate is left to serve.
+	CompletedAt time.Time `firestore:"completed_at"`
+}
+
+// SessionQuestion is one question a session has served. Its sentence is
+// copied in as served, so the summary shows what the learner was asked.
+type SessionQuestion struct {
+	SentenceID int       `firestore:"sentence_id" json:"sentence_id"`
+	Japanese   string    `firestore:"japanese" json:"japanese"`
+	English    string    `firestore:"english" json:"english"`
+	Page       string    `firestore:"page" json:"page"`
+	Level      int       `firestore:"level" json:"level"`
+	ServedAt   time.Time `firestore:"served_at" json:"served_at"`
+	// AnsweredAt is zero until the question is answered.
+	AnsweredAt time.Time `firestore:"answered_at" json:"answered_at"`
+	Correct    bool      `firestore:"correct" json:"correct"`
+	Answer     string    `firestore:"answer" json:"answer"`
+	// HistoryID is the answer's history entry, whose verdict
+	// /api/answer/override may change.
+	HistoryID int64 `firestore:"history_id" json:"history_id"`
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validSessionID accepts the IDs newSessionID makes, so no other string
+// reaches the repository, where it could name something besides a session.
+func validSessionID(id string) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == 16
+}
+
+// pending returns the served question awaiting an answer, or nil.
+func (s *PracticeSession) pending() *SessionQuestion {
+	if n := len(s.Questions); n > 0 && s.Questions[n-1].AnsweredAt.IsZero() {
+		return &s.Questions[n-1]
+	}
+	return nil
+}
+
+func (s *PracticeSession) completed() bool {
+	return !s.CompletedAt.IsZero()
+}
+
+func (s *PracticeSession) served() []int {
+	ids := make([]int, len(s.Questions))
+	for i, q := range s.Questions {
+		ids[i] = q.SentenceID
+	}
+	return ids
+}
+
+// serve adds sentence as the next question, or completes the session when
+// sentence is nil. It leaves a session that is completed or has a question
+// pending, which a concurrent request has served, as it is.
+func (s *PracticeSession) serve(sentence *Sentence, now time.Time) {
+	if s.completed() || s.pending() != nil {
+		return
+	}
+	if sentence == nil || len(s.Questions) >= s.Size {
+		s.CompletedAt = now
+		return
+	}
+	s.Questions = append(s.Questions, SessionQuestion{
+		SentenceID: sentence.ID,
+		Japanese:   sentence.Japanese,
+		English:    sentence.English,
+		Page:       sentence.Page,
+		Level:      sentence.Level,
+		ServedAt:   now,
+	})
+}
+
+// errNotPending is returned when an answer is for a sentence that is not the
+// session's pending question.
+var errNotPending = errors.New("sentence is not the session's pending question")
+
+// answer records the verdict on the pending question, completing the
+// session with its last one.
+func (s *PracticeSession) answer(sentenceID int, rec AnswerRecord, historyID int64, now time.Time) error {
+	q := s.pending()
+	if q == nil || q.SentenceID != sentenceID {
+		return errNotPending
+	}
+	q.AnsweredAt, q.Correct, q.Answer, q.HistoryID = now, rec.Correct, rec.Answer, historyID
+	if len(s.Questions) >= s.Size {
+		s.CompletedAt = now
+	}
+	return nil
+}
+
+// override applies an overridden verdict to the question answered by
+// history entry historyID, if the session has one.
+func (s *PracticeSession) override(historyID int64, correct bool) {
+	for i := range s.Questions {
+		if q := &s.Questions[i]; q.HistoryID == historyID && !q.AnsweredAt.IsZero() {
+			q.Correct = correct
+		}
+	}
+}
+
+func (s *PracticeSession) summary() SessionSummary {
+	sum := SessionSummary{
+		SessionID: s.ID,
+		Size:      s.Size,
+		Levels:    s.Levels,
+		Source:    s.Source,
+		Completed: s.completed(),
+		CreatedAt: s.CreatedAt.UTC().Format(time.RFC3339),
+		Missed:    []SessionMiss{},
+	}
+	if sum.Levels == nil {
+		sum.Levels = []int{}
+	}
+	if sum.Completed {
+		sum.CompletedAt = s.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	var spent time.Duration
+	for _, q := range s.Questions {
+		if q.AnsweredAt.IsZero() {
+			continue
+		}
+		sum.Answered++
+		spent += q.AnsweredAt.Sub(q.ServedAt)
+		if q.Correct {
+			sum.CorrectCount++
+			continue
+		}
+		sum.IncorrectCount++
+		sum.Missed = append(sum.Missed, SessionMiss{
+			SentenceID:    q.SentenceID,
+			Japanese:      q.Japanese,
+			CorrectAnswer: q.English,
+			Answer:        q.Answer,
+			HistoryID:     q.HistoryID,
+		})
+	}
+	if sum.Answered > 0 {
+		sum.Accuracy = float64(sum.CorrectCount) / float64(sum.Answered)
+	}
+	sum.TimeSpentMS = spent.Milliseconds()
+	return sum
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	var req CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+	if req.Size == 0 {
+		req.Size = defaultSessionSize
+	}
+	if req.Size < 1 || req.Size > maxSessionSize {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidSize, "Invalid size")
+		return
+	}
+	levels, ok := normalizeLevels(req.Levels)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidLevels, "Invalid levels")
+		return
+	}
+	if req.Source == "" {
+		req.Source = SourceAll
+	}
+	if !req.Source.valid() {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidSource, "Invalid source")
+		return
+	}
+	uid, _ := uidFromContext(r.Context())
+	sess := PracticeSession{
+		ID:        newSessionID(),
+		Size:      req.Size,
+		Levels:    levels,
+		Source:    req.Source,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.repo.CreateSession(r.Context(), uid, sess); err != nil {
+		slog.ErrorContext(r.Context(), "create session error", "err", err)
+		writeInternalError(w, r)
+		return
+	}
+	writeJSON(w, r, sess.summary())
+}
+
+// nextQuestion serves the session's pending question, so a reload picks up
+// where the learner was, or else picks and serves a new one.
+func (s *Server) nextQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	var req SessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+	uid, _ := uidFromContext(r.Context())
+	sess, ok := s.loadSession(w, r, uid, req.SessionID)
+	if !ok {
+		return
+	}
+	if sess.pending() == nil && !sess.completed() {
+		q := CandidateQuery{Levels: sess.Levels, Source: sess.Source, Exclude: sess.served()}
+		sentence, err := s.repo.RandomCandidate(r.Context(), uid, q)
+		if err != nil && !errors.Is(err, ErrNoCandidate) {
+			slog.ErrorContext(r.Context(), "random candidate error", "err", err)
+			writeInternalError(w, r)
+			return
+		}
+		err = s.repo.UpdateSession(r.Context(), uid, req.SessionID, func(stored *PracticeSession) error {
+			stored.serve(sentence, s.now().UTC())
+			sess = *stored
+			return nil
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "serve question error", "err", err)
+			writeInternalError(w, r)
+			return
+		}
+	}
+	resp := NextQuestionResponse{SessionID: sess.ID, Size: sess.Size, Done: true}
+	if q := sess.pending(); q != nil {
+		resp.Done = false
+		resp.Position = len(sess.Questions)
+		resp.Sentence = &SessionSentence{
+			ID:       q.SentenceID,
+			Japanese: q.Japanese,
+			English:  q.English,
+			Page:     q.Page,
+			Level:    q.Level,
+		}
+	}
+	writeJSON(w, r, resp)
+}
+
+func (s *Server) getSessionSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	uid, _ := uidFromContext(r.Context())
+	sess, ok := s.loadSession(w, r, uid, r.URL.Query().Get("session_id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, r, sess.summary())
+}
+
+// loadSession returns uid's session id, writing the error response when it
+// cannot.
+func (s *Server) loadSession(w http.ResponseWriter, r *http.Request, uid, id string) (PracticeSession, bool) {
+	sess, err := PracticeSession{}, ErrSessionNotFound
+	if validSessionID(id) {
+		sess, err = s.repo.GetSession(r.Context(), uid, id)
+	}
+	if errors.Is(err, ErrSessionNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeSessionNotFound, "Session not found")
+		return PracticeSession{}, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "get session error", "err", err)
+		writeInternalError(w, r)
+		return PracticeSession{}, false
+	}
+	return sess, true
+}
+
+// checkSessionAnswer reports whether sentenceID is the pending question of
+// uid's session sessionID, writing the error response when it is not.
+func (s *Server) checkSessionAnswer(w http.ResponseWriter, r *http.Request, uid, sessionID string, sentenceID int) bool {
+	sess, ok := s.loadSession(w, r, uid, sessionID)
+	if !ok {
+		return false
+	}
+	if q := sess.pending(); q == nil || q.SentenceID != sentenceID {
+		writeError(w, r, http.StatusConflict, CodeNotSessionQuestion, "Sentence is not the session's current question")
+		return false
+	}
+	return true
+}
+
+// recordSessionAnswer records a graded answer against its session. Like
+// RecordAnswer's, a failure is logged rather than failing the check.
+func (s *Server) recordSessionAnswer(ctx context.Context, uid string, req CheckAnswerRequest, rec AnswerRecord, historyID int64) {
+	err := s.repo.UpdateSession(ctx, uid, req.SessionID, func(sess *PracticeSession) error {
+		return sess.answer(req.SentenceID, rec, historyID, s.now().UTC())
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "record session answer error", "err", err)
+	}
+}
+
+// overrideSessionAnswer applies an overridden verdict to the session the
+// answer was given in. Like recordSessionAnswer, it only logs a failure.
+func (s *Server) overrideSessionAnswer(ctx context.Context, uid string, req OverrideAnswerRequest) {
+	err := s.repo.UpdateSession(ctx, uid, req.SessionID, func(sess *PracticeSession) error {
+		sess.override(req.HistoryID, req.IsCorrect)
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "override session answer error", "err", err)
+	}
+}
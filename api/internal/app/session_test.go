@@ -0,0 +1,187 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sessionServer is a Server on a memory repository holding sentences 1-3 at
+// level 1 and 4 at level 2, with a clock the test moves.
+func sessionServer(t *testing.T) (*Server, *memoryRepo, *time.Time) {
+	t.Helper()
+	repo := NewMemoryRepo()
+	for id, level := range map[int]int{1: 1, 2: 1, 3: 1, 4: 2} {
+		jp, en := "文"+string(rune('0'+id)), "sentence "+string(rune('0'+id))
+		repo.putSentence(id, sentenceDoc{Japanese: jp, English: en, Page: "1", Level: level})
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	srv := NewServer(repo, &fakeExplainer{}, &fakeAnalyzer{})
+	srv.now = func() time.Time { return now }
+	return srv, repo, &now
+}
+
+func callJSON(t *testing.T, h http.HandlerFunc, method, path string, body any, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h(rec, authed(httptest.NewRequest(method, path, bytes.NewReader(b)), "u1"))
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec
+}
+
+func createTestSession(t *testing.T, srv *Server, req CreateSessionRequest) SessionSummary {
+	t.Helper()
+	var sum SessionSummary
+	if rec := callJSON(t, srv.createSession, http.MethodPost, "/api/session/create", req, &sum); rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	return sum
+}
+
+func nextTestQuestion(t *testing.T, srv *Server, id string) NextQuestionResponse {
+	t.Helper()
+	var next NextQuestionResponse
+	if rec := callJSON(t, srv.nextQuestion, http.MethodPost, "/api/session/next", SessionRequest{SessionID: id}, &next); rec.Code != http.StatusOK {
+		t.Fatalf("next: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	return next
+}
+
+func TestPracticeSessionRunsToASummary(t *testing.T) {
+	srv, _, now := sessionServer(t)
+	sum := createTestSession(t, srv, CreateSessionRequest{Size: 3, Levels: []int{1}, Source: SourceNew})
+	if sum.Size != 3 || sum.Source != SourceNew || sum.Completed || sum.Answered != 0 {
+		t.Fatalf("expected a fresh session, got %+v", sum)
+	}
+
+	seen := map[int]bool{}
+	for i := 1; i <= 3; i++ {
+		next := nextTestQuestion(t, srv, sum.SessionID)
+		if next.Done || next.Position != i || next.Sentence == nil || next.Sentence.Level != 1 || seen[next.Sentence.ID] {
+			t.Fatalf("question %d: expected a new level-1 sentence, got %+v", i, next)
+		}
+		seen[next.Sentence.ID] = true
+		// Asking again, as a reload would, serves the same question.
+		if again := nextTestQuestion(t, srv, sum.SessionID); again.Sentence == nil || again.Sentence.ID != next.Sentence.ID {
+			t.Fatalf("question %d: expected %d served again, got %+v", i, next.Sentence.ID, again)
+		}
+
+		*now = now.Add(10 * time.Second)
+		answer := next.Sentence.English
+		if i == 2 {
+			answer = "wrong"
+		}
+		req := CheckAnswerRequest{SentenceID: next.Sentence.ID, UserAnswer: answer, SessionID: sum.SessionID}
+		if rec := callJSON(t, srv.checkAnswer, http.MethodPost, "/api/answer/check", req, nil); rec.Code != http.StatusOK {
+			t.Fatalf("check: expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if next := nextTestQuestion(t, srv, sum.SessionID); !next.Done || next.Sentence != nil {
+		t.Fatalf("expected the session done after 3 questions, got %+v", next)
+	}
+
+	var got SessionSummary
+	if rec := callJSON(t, srv.getSessionSummary, http.MethodGet, "/api/session/summary?session_id="+sum.SessionID, nil, &got); rec.Code != http.StatusOK {
+		t.Fatalf("summary: expected 200, got %d", rec.Code)
+	}
+	if !got.Completed || got.Answered != 3 || got.CorrectCount != 2 || got.IncorrectCount != 1 || got.TimeSpentMS != 30000 {
+		t.Fatalf("expected 2 of 3 correct in 30s, got %+v", got)
+	}
+	if len(got.Missed) != 1 || got.Missed[0].Answer != "wrong" || got.Missed[0].CorrectAnswer == "" {
+		t.Fatalf("expected the miss listed, got %+v", got.Missed)
+	}
+
+	// Overriding the miss in the session corrects the summary too.
+	override := OverrideAnswerRequest{SentenceID: got.Missed[0].SentenceID, HistoryID: got.Missed[0].HistoryID, IsCorrect: true, SessionID: sum.SessionID}
+	if rec := callJSON(t, srv.overrideAnswer, http.MethodPost, "/api/answer/override", override, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("override: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	callJSON(t, srv.getSessionSummary, http.MethodGet, "/api/session/summary?session_id="+sum.SessionID, nil, &got)
+	if got.CorrectCount != 3 || got.Accuracy != 1 || len(got.Missed) != 0 {
+		t.Fatalf("expected the overridden answer counted correct, got %+v", got)
+	}
+}
+
+func TestPracticeSessionEndsWhenCandidatesRunOut(t *testing.T) {
+	srv, _, _ := sessionServer(t)
+	sum := createTestSession(t, srv, CreateSessionRequest{Size: 10, Levels: []int{2}})
+	next := nextTestQuestion(t, srv, sum.SessionID)
+	if next.Sentence == nil || next.Sentence.ID != 4 {
+		t.Fatalf("expected the only level-2 sentence, got %+v", next)
+	}
+	req := CheckAnswerRequest{SentenceID: 4, UserAnswer: "sentence 4", SessionID: sum.SessionID}
+	callJSON(t, srv.checkAnswer, http.MethodPost, "/api/answer/check", req, nil)
+	if next := nextTestQuestion(t, srv, sum.SessionID); !next.Done {
+		t.Fatalf("expected the session done without repeating sentence 4, got %+v", next)
+	}
+	var got SessionSummary
+	callJSON(t, srv.getSessionSummary, http.MethodGet, "/api/session/summary?session_id="+sum.SessionID, nil, &got)
+	if !got.Completed || got.Answered != 1 {
+		t.Fatalf("expected the session completed after one question, got %+v", got)
+	}
+}
+
+func TestCheckAnswerRejectsSentenceOutsideSession(t *testing.T) {
+	srv, repo, _ := sessionServer(t)
+	sum := createTestSession(t, srv, CreateSessionRequest{Size: 2})
+	next := nextTestQuestion(t, srv, sum.SessionID)
+	other := 1
+	if next.Sentence.ID == 1 {
+		other = 2
+	}
+	req := CheckAnswerRequest{SentenceID: other, UserAnswer: "x", SessionID: sum.SessionID}
+	rec := callJSON(t, srv.checkAnswer, http.MethodPost, "/api/answer/check", req, nil)
+	decodeProblem(t, rec, http.StatusConflict, CodeNotSessionQuestion)
+	if histories, _ := repo.ListIncorrectHistories(t.Context(), "u1", other); len(histories) != 0 {
+		t.Fatalf("expected the rejected answer not recorded, got %+v", histories)
+	}
+}
+
+func TestCreateSessionInvalidRequest(t *testing.T) {
+	srv, _, _ := sessionServer(t)
+	cases := []struct {
+		req  CreateSessionRequest
+		code string
+	}{
+		{CreateSessionRequest{Size: -1}, CodeInvalidSize},
+		{CreateSessionRequest{Size: maxSessionSize + 1}, CodeInvalidSize},
+		{CreateSessionRequest{Levels: []int{6}}, CodeInvalidLevels},
+		{CreateSessionRequest{Source: "everything"}, CodeInvalidSource},
+	}
+	for _, tc := range cases {
+		rec := callJSON(t, srv.createSession, http.MethodPost, "/api/session/create", tc.req, nil)
+		decodeProblem(t, rec, http.StatusBadRequest, tc.code)
+	}
+	if sum := createTestSession(t, srv, CreateSessionRequest{}); sum.Size != defaultSessionSize || sum.Source != SourceAll {
+		t.Fatalf("expected the defaults, got %+v", sum)
+	}
+}
+
+func TestSessionNotFound(t *testing.T) {
+	srv, _, _ := sessionServer(t)
+	for _, id := range []string{"", "../sentences/1", "0123456789abcdef0123456789abcdef"} {
+		rec := callJSON(t, srv.nextQuestion, http.MethodPost, "/api/session/next", SessionRequest{SessionID: id}, nil)
+		decodeProblem(t, rec, http.StatusNotFound, CodeSessionNotFound)
+		if id != "" {
+			rec = callJSON(t, srv.checkAnswer, http.MethodPost, "/api/answer/check", CheckAnswerRequest{SentenceID: 1, SessionID: id}, nil)
+			decodeProblem(t, rec, http.StatusNotFound, CodeSessionNotFound)
+		}
+	}
+	rec := callJSON(t, srv.getSessionSummary, http.MethodGet, "/api/session/summary?session_id=nope", nil, nil)
+	decodeProblem(t, rec, http.StatusNotFound, CodeSessionNotFound)
+}
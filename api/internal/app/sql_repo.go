@@ -0,0 +1,1042 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// sqlDialect captures the few places SQLite and Postgres disagree. Queries
+// are written once with "?" placeholders and rebound per dialect.
+type sqlDialect struct {
+	driver string
+	// autoIncrementPK is the column definition for a surrogate integer key.
+	autoIncrementPK string
+	// numberedPlaceholders rewrites "?" to "$1", "$2", ... (Postgres).
+	numberedPlaceholders bool
+	// forUpdate is appended to read-modify-write SELECTs to lock the row.
+	// SQLite has no row locks and needs none: writes are serialized on its
+	// single connection.
+	forUpdate string
+}
+
+var sqlDialects = map[string]sqlDialect{
+	"sqlite": {
+		driver:          "sqlite",
+		autoIncrementPK: "INTEGER PRIMARY KEY AUTOINCREMENT",
+	},
+	"postgres": {
+		driver:               "pgx",
+		autoIncrementPK:      "BIGSERIAL PRIMARY KEY",
+		numberedPlaceholders: true,
+		forUpdate:            " FOR UPDATE",
+	},
+}
+
+func (d sqlDialect) rebind(query string) string {
+	if !d.numberedPlaceholders {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// sqlMigrations is the ordered schema history. Each entry is applied once,
+// in its own transaction, and recorded in schema_migrations; never edit an
+// entry that has shipped — append a new one instead. "{{pk}}" expands to the
+// dialect's auto-increment primary key.
+//
+// The layout mirrors the Firestore model rather than the original MySQL
+// schema: stats and histories are per user (uid), so one database serves
+// every allowlisted learner. Timestamps on stats and histories are stored as
+// Unix microseconds, which sort correctly and round-trip exactly on every
+// driver; the sentence timestamps stay RFC 3339 strings, as in Firestore.
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE sentences (
+			id INTEGER PRIMARY KEY,
+			japanese TEXT NOT NULL,
+			english TEXT NOT NULL,
+			page TEXT NOT NULL DEFAULT '',
+			level INTEGER NOT NULL DEFAULT 0,
+			is_reported BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE sentence_stats (
+			uid TEXT NOT NULL,
+			sentence_id INTEGER NOT NULL,
+			correct_count INTEGER NOT NULL DEFAULT 0,
+			incorrect_count INTEGER NOT NULL DEFAULT 0,
+			updated_at BIGINT NOT NULL,
+			PRIMARY KEY (uid, sentence_id)
+		)`,
+		`CREATE INDEX sentence_stats_uid_updated_at ON sentence_stats (uid, updated_at)`,
+		`CREATE TABLE answer_histories (
+			id {{pk}},
+			uid TEXT NOT NULL,
+			sentence_id INTEGER NOT NULL,
+			is_correct BOOLEAN NOT NULL,
+			incorrect_answer TEXT NOT NULL,
+			created_at BIGINT NOT NULL
+		)`,
+		`CREATE INDEX answer_histories_uid_sentence_created_at ON answer_histories (uid, sentence_id, created_at)`,
+	},
+	// 2: spaced-repetition state (ReviewState). Zero means unset, as on a
+	// Firestore doc without the fields; existing rows count as last reviewed
+	// when their stats were last touched.
+	{
+		`ALTER TABLE sentence_stats ADD COLUMN due_at BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE sentence_stats ADD COLUMN ease DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE sentence_stats ADD COLUMN interval_days INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sentence_stats ADD COLUMN reps INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sentence_stats ADD COLUMN first_reviewed_at BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE sentence_stats ADD COLUMN last_reviewed_at BIGINT NOT NULL DEFAULT 0`,
+		`UPDATE sentence_stats SET last_reviewed_at = updated_at`,
+	},
+	// 3: alternative accepted translations, as a JSON array of strings.
+	{
+		`ALTER TABLE sentences ADD COLUMN accepted_answers TEXT NOT NULL DEFAULT '[]'`,
+	},
+	// 4: how each answer was graded, and whether the learner overrode it.
+	{
+		`ALTER TABLE answer_histories ADD COLUMN graded_by TEXT NOT NULL DEFAULT 'exact'`,
+		`ALTER TABLE answer_histories ADD COLUMN grader_reason TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE answer_histories ADD COLUMN overridden BOOLEAN NOT NULL DEFAULT FALSE`,
+	},
+	// 5: cached LLM output (explanations, weakness insights) by cache key.
+	{
+		`CREATE TABLE generation_cache (
+			cache_key TEXT PRIMARY KEY,
+			text TEXT NOT NULL,
+			expires_at BIGINT NOT NULL
+		)`,
+	},
+	// 6: per-user rate-limit state (RateState) by RateLimiter key.
+	{
+		`CREATE TABLE rate_limits (
+			rate_key TEXT PRIMARY KEY,
+			tokens DOUBLE PRECISION NOT NULL,
+			updated_at BIGINT NOT NULL,
+			day BIGINT NOT NULL,
+			day_count INTEGER NOT NULL
+		)`,
+	},
+	// 7: one report per learner per sentence (SentenceReport).
+	{
+		`CREATE TABLE sentence_reports (
+			sentence_id INTEGER NOT NULL,
+			uid TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			comment TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			PRIMARY KEY (sentence_id, uid)
+		)`,
+	},
+	// 8: practice sessions (PracticeSession), with their levels and
+	// questions as JSON arrays.
+	{
+		`CREATE TABLE practice_sessions (
+			uid TEXT NOT NULL,
+			id TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			levels TEXT NOT NULL,
+			source TEXT NOT NULL,
+			questions TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			completed_at BIGINT NOT NULL,
+			PRIMARY KEY (uid, id)
+		)`,
+	},
+	// 9: lets CacheGeneration find expired entries to prune.
+	{
+		`CREATE INDEX generation_cache_expires_at ON generation_cache (expires_at)`,
+	},
+}
+
+// toMicros and fromMicros convert the BIGINT timestamp columns, mapping the
+// zero time to 0 and back so unset ReviewState times round-trip as unset.
+func toMicros(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UTC().UnixMicro()
+}
+
+func fromMicros(v int64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(v).UTC()
+}
+
+// sqlRepo implements SentenceRepository on database/sql, for self-hosting on
+// SQLite or Postgres without any Google Cloud dependency.
+type sqlRepo struct {
+	db      *sql.DB
+	dialect sqlDialect
+	now     func() time.Time
+	policy  ReviewPolicy
+	// reportPolicy decides when Report hides a sentence from everyone.
+	reportPolicy ReportPolicy
+}
+
+// OpenSQLRepo opens backend ("sqlite" or "postgres") at dsn — a file path
+// for SQLite, a connection string for Postgres — and applies any pending
+// schema migrations.
+func OpenSQLRepo(ctx context.Context, backend, dsn string) (*sqlRepo, error) {
+	dialect, ok := sqlDialects[backend]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL backend %q", backend)
+	}
+	if dsn == "" {
+		return nil, errors.New("DATABASE_URL is required for SQL backends")
+	}
+	db, err := sql.Open(dialect.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", backend, err)
+	}
+	if backend == "sqlite" {
+		// SQLite allows a single writer; serializing on one connection
+		// avoids SQLITE_BUSY under concurrent requests, and keeps a
+		// ":memory:" database from being split across connections.
+		db.SetMaxOpenConns(1)
+	}
+	r := &sqlRepo{db: db, dialect: dialect, now: time.Now, policy: DefaultReviewPolicy()}
+	if err := r.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+func (r *sqlRepo) SetReviewPolicy(p ReviewPolicy) {
+	r.policy = p
+}
+
+func (r *sqlRepo) SetReportPolicy(p ReportPolicy) {
+	r.reportPolicy = p
+}
+
+func (r *sqlRepo) migrate(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var current int
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	for i := current; i < len(sqlMigrations); i++ {
+		version := i + 1
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range sqlMigrations[i] {
+			stmt = strings.ReplaceAll(stmt, "{{pk}}", r.dialect.autoIncrementPK)
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, r.dialect.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+			version, r.now().UTC().UnixMicro()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// LoadNDJSON upserts every sentence in an NDJSON export (the format cmd/seed
+// reads) in one transaction, overwriting existing rows with the same ID the
+// way cmd/seed's Firestore Set does. It returns the number of rows written.
+func (r *sqlRepo) LoadNDJSON(ctx context.Context, rd io.Reader) (int, error) {
+	now := r.now().UTC().Format(time.RFC3339)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	count := 0
+	err = ScanSeedRows(rd, func(rw SeedRow) error {
+		if err := rw.Validate(); err != nil {
+			return fmt.Errorf("invalid row: %w", err)
+		}
+		err := r.upsertSentence(ctx, tx, rw.ID, sentenceDoc{
+			Japanese:        rw.Japanese,
+			English:         rw.English,
+			Page:            rw.Page.String(),
+			Level:           rw.Level,
+			IsReported:      rw.IsReported != 0,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			AcceptedAnswers: rw.AcceptedAnswers,
+		})
+		if err != nil {
+			return fmt.Errorf("write sentence %d: %w", rw.ID, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (r *sqlRepo) upsertSentence(ctx context.Context, ex sqlExecer, id int, sd sentenceDoc) error {
+	accepted, err := encodeAcceptedAnswers(sd.AcceptedAnswers)
+	if err != nil {
+		return err
+	}
+	_, err = ex.ExecContext(ctx, r.dialect.rebind(`INSERT INTO sentences
+		(id, japanese, english, page, level, is_reported, created_at, updated_at, accepted_answers)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			japanese = excluded.japanese,
+			english = excluded.english,
+			page = excluded.page,
+			level = excluded.level,
+			is_reported = excluded.is_reported,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			accepted_answers = excluded.accepted_answers`),
+		id, sd.Japanese, sd.English, sd.Page, sd.Level, sd.IsReported, sd.CreatedAt, sd.UpdatedAt, accepted)
+	return err
+}
+
+func encodeAcceptedAnswers(answers []string) (string, error) {
+	if answers == nil {
+		answers = []string{}
+	}
+	b, err := json.Marshal(answers)
+	return string(b), err
+}
+
+// reviewColumns are the ReviewState columns of sentence_stats, in the order
+// scanReviewState reads them.
+const reviewColumns = `due_at, ease, interval_days, reps, first_reviewed_at, last_reviewed_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanReviewState scans the reviewColumns, after any leading columns whose
+// destinations are passed in head.
+func scanReviewState(sc rowScanner, head ...any) (ReviewState, error) {
+	var rs ReviewState
+	var due, first, last int64
+	dest := append(head, &due, &rs.Ease, &rs.IntervalDays, &rs.Reps, &first, &last)
+	if err := sc.Scan(dest...); err != nil {
+		return ReviewState{}, err
+	}
+	rs.DueAt, rs.FirstReviewedAt, rs.LastReviewedAt = fromMicros(due), fromMicros(first), fromMicros(last)
+	return rs, nil
+}
+
+func (r *sqlRepo) RandomCandidate(ctx context.Context, uid string, q CandidateQuery) (*Sentence, error) {
+	now := r.now()
+	query := `SELECT s.id, s.japanese, s.english, s.page, s.level, s.created_at, s.updated_at,
+			COALESCE(st.correct_count, 0), COALESCE(st.incorrect_count, 0), st.uid IS NOT NULL,
+			COALESCE(st.due_at, 0), COALESCE(st.ease, 0), COALESCE(st.interval_days, 0),
+			COALESCE(st.reps, 0), COALESCE(st.first_reviewed_at, 0), COALESCE(st.last_reviewed_at, 0)
+		FROM sentences s
+		LEFT JOIN sentence_stats st ON st.sentence_id = s.id AND st.uid = ?
+		WHERE s.is_reported = ?
+			AND NOT EXISTS (SELECT 1 FROM sentence_reports sr WHERE sr.sentence_id = s.id AND sr.uid = ?)`
+	args := []any{uid, false, uid}
+	if len(q.Levels) > 0 {
+		query += ` AND s.level IN (?` + strings.Repeat(`, ?`, len(q.Levels)-1) + `)`
+		for _, lv := range q.Levels {
+			args = append(args, lv)
+		}
+	}
+	if len(q.Exclude) > 0 {
+		query += ` AND s.id NOT IN (?` + strings.Repeat(`, ?`, len(q.Exclude)-1) + `)`
+		for _, id := range q.Exclude {
+			args = append(args, id)
+		}
+	}
+	// The SQL form of CandidateQuery.admits.
+	switch q.source() {
+	case SourceNew:
+		query += ` AND st.uid IS NULL`
+	case SourceSeen:
+		query += ` AND st.uid IS NOT NULL`
+	case SourceReview:
+		query += ` AND st.uid IS NOT NULL AND st.due_at <= ?`
+		args = append(args, toMicros(now))
+	case SourceMistakes:
+		query += ` AND COALESCE(st.incorrect_count, 0) > 0
+			AND COALESCE(st.correct_count, 0) - COALESCE(st.incorrect_count, 0) < ?`
+		args = append(args, r.policy.masteryThreshold())
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []reviewCandidate
+	for rows.Next() {
+		var c reviewCandidate
+		s := &Sentence{}
+		c.state, err = scanReviewState(rows, &s.ID, &s.Japanese, &s.English, &s.Page, &s.Level,
+			&s.CreatedAt, &s.UpdatedAt, &s.CorrectCount, &s.IncorrectCount, &c.seen)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.sentence = s
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states, err := r.reviewedSince(ctx, uid, r.policy.dayStart(now))
+	if err != nil {
+		return nil, err
+	}
+	newToday, reviewsToday := r.policy.dailyUsage(states, now)
+	s := r.policy.pick(candidates, newToday, reviewsToday, now)
+	if s == nil {
+		return nil, ErrNoCandidate
+	}
+	return s, nil
+}
+
+// reviewedSince returns the review state of every sentence the user has
+// answered at or after since — the input ReviewPolicy.dailyUsage needs.
+func (r *sqlRepo) reviewedSince(ctx context.Context, uid string, since time.Time) ([]ReviewState, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(`SELECT `+reviewColumns+`
+		FROM sentence_stats WHERE uid = ? AND last_reviewed_at >= ?`), uid, toMicros(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var states []ReviewState
+	for rows.Next() {
+		rs, err := scanReviewState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, rs)
+	}
+	return states, rows.Err()
+}
+
+func (r *sqlRepo) AcceptedAnswers(ctx context.Context, id int) ([]string, int, error) {
+	var sd sentenceDoc
+	var accepted string
+	err := r.db.QueryRowContext(ctx, r.dialect.rebind(`SELECT english, accepted_answers, level FROM sentences WHERE id = ?`), id).
+		Scan(&sd.English, &accepted, &sd.Level)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := json.Unmarshal([]byte(accepted), &sd.AcceptedAnswers); err != nil {
+		return nil, 0, fmt.Errorf("sentence %d: decode accepted_answers: %w", id, err)
+	}
+	return sd.acceptedAnswers(), sd.Level, nil
+}
+
+func (r *sqlRepo) GetSentence(ctx context.Context, id int) (string, string, error) {
+	var japanese, english string
+	err := r.db.QueryRowContext(ctx, r.dialect.rebind(`SELECT japanese, english FROM sentences WHERE id = ?`), id).
+		Scan(&japanese, &english)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", ErrNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return japanese, english, nil
+}
+
+// incorrectHistories reads a sentence's wrong-answer history, newest first,
+// following firestoreRepo.incorrectHistories' limit<=0-means-unbounded
+// convention.
+func (r *sqlRepo) incorrectHistories(ctx context.Context, uid string, id, limit int) ([]AnswerHistory, error) {
+	query := `SELECT id, incorrect_answer, created_at, graded_by, grader_reason, overridden FROM answer_histories
+		WHERE uid = ? AND sentence_id = ? AND is_correct = ?
+		ORDER BY created_at DESC, id DESC`
+	args := []any{uid, id, false}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	histories := make([]AnswerHistory, 0)
+	for rows.Next() {
+		var h AnswerHistory
+		var createdAt int64
+		if err := rows.Scan(&h.ID, &h.IncorrectAnswer, &createdAt, &h.GradedBy, &h.GraderReason, &h.Overridden); err != nil {
+			return nil, err
+		}
+		h.CreatedAt = time.UnixMicro(createdAt).UTC().Format(time.RFC3339Nano)
+		histories = append(histories, h)
+	}
+	return histories, rows.Err()
+}
+
+func (r *sqlRepo) ListIncorrectHistories(ctx context.Context, uid string, id int) ([]AnswerHistory, error) {
+	return r.incorrectHistories(ctx, uid, id, 0)
+}
+
+// listMistakes mirrors firestoreRepo.listMistakes, including the insight
+// path's bounds (see maxInsightStatsScan). The stats scan is read in full
+// before any history query runs, since a SQLite repo has only one
+// connection to share between them.
+func (r *sqlRepo) listMistakes(ctx context.Context, uid string, historyLimit, scanLimit int) ([]MistakeSentence, error) {
+	query := `SELECT st.sentence_id, st.correct_count, st.incorrect_count, s.japanese, s.english, s.level
+		FROM sentence_stats st
+		LEFT JOIN sentences s ON s.id = st.sentence_id
+		WHERE st.uid = ?`
+	args := []any{uid}
+	if scanLimit > 0 {
+		query += ` ORDER BY st.updated_at DESC, st.sentence_id LIMIT ?`
+		args = append(args, maxInsightStatsScanCeiling)
+	}
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	var scanned []MistakeSentence
+	for rows.Next() {
+		var m MistakeSentence
+		var japanese, english sql.NullString
+		var level sql.NullInt64
+		if err := rows.Scan(&m.SentenceID, &m.CorrectCount, &m.IncorrectCount, &japanese, &english, &level); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if m.IncorrectCount == 0 || !japanese.Valid {
+			continue
+		}
+		m.Japanese, m.CorrectAnswer, m.Level = japanese.String, english.String, int(level.Int64)
+		scanned = append(scanned, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	mistakes := make([]MistakeSentence, 0)
+	for _, m := range scanned {
+		if scanLimit > 0 && len(mistakes) >= scanLimit {
+			break
+		}
+		m.WrongAnswers, err = r.incorrectHistories(ctx, uid, m.SentenceID, historyLimit)
+		if err != nil {
+			return nil, err
+		}
+		if len(m.WrongAnswers) == 0 {
+			continue
+		}
+		mistakes = append(mistakes, m)
+	}
+
+	sort.Slice(mistakes, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, mistakes[i].WrongAnswers[0].CreatedAt)
+		tj, _ := time.Parse(time.RFC3339, mistakes[j].WrongAnswers[0].CreatedAt)
+		return ti.After(tj)
+	})
+	return mistakes, nil
+}
+
+// ListMistakes reads a summary of every mistaken sentence, narrowed by the
+// filters SQL can apply, and then the histories of the page's sentences
+// only.
+func (r *sqlRepo) ListMistakes(ctx context.Context, uid string, q MistakeQuery) ([]MistakeSentence, string, error) {
+	query := `SELECT st.sentence_id, s.level, st.correct_count, st.incorrect_count, MAX(h.created_at), s.japanese, s.english
+		FROM sentence_stats st
+		JOIN sentences s ON s.id = st.sentence_id
+		JOIN answer_histories h ON h.uid = st.uid AND h.sentence_id = st.sentence_id AND h.is_correct = ?
+		WHERE st.uid = ? AND st.incorrect_count > 0 AND st.incorrect_count >= ?`
+	args := []any{false, uid, q.MinIncorrect}
+	if len(q.Levels) > 0 {
+		query += ` AND s.level IN (?` + strings.Repeat(`, ?`, len(q.Levels)-1) + `)`
+		for _, lv := range q.Levels {
+			args = append(args, lv)
+		}
+	}
+	query += ` GROUP BY st.sentence_id, s.level, st.correct_count, st.incorrect_count, s.japanese, s.english`
+	if !q.Since.IsZero() {
+		query += ` HAVING MAX(h.created_at) >= ?`
+		args = append(args, q.Since.UnixMicro())
+	}
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	var all []mistakeSummary
+	texts := map[int][2]string{}
+	for rows.Next() {
+		var m mistakeSummary
+		var lastMissedAt int64
+		var japanese, english string
+		if err := rows.Scan(&m.id, &m.level, &m.correct, &m.incorrect, &lastMissedAt, &japanese, &english); err != nil {
+			rows.Close()
+			return nil, "", err
+		}
+		m.lastMissedAt = time.UnixMicro(lastMissedAt).UTC()
+		all = append(all, m)
+		texts[m.id] = [2]string{japanese, english}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	page, next, err := q.page(all, r.policy)
+	if err != nil {
+		return nil, "", err
+	}
+	mistakes := make([]MistakeSentence, 0, len(page))
+	for _, m := range page {
+		wrongAnswers, err := r.incorrectHistories(ctx, uid, m.id, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		mistakes = append(mistakes, m.mistakeSentence(texts[m.id][0], texts[m.id][1], wrongAnswers))
+	}
+	return mistakes, next, nil
+}
+
+func (r *sqlRepo) ListMistakesForInsight(ctx context.Context, uid string) ([]MistakeSentence, error) {
+	return r.listMistakes(ctx, uid, maxWrongAnswersPerSentence, maxInsightStatsScan)
+}
+
+func (r *sqlRepo) RecordAnswer(ctx context.Context, uid string, id int, rec AnswerRecord) (int64, error) {
+	nowTime := r.now().UTC()
+	now := nowTime.UnixMicro()
+	correctDelta, incorrectDelta := 0, 1
+	if rec.Correct {
+		correctDelta, incorrectDelta = 1, 0
+	}
+	hd := newHistoryDoc(rec, nowTime)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	prev, err := scanReviewState(tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT `+reviewColumns+`
+		FROM sentence_stats WHERE uid = ? AND sentence_id = ?`+r.dialect.forUpdate), uid, id))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	rs := r.policy.advance(prev, rec.Correct, nowTime)
+
+	_, err = tx.ExecContext(ctx, r.dialect.rebind(`INSERT INTO sentence_stats
+		(uid, sentence_id, correct_count, incorrect_count, updated_at, `+reviewColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid, sentence_id) DO UPDATE SET
+			correct_count = sentence_stats.correct_count + excluded.correct_count,
+			incorrect_count = sentence_stats.incorrect_count + excluded.incorrect_count,
+			updated_at = excluded.updated_at,
+			due_at = excluded.due_at,
+			ease = excluded.ease,
+			interval_days = excluded.interval_days,
+			reps = excluded.reps,
+			first_reviewed_at = excluded.first_reviewed_at,
+			last_reviewed_at = excluded.last_reviewed_at`),
+		uid, id, correctDelta, incorrectDelta, now,
+		toMicros(rs.DueAt), rs.Ease, rs.IntervalDays, rs.Reps, toMicros(rs.FirstReviewedAt), toMicros(rs.LastReviewedAt))
+	if err != nil {
+		return 0, err
+	}
+	// RETURNING rather than LastInsertId, which the Postgres driver lacks.
+	var historyID int64
+	err = tx.QueryRowContext(ctx, r.dialect.rebind(`INSERT INTO answer_histories
+		(uid, sentence_id, is_correct, incorrect_answer, created_at, graded_by, grader_reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		uid, id, hd.IsCorrect, hd.IncorrectAnswer, now, hd.GradedBy, hd.GraderReason).Scan(&historyID)
+	if err != nil {
+		return 0, err
+	}
+	return historyID, tx.Commit()
+}
+
+func (r *sqlRepo) OverrideAnswer(ctx context.Context, uid string, id int, historyID int64, ov AnswerOverride) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var hd historyDoc
+	err = tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT is_correct, incorrect_answer, graded_by FROM answer_histories
+		WHERE id = ? AND uid = ? AND sentence_id = ?`+r.dialect.forUpdate), historyID, uid, id).
+		Scan(&hd.IsCorrect, &hd.IncorrectAnswer, &hd.GradedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrHistoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !hd.overridable(ov.Correct) {
+		return ErrNotOverridable
+	}
+	if ov.Promote && ov.Correct {
+		if err := r.promoteAnswer(ctx, tx, id, hd.IncorrectAnswer); err != nil {
+			return err
+		}
+	}
+	if hd.IsCorrect == ov.Correct {
+		return tx.Commit()
+	}
+	correctDelta, incorrectDelta := overrideDeltas(ov.Correct)
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`UPDATE answer_histories SET is_correct = ?, overridden = ? WHERE id = ?`),
+		ov.Correct, true, historyID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`UPDATE sentence_stats
+		SET correct_count = correct_count + ?, incorrect_count = incorrect_count + ?
+		WHERE uid = ? AND sentence_id = ?`), correctDelta, incorrectDelta, uid, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// promoteAnswer adds answer to the sentence's accepted_answers within tx.
+func (r *sqlRepo) promoteAnswer(ctx context.Context, tx *sql.Tx, id int, answer string) error {
+	var sd sentenceDoc
+	var accepted string
+	err := tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT english, accepted_answers FROM sentences WHERE id = ?`+r.dialect.forUpdate), id).
+		Scan(&sd.English, &accepted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(accepted), &sd.AcceptedAnswers); err != nil {
+		return fmt.Errorf("sentence %d: decode accepted_answers: %w", id, err)
+	}
+	if !sd.addAlternative(answer) {
+		return nil
+	}
+	if accepted, err = encodeAcceptedAnswers(sd.AcceptedAnswers); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, r.dialect.rebind(`UPDATE sentences SET accepted_answers = ? WHERE id = ?`), accepted, id)
+	return err
+}
+
+// Report returns ErrNotFound for an unknown sentence, where Firestore's
+// Update would fail with a NotFound status.
+func (r *sqlRepo) Report(ctx context.Context, uid string, id int, rec ReportRecord) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists int
+	err = tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT 1 FROM sentences WHERE id = ?`+r.dialect.forUpdate), id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`INSERT INTO sentence_reports (sentence_id, uid, reason, comment, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (sentence_id, uid) DO UPDATE SET reason = excluded.reason, comment = excluded.comment,
+			created_at = excluded.created_at`),
+		id, uid, rec.Reason, rec.Comment, toMicros(r.now())); err != nil {
+		return err
+	}
+	var reporters int
+	if err := tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT COUNT(*) FROM sentence_reports WHERE sentence_id = ?`), id).
+		Scan(&reporters); err != nil {
+		return err
+	}
+	if r.reportPolicy.hides(reporters) {
+		if _, err := tx.ExecContext(ctx, r.dialect.rebind(`UPDATE sentences SET is_reported = ? WHERE id = ?`), true, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListReported reads the sentences in full before their reports, since a
+// SQLite repo has only one connection to share between the queries.
+func (r *sqlRepo) ListReported(ctx context.Context) ([]AdminSentence, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(`SELECT id, japanese, english, page, level, is_reported, created_at, updated_at
+		FROM sentences s
+		WHERE is_reported = ? OR EXISTS (SELECT 1 FROM sentence_reports sr WHERE sr.sentence_id = s.id)
+		ORDER BY id`), true)
+	if err != nil {
+		return nil, err
+	}
+	sentences := make([]AdminSentence, 0)
+	byID := map[int]int{}
+	for rows.Next() {
+		s := AdminSentence{Reports: []SentenceReport{}}
+		if err := rows.Scan(&s.ID, &s.Japanese, &s.English, &s.Page, &s.Level, &s.IsReported, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byID[s.ID] = len(sentences)
+		sentences = append(sentences, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.QueryContext(ctx, `SELECT sentence_id, uid, reason, comment, created_at
+		FROM sentence_reports ORDER BY created_at, uid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var createdAt int64
+		var sr SentenceReport
+		if err := rows.Scan(&id, &sr.UID, &sr.Reason, &sr.Comment, &createdAt); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[id]; ok {
+			sr.CreatedAt = time.UnixMicro(createdAt).UTC().Format(time.RFC3339Nano)
+			sentences[i].Reports = append(sentences[i].Reports, sr)
+		}
+	}
+	return sentences, rows.Err()
+}
+
+// execOnSentence runs a statement that affects the sentence with the given
+// ID, returning ErrNotFound when it affects nothing.
+func (r *sqlRepo) execOnSentence(ctx context.Context, ex sqlExecer, query string, args ...any) error {
+	res, err := ex.ExecContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqlRepo) UpdateSentence(ctx context.Context, id int, edit SentenceEdit) error {
+	return r.execOnSentence(ctx, r.db, `UPDATE sentences SET japanese = ?, english = ?, page = ?, level = ?, updated_at = ? WHERE id = ?`,
+		edit.Japanese, edit.English, edit.Page, edit.Level, r.now().UTC().Format(time.RFC3339), id)
+}
+
+// clearReports deletes a sentence's reports, then runs stmt (which must
+// affect the sentence) in the same transaction.
+func (r *sqlRepo) clearReports(ctx context.Context, id int, stmt string, args ...any) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`DELETE FROM sentence_reports WHERE sentence_id = ?`), id); err != nil {
+		return err
+	}
+	if err := r.execOnSentence(ctx, tx, stmt, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) Unreport(ctx context.Context, id int) error {
+	return r.clearReports(ctx, id, `UPDATE sentences SET is_reported = ?, updated_at = ? WHERE id = ?`,
+		false, r.now().UTC().Format(time.RFC3339), id)
+}
+
+func (r *sqlRepo) DeleteSentence(ctx context.Context, id int) error {
+	return r.clearReports(ctx, id, `DELETE FROM sentences WHERE id = ?`, id)
+}
+
+func (r *sqlRepo) CachedGeneration(ctx context.Context, key string) (string, bool, error) {
+	var cd cacheDoc
+	var expiresAt int64
+	err := r.db.QueryRowContext(ctx, r.dialect.rebind(`SELECT text, expires_at FROM generation_cache WHERE cache_key = ?`), key).
+		Scan(&cd.Text, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	cd.ExpiresAt = fromMicros(expiresAt)
+	if !cd.fresh(r.now()) {
+		return "", false, nil
+	}
+	return cd.Text, true, nil
+}
+
+func (r *sqlRepo) UpdateRateState(ctx context.Context, key string, fn func(RateState) RateState) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var st RateState
+	var updatedAt, day int64
+	err = tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT tokens, updated_at, day, day_count FROM rate_limits
+		WHERE rate_key = ?`+r.dialect.forUpdate), key).
+		Scan(&st.Tokens, &updatedAt, &day, &st.DayCount)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	st.UpdatedAt, st.Day = fromMicros(updatedAt), fromMicros(day)
+	st = fn(st)
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`INSERT INTO rate_limits (rate_key, tokens, updated_at, day, day_count)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (rate_key) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at,
+			day = excluded.day, day_count = excluded.day_count`),
+		key, st.Tokens, toMicros(st.UpdatedAt), toMicros(st.Day), st.DayCount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sessionColumns are the practice_sessions columns scanSession reads and
+// saveSession writes, in order.
+const sessionColumns = `id, size, levels, source, questions, created_at, completed_at`
+
+func scanSession(sc rowScanner) (PracticeSession, error) {
+	var s PracticeSession
+	var levels, questions string
+	var createdAt, completedAt int64
+	if err := sc.Scan(&s.ID, &s.Size, &levels, &s.Source, &questions, &createdAt, &completedAt); err != nil {
+		return PracticeSession{}, err
+	}
+	if err := json.Unmarshal([]byte(levels), &s.Levels); err != nil {
+		return PracticeSession{}, err
+	}
+	if err := json.Unmarshal([]byte(questions), &s.Questions); err != nil {
+		return PracticeSession{}, err
+	}
+	s.CreatedAt, s.CompletedAt = fromMicros(createdAt), fromMicros(completedAt)
+	return s, nil
+}
+
+// saveSession inserts s, or overwrites it when overwrite is set.
+func (r *sqlRepo) saveSession(ctx context.Context, ex sqlExecer, uid string, s PracticeSession, overwrite bool) error {
+	levels, err := json.Marshal(s.Levels)
+	if err != nil {
+		return err
+	}
+	questions, err := json.Marshal(s.Questions)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO practice_sessions (uid, ` + sessionColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if overwrite {
+		query += ` ON CONFLICT (uid, id) DO UPDATE SET questions = excluded.questions, completed_at = excluded.completed_at`
+	}
+	_, err = ex.ExecContext(ctx, r.dialect.rebind(query),
+		uid, s.ID, s.Size, string(levels), s.Source, string(questions), toMicros(s.CreatedAt), toMicros(s.CompletedAt))
+	return err
+}
+
+func (r *sqlRepo) CreateSession(ctx context.Context, uid string, s PracticeSession) error {
+	return r.saveSession(ctx, r.db, uid, s, false)
+}
+
+func (r *sqlRepo) GetSession(ctx context.Context, uid, id string) (PracticeSession, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx, r.dialect.rebind(`SELECT `+sessionColumns+`
+		FROM practice_sessions WHERE uid = ? AND id = ?`), uid, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return PracticeSession{}, ErrSessionNotFound
+	}
+	return s, err
+}
+
+func (r *sqlRepo) UpdateSession(ctx context.Context, uid, id string, fn func(*PracticeSession) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	s, err := scanSession(tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT `+sessionColumns+`
+		FROM practice_sessions WHERE uid = ? AND id = ?`+r.dialect.forUpdate), uid, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := fn(&s); err != nil {
+		return err
+	}
+	if err := r.saveSession(ctx, tx, uid, s, true); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// CacheGeneration deletes expired rows as it writes, so the table holds at
+// most what was written within one TTL.
+func (r *sqlRepo) CacheGeneration(ctx context.Context, key, text string, ttl time.Duration) error {
+	now := r.now()
+	cd := newCacheDoc(text, ttl, now)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`DELETE FROM generation_cache WHERE expires_at <= ?`), toMicros(now)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind(`INSERT INTO generation_cache (cache_key, text, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (cache_key) DO UPDATE SET text = excluded.text, expires_at = excluded.expires_at`),
+		key, cd.Text, toMicros(cd.ExpiresAt)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sqlHarness(t *testing.T, repo *sqlRepo) repoHarness {
+	t.Helper()
+	ctx := context.Background()
+	return repoHarness{
+		repo: repo,
+		put: func(t *testing.T, id int, sd sentenceDoc) {
+			if err := repo.upsertSentence(ctx, repo.db, id, sd); err != nil {
+				t.Fatalf("seed sentence %d: %v", id, err)
+			}
+		},
+		deleteSentence: func(t *testing.T, id int) {
+			if _, err := repo.db.ExecContext(ctx, repo.dialect.rebind(`DELETE FROM sentences WHERE id = ?`), id); err != nil {
+				t.Fatal(err)
+			}
+		},
+		setNow: func(now time.Time) { repo.now = func() time.Time { return now } },
+	}
+}
+
+func newSQLiteRepo(t *testing.T) *sqlRepo {
+	t.Helper()
+	repo, err := OpenSQLRepo(context.Background(), "sqlite", filepath.Join(t.TempDir(), "eagle.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestSQLiteRepoConformance(t *testing.T) {
+	runRepoConformance(t, func(t *testing.T) repoHarness {
+		return sqlHarness(t, newSQLiteRepo(t))
+	})
+}
+
+// TestPostgresRepoConformance runs against the database at
+// POSTGRES_TEST_URL, emptying its tables before each case. Like the
+// Firestore emulator tests, it is skipped when the variable is unset.
+func TestPostgresRepoConformance(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_URL")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_URL not set; skipping Postgres test")
+	}
+	runRepoConformance(t, func(t *testing.T) repoHarness {
+		ctx := context.Background()
+		repo, err := OpenSQLRepo(ctx, "postgres", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		for _, table := range []string{"answer_histories", "sentence_stats", "sentences", "generation_cache", "rate_limits", "sentence_reports"} {
+			if _, err := repo.db.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				t.Fatalf("clear %s: %v", table, err)
+			}
+		}
+		return sqlHarness(t, repo)
+	})
+}
+
+func TestSQLRepoMigrationsAreIdempotent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "eagle.db")
+	for i := 0; i < 2; i++ {
+		repo, err := OpenSQLRepo(ctx, "sqlite", path)
+		if err != nil {
+			t.Fatalf("open %d: %v", i, err)
+		}
+		var version int
+		if err := repo.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version != len(sqlMigrations) {
+			t.Fatalf("open %d: expected schema version %d, got %d", i, len(sqlMigrations), version)
+		}
+		repo.Close()
+	}
+}
+
+func TestSQLRepoLoadNDJSONUpserts(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepo(t)
+	first := `{"id": 1, "japanese": "犬", "english": "dog", "page": 3, "level": 1, "is_reported": 0, "accepted_answers": ["a dog"]}`
+	if n, err := repo.LoadNDJSON(ctx, strings.NewReader(first)); err != nil || n != 1 {
+		t.Fatalf("expected 1 row, got %d, %v", n, err)
+	}
+	second := `{"id": 1, "japanese": "犬", "english": "a dog", "page": 3, "level": 1, "is_reported": 0}`
+	if _, err := repo.LoadNDJSON(ctx, strings.NewReader(second)); err != nil {
+		t.Fatal(err)
+	}
+	answers, _, err := repo.AcceptedAnswers(ctx, 1)
+	if err != nil || len(answers) != 1 || answers[0] != "a dog" {
+		t.Fatalf("expected the second load to overwrite english and alternatives, got %q, %v", answers, err)
+	}
+	if _, err := repo.LoadNDJSON(ctx, strings.NewReader(`{"id": 2, "japanese": "猫", "english": "cat", "page": 1, "level": 9}`)); err == nil {
+		t.Fatal("expected an error for level 9")
+	}
+}
+
+func TestSQLRepoCacheGenerationPrunesExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepo(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	if err := repo.CacheGeneration(ctx, "old", "stale", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CacheGeneration(ctx, "kept", "fresh", 3*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Hour)
+	if err := repo.CacheGeneration(ctx, "new", "fresh", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := repo.db.QueryContext(ctx, `SELECT cache_key FROM generation_cache ORDER BY cache_key`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+	if strings.Join(keys, ",") != "kept,new" {
+		t.Fatalf("expected the expired entry to be pruned, got %q", keys)
+	}
+}
+
+func TestSQLDialectRebind(t *testing.T) {
+	q := `SELECT a FROM t WHERE b = ? AND c IN (?, ?)`
+	if got := sqlDialects["sqlite"].rebind(q); got != q {
+		t.Fatalf("sqlite should keep ? placeholders, got %q", got)
+	}
+	want := `SELECT a FROM t WHERE b = $1 AND c IN ($2, $3)`
+	if got := sqlDialects["postgres"].rebind(q); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/hokita/eagle/internal/app"
+
+// tracer looks the tracer up on every use rather than once, so spans go to
+// whichever provider is installed when they start. Until StartTracing
+// installs one, the global provider discards them at next to no cost.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartTracing installs a global tracer provider that batches spans to an
+// OTLP/HTTP collector, and returns a function that flushes and stops it.
+// The exporter is configured by the standard OTEL_EXPORTER_OTLP_* variables
+// (OTEL_EXPORTER_OTLP_ENDPOINT defaults to http://localhost:4318), and the
+// sampler by OTEL_TRACES_SAMPLER. serviceName names the service unless
+// OTEL_SERVICE_NAME overrides it.
+func StartTracing(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// TraceHandler wraps h, typically the mux NewMux returns, in a server span
+// per request, continuing the caller's trace when it sends a traceparent
+// header. Spans are named by method and the route pattern the mux matched,
+// not the raw path.
+func TraceHandler(h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if r.Pattern != "" {
+				return r.Method + " " + r.Pattern
+			}
+			return r.Method
+		}))
+}
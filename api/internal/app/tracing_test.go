@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps ended spans in memory
+// for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestTraceHandlerNamesSpansByRoute(t *testing.T) {
+	spans := recordSpans(t)
+	mux := NewMux(NewServer(&fakeRepo{}, &fakeExplainer{}, &fakeAnalyzer{}), fakeVerifier{}, nil, nil, "")
+	TraceHandler(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/liveness", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Name() != "GET /api/liveness" {
+		t.Fatalf("expected one span named for the route, got %v", ended)
+	}
+}
+
+func TestInstrumentRepositoryTracesCallsUnderTheRequest(t *testing.T) {
+	spans := recordSpans(t)
+	fake := &fakeRepo{randomErr: errors.New("boom")}
+	repo := InstrumentRepository(fake, NewMetrics())
+	ctx, parent := tracer().Start(context.Background(), "request")
+	repo.RandomCandidate(ctx, "u1", CandidateQuery{})
+	fake.randomErr = ErrNoCandidate
+	repo.RandomCandidate(ctx, "u1", CandidateQuery{})
+	parent.End()
+
+	ended := spans.Ended()
+	if len(ended) != 3 {
+		t.Fatalf("expected two repository spans and the parent, got %d", len(ended))
+	}
+	for i, want := range []codes.Code{codes.Error, codes.Unset} {
+		s := ended[i]
+		if s.Name() != "SentenceRepository.RandomCandidate" || s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d: expected a RandomCandidate child of the request, got %q", i, s.Name())
+		}
+		if s.Status().Code != want {
+			t.Errorf("span %d: expected status %v, got %v", i, want, s.Status().Code)
+		}
+	}
+}
+
+func TestInstrumentContentGeneratorTracesModel(t *testing.T) {
+	spans := recordSpans(t)
+	gen := InstrumentContentGenerator(&fakeContentGenerator{resp: textResponse("hi")}, NewMetrics())
+	gen.GenerateContent(context.Background(), "m1", nil, nil)
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Name() != "llm.generate" {
+		t.Fatalf("expected one llm.generate span, got %v", ended)
+	}
+	want := attribute.String("gen_ai.request.model", "m1")
+	found := false
+	for _, a := range ended[0].Attributes() {
+		found = found || a == want
+	}
+	if !found {
+		t.Errorf("expected %v among %v", want, ended[0].Attributes())
+	}
+}
+
+func TestLogsCarryTraceID(t *testing.T) {
+	recordSpans(t)
+	buf := captureLogs(t)
+	ctx, span := tracer().Start(context.Background(), "request")
+	slog.ErrorContext(ctx, "boom")
+	span.End()
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 || lines[0]["trace_id"] != span.SpanContext().TraceID().String() {
+		t.Fatalf("expected the span's trace_id on the log line, got %v", lines)
+	}
+}
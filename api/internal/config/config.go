@@ -0,0 +1,335 @@
+// Package config loads the API server's settings from the environment and an
+// optional file, validates them once at startup, and hands each part of the
+// app the typed configuration it takes.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokita/eagle/internal/app"
+)
+
+// Config is every setting cmd/e2eserver and the API server read. The
+// variable each field comes from is documented in api/.env.example.
+type Config struct {
+	// ProjectID is GOOGLE_CLOUD_PROJECT: the Firebase Auth project and, for
+	// the firestore backend, the database's project.
+	ProjectID     string
+	AllowedEmails []string
+	AdminEmails   []string
+	// FrontendURL is the comma-separated list of origins CORS allows.
+	FrontendURL string
+	Port        int
+
+	LogFormat string
+	LogLevel  slog.Level
+	Tracing   bool
+	// MetricsToken is the bearer token /metrics requires; empty turns
+	// /metrics off.
+	MetricsToken string
+
+	Repo app.RepoConfig
+	LLM  app.LLMConfig
+	// GradingMode is "exact" or "ai".
+	GradingMode        string
+	GenerationCacheTTL time.Duration
+	// RateLimitBackend is "memory", "repository" or "off".
+	RateLimitBackend string
+	RateLimit        app.RateLimit
+	Limits           app.Limits
+	Serve            app.ServeConfig
+	Readiness        app.ReadinessConfig
+	// ReadinessLLM is "off", "optional" (reported only) or "required" (503
+	// while the model provider is down).
+	ReadinessLLM string
+}
+
+// Load reads the configuration from the environment. When CONFIG_FILE names
+// a file of KEY=VALUE lines in the format of api/.env.example, its settings
+// apply wherever the environment does not set the same variable. Every
+// invalid setting is reported in the returned error, not just the first.
+func Load() (*Config, error) {
+	return load(os.LookupEnv)
+}
+
+func load(lookupEnv func(string) (string, bool)) (*Config, error) {
+	src := &source{lookupEnv: lookupEnv}
+	if path, ok := lookupEnv("CONFIG_FILE"); ok && path != "" {
+		file, err := readEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src.file, src.filePath = file, path
+	}
+
+	c := &Config{
+		ProjectID:     src.str("GOOGLE_CLOUD_PROJECT", ""),
+		AllowedEmails: app.ParseAllowedEmails(src.str("ALLOWED_EMAILS", "")),
+		AdminEmails:   app.ParseAllowedEmails(src.str("ADMIN_EMAILS", "")),
+		FrontendURL:   src.str("FRONTEND_URL", ""),
+		Port:          src.int("PORT", 8080),
+		LogFormat:     src.str("LOG_FORMAT", ""),
+		Tracing:       src.bool("TRACING_ENABLED", false),
+		MetricsToken:  src.str("METRICS_TOKEN", ""),
+	}
+	// json is the default on Cloud Run, which sets K_SERVICE.
+	if c.LogFormat == "" && src.str("K_SERVICE", "") != "" {
+		c.LogFormat = app.LogFormatJSON
+	}
+	if v := src.str("LOG_LEVEL", ""); v != "" {
+		if err := c.LogLevel.UnmarshalText([]byte(v)); err != nil {
+			src.fail("LOG_LEVEL", "must be debug, info, warn or error, got %q", v)
+		}
+	}
+
+	review := app.DefaultReviewPolicy()
+	review.NewPerDay = src.int("NEW_CARDS_PER_DAY", review.NewPerDay)
+	review.ReviewsPerDay = src.int("REVIEWS_PER_DAY", review.ReviewsPerDay)
+	review.MasteryThreshold = src.int("MASTERY_THRESHOLD", review.MasteryThreshold)
+	c.Repo = app.RepoConfig{
+		Backend:      src.str("REPO_BACKEND", "firestore"),
+		ProjectID:    c.ProjectID,
+		DatabaseURL:  src.str("DATABASE_URL", ""),
+		SeedFile:     src.str("SEED_FILE", ""),
+		ReviewPolicy: review,
+		ReportPolicy: app.ReportPolicy{HideAfter: src.int("REPORT_HIDE_THRESHOLD", 0)},
+	}
+
+	c.LLM = loadLLM(src)
+	c.GradingMode = src.str("GRADING_MODE", "exact")
+	c.GenerationCacheTTL = src.duration("GENERATION_CACHE_TTL", 7*24*time.Hour)
+
+	c.RateLimitBackend = src.str("RATE_LIMIT_BACKEND", "memory")
+	c.RateLimit = app.DefaultRateLimit()
+	c.RateLimit.Burst = src.int("AI_RATE_BURST", c.RateLimit.Burst)
+	c.RateLimit.Refill = src.duration("AI_RATE_REFILL", c.RateLimit.Refill)
+	c.RateLimit.DailyQuota = src.int("AI_DAILY_QUOTA", c.RateLimit.DailyQuota)
+
+	c.Limits = app.DefaultLimits()
+	c.Limits.MaxUserAnswerLength = src.int("MAX_USER_ANSWER_LENGTH", c.Limits.MaxUserAnswerLength)
+	c.Limits.MaxInsightMistakes = src.int("MAX_INSIGHT_MISTAKES", c.Limits.MaxInsightMistakes)
+
+	c.Serve = app.DefaultServeConfig(":" + strconv.Itoa(c.Port))
+	c.Serve.ReadHeaderTimeout = src.duration("HTTP_READ_HEADER_TIMEOUT", c.Serve.ReadHeaderTimeout)
+	c.Serve.ReadTimeout = src.duration("HTTP_READ_TIMEOUT", c.Serve.ReadTimeout)
+	c.Serve.WriteTimeout = src.duration("HTTP_WRITE_TIMEOUT", c.Serve.WriteTimeout)
+	c.Serve.IdleTimeout = src.duration("HTTP_IDLE_TIMEOUT", c.Serve.IdleTimeout)
+	c.Serve.ShutdownTimeout = src.duration("SHUTDOWN_TIMEOUT", c.Serve.ShutdownTimeout)
+
+	c.Readiness = app.DefaultReadinessConfig()
+	c.Readiness.Timeout = src.duration("READINESS_TIMEOUT", c.Readiness.Timeout)
+	c.Readiness.CacheTTL = src.duration("READINESS_CACHE_TTL", c.Readiness.CacheTTL)
+	c.ReadinessLLM = src.str("READINESS_LLM", "off")
+
+	src.unknownFileKeys()
+	c.validate(src)
+	if len(src.errs) > 0 {
+		// One line, so the whole list lands in a single log record.
+		msgs := make([]string, len(src.errs))
+		for i, err := range src.errs {
+			msgs[i] = err.Error()
+		}
+		return nil, errors.New("invalid configuration: " + strings.Join(msgs, "; "))
+	}
+	return c, nil
+}
+
+// loadLLM reads the LLM provider and per-use-case model settings.
+// LLM_PROVIDER is gemini (the default, keyed by GEMINI_API_KEY) or openai,
+// for any OpenAI-compatible server at OPENAI_BASE_URL such as a local Ollama;
+// OpenAI-compatible servers have no default model, so LLM_MODEL or every
+// per-use-case model in use must be set (GRADE_MODEL only with
+// GRADING_MODE=ai). LLM_MODEL sets the model for every use
+// case; EXPLAIN_*, INSIGHT_* and GRADE_* override the model, timeout and
+// max output tokens of each one.
+func loadLLM(src *source) app.LLMConfig {
+	cfg := app.DefaultLLMConfig()
+	cfg.Provider = src.str("LLM_PROVIDER", cfg.Provider)
+	// Both keys are read either way, so neither is reported as unknown.
+	geminiKey, openAIKey := src.str("GEMINI_API_KEY", ""), src.str("OPENAI_API_KEY", "")
+	baseURL := src.str("OPENAI_BASE_URL", "")
+	switch cfg.Provider {
+	case app.ProviderGemini:
+		cfg.APIKey = geminiKey
+	case app.ProviderOpenAI:
+		cfg.APIKey, cfg.BaseURL = openAIKey, baseURL
+		cfg.Explain.Model, cfg.Insight.Model, cfg.Grade.Model = "", "", ""
+	}
+	if m := src.str("LLM_MODEL", ""); m != "" {
+		cfg.Explain.Model, cfg.Insight.Model, cfg.Grade.Model = m, m, m
+	}
+	cfg.Explain = loadModelSettings(src, "EXPLAIN", cfg.Explain)
+	cfg.Insight = loadModelSettings(src, "INSIGHT", cfg.Insight)
+	cfg.Grade = loadModelSettings(src, "GRADE", cfg.Grade)
+	return cfg
+}
+
+func loadModelSettings(src *source, prefix string, s app.ModelSettings) app.ModelSettings {
+	s.Model = src.str(prefix+"_MODEL", s.Model)
+	s.Timeout = src.duration(prefix+"_TIMEOUT", s.Timeout)
+	s.MaxOutputTokens = int32(src.int(prefix+"_MAX_OUTPUT_TOKENS", int(s.MaxOutputTokens)))
+	return s
+}
+
+// validate checks the settings that parsed but make no sense. The LLM
+// settings are left to ValidateLLM, since cmd/e2eserver runs without a
+// model.
+func (c *Config) validate(src *source) {
+	if c.ProjectID == "" {
+		src.fail("GOOGLE_CLOUD_PROJECT", "is required")
+	}
+	if len(c.AllowedEmails) == 0 {
+		src.fail("ALLOWED_EMAILS", "is required")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		src.fail("PORT", "must be between 1 and 65535, got %d", c.Port)
+	}
+	oneOf(src, "LOG_FORMAT", c.LogFormat, "", app.LogFormatText, app.LogFormatJSON)
+	oneOf(src, "REPO_BACKEND", c.Repo.Backend, "firestore", "memory", "sqlite", "postgres")
+	if (c.Repo.Backend == "sqlite" || c.Repo.Backend == "postgres") && c.Repo.DatabaseURL == "" {
+		src.fail("DATABASE_URL", "is required for REPO_BACKEND=%s", c.Repo.Backend)
+	}
+	oneOf(src, "LLM_PROVIDER", c.LLM.Provider, app.ProviderGemini, app.ProviderOpenAI)
+	oneOf(src, "GRADING_MODE", c.GradingMode, "exact", "ai")
+	oneOf(src, "RATE_LIMIT_BACKEND", c.RateLimitBackend, "memory", "repository", "off")
+	oneOf(src, "READINESS_LLM", c.ReadinessLLM, "off", "optional", "required")
+
+	nonNegative(src, "REPORT_HIDE_THRESHOLD", int64(c.Repo.ReportPolicy.HideAfter))
+	nonNegative(src, "AI_RATE_BURST", int64(c.RateLimit.Burst))
+	nonNegative(src, "AI_DAILY_QUOTA", int64(c.RateLimit.DailyQuota))
+	if c.RateLimit.Burst > 0 {
+		positive(src, "AI_RATE_REFILL", int64(c.RateLimit.Refill))
+	}
+	nonNegative(src, "GENERATION_CACHE_TTL", int64(c.GenerationCacheTTL))
+	positive(src, "MASTERY_THRESHOLD", int64(c.Repo.ReviewPolicy.MasteryThreshold))
+	positive(src, "MAX_USER_ANSWER_LENGTH", int64(c.Limits.MaxUserAnswerLength))
+	positive(src, "MAX_INSIGHT_MISTAKES", int64(c.Limits.MaxInsightMistakes))
+	positive(src, "HTTP_READ_HEADER_TIMEOUT", int64(c.Serve.ReadHeaderTimeout))
+	positive(src, "HTTP_READ_TIMEOUT", int64(c.Serve.ReadTimeout))
+	positive(src, "HTTP_WRITE_TIMEOUT", int64(c.Serve.WriteTimeout))
+	positive(src, "HTTP_IDLE_TIMEOUT", int64(c.Serve.IdleTimeout))
+	positive(src, "SHUTDOWN_TIMEOUT", int64(c.Serve.ShutdownTimeout))
+	positive(src, "READINESS_TIMEOUT", int64(c.Readiness.Timeout))
+	nonNegative(src, "READINESS_CACHE_TTL", int64(c.Readiness.CacheTTL))
+}
+
+// ValidateLLM reports whether the LLM settings can reach a model. Only the
+// server that calls one needs them valid, and the grade settings only with
+// GRADING_MODE=ai.
+func (c *Config) ValidateLLM() error {
+	if err := c.LLM.Validate(); err != nil {
+		return fmt.Errorf("invalid LLM configuration: %w", err)
+	}
+	if c.GradingMode == "ai" {
+		if err := c.LLM.ValidateGrade(); err != nil {
+			return fmt.Errorf("invalid LLM configuration: %w", err)
+		}
+	}
+	return nil
+}
+
+func oneOf(src *source, key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		src.fail(key, "must be one of %s, got %q", strings.Join(slices.DeleteFunc(allowed, func(a string) bool { return a == "" }), ", "), value)
+	}
+}
+
+func positive(src *source, key string, n int64) {
+	if n <= 0 {
+		src.fail(key, "must be positive")
+	}
+}
+
+func nonNegative(src *source, key string, n int64) {
+	if n < 0 {
+		src.fail(key, "must not be negative")
+	}
+}
+
+const redacted = "[redacted]"
+
+// LogValue is the effective configuration with secrets redacted: the API
+// keys, the metrics token, any password in DATABASE_URL, and the allowlisted
+// addresses, of which only the count is shown.
+func (c *Config) LogValue() slog.Value {
+	secret := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return redacted
+	}
+	model := func(s app.ModelSettings) slog.Value {
+		return slog.GroupValue(
+			slog.String("model", s.Model),
+			slog.Duration("timeout", s.Timeout),
+			slog.Int("max_output_tokens", int(s.MaxOutputTokens)))
+	}
+	return slog.GroupValue(
+		slog.String("project_id", c.ProjectID),
+		slog.Int("allowed_emails", len(c.AllowedEmails)),
+		slog.Int("admin_emails", len(c.AdminEmails)),
+		slog.String("frontend_url", c.FrontendURL),
+		slog.Int("port", c.Port),
+		slog.String("log_format", c.LogFormat),
+		slog.String("log_level", c.LogLevel.String()),
+		slog.Bool("tracing", c.Tracing),
+		slog.String("metrics_token", secret(c.MetricsToken)),
+		slog.Group("repo",
+			slog.String("backend", c.Repo.Backend),
+			slog.String("database_url", redactDatabaseURL(c.Repo.DatabaseURL)),
+			slog.String("seed_file", c.Repo.SeedFile),
+			slog.Int("new_cards_per_day", c.Repo.ReviewPolicy.NewPerDay),
+			slog.Int("reviews_per_day", c.Repo.ReviewPolicy.ReviewsPerDay),
+			slog.Int("mastery_threshold", c.Repo.ReviewPolicy.MasteryThreshold),
+			slog.Int("report_hide_threshold", c.Repo.ReportPolicy.HideAfter)),
+		slog.Group("llm",
+			slog.String("provider", c.LLM.Provider),
+			slog.String("api_key", secret(c.LLM.APIKey)),
+			slog.String("base_url", c.LLM.BaseURL),
+			slog.Any("explain", model(c.LLM.Explain)),
+			slog.Any("insight", model(c.LLM.Insight)),
+			slog.Any("grade", model(c.LLM.Grade))),
+		slog.String("grading_mode", c.GradingMode),
+		slog.Duration("generation_cache_ttl", c.GenerationCacheTTL),
+		slog.Group("rate_limit",
+			slog.String("backend", c.RateLimitBackend),
+			slog.Int("burst", c.RateLimit.Burst),
+			slog.Duration("refill", c.RateLimit.Refill),
+			slog.Int("daily_quota", c.RateLimit.DailyQuota)),
+		slog.Group("limits",
+			slog.Int("max_user_answer_length", c.Limits.MaxUserAnswerLength),
+			slog.Int("max_insight_mistakes", c.Limits.MaxInsightMistakes)),
+		slog.Group("http",
+			slog.Duration("read_header_timeout", c.Serve.ReadHeaderTimeout),
+			slog.Duration("read_timeout", c.Serve.ReadTimeout),
+			slog.Duration("write_timeout", c.Serve.WriteTimeout),
+			slog.Duration("idle_timeout", c.Serve.IdleTimeout),
+			slog.Duration("shutdown_timeout", c.Serve.ShutdownTimeout)),
+		slog.Group("readiness",
+			slog.Duration("timeout", c.Readiness.Timeout),
+			slog.Duration("cache_ttl", c.Readiness.CacheTTL),
+			slog.String("llm", c.ReadinessLLM)),
+	)
+}
+
+// dsnPassword matches the password in a key=value Postgres connection
+// string.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// redactDatabaseURL hides the password in a Postgres URL or key=value
+// connection string; a SQLite path has none and is returned as is.
+func redactDatabaseURL(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(s, "${1}"+redacted)
+}
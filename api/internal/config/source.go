@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// source looks settings up in the environment, then in the config file, and
+// collects an error for each one that does not parse.
+type source struct {
+	lookupEnv func(string) (string, bool)
+	file      map[string]string
+	filePath  string
+	// read is every key looked up, so unknownFileKeys can report the rest.
+	read map[string]bool
+	errs []error
+}
+
+func (s *source) lookup(key string) (string, bool) {
+	if s.read == nil {
+		s.read = map[string]bool{}
+	}
+	s.read[key] = true
+	if v, ok := s.lookupEnv(key); ok {
+		return v, true
+	}
+	v, ok := s.file[key]
+	return v, ok
+}
+
+func (s *source) fail(key, format string, args ...any) {
+	s.errs = append(s.errs, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+}
+
+// str returns key's value, or def when it is unset or empty.
+func (s *source) str(key, def string) string {
+	if v, ok := s.lookup(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+func (s *source) int(key string, def int) int {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		s.fail(key, "must be an integer, got %q", v)
+		return def
+	}
+	return n
+}
+
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		s.fail(key, "must be a duration such as 20s, got %q", v)
+		return def
+	}
+	return d
+}
+
+func (s *source) bool(key string, def bool) bool {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.fail(key, "must be true or false, got %q", v)
+		return def
+	}
+	return b
+}
+
+// unknownFileKeys reports settings in the config file that nothing reads,
+// which are most likely misspelled. Unknown environment variables are not
+// reported; the environment holds plenty that are not ours.
+func (s *source) unknownFileKeys() {
+	var unknown []string
+	for key := range s.file {
+		if !s.read[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	for _, key := range unknown {
+		s.fail(key, "in %s is not a known setting", s.filePath)
+	}
+}
+
+// readEnvFile parses a file of KEY=VALUE lines. Blank lines and lines
+// starting with # are skipped, and a value may be wrapped in single or double
+// quotes.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	defer f.Close()
+	vars := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[key] = value
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	return vars, nil
+}
@@ -2,63 +2,114 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 
-	"cloud.google.com/go/firestore"
-
 	"github.com/hokita/eagle/internal/app"
+	"github.com/hokita/eagle/internal/config"
 )
 
 func main() {
 	ctx := context.Background()
 
-	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
-	if projectID == "" {
-		log.Fatal("GOOGLE_CLOUD_PROJECT is required")
+	// Every setting, and the CONFIG_FILE they may also come from, is
+	// documented in .env.example.
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("failed to load configuration", "err", err)
+	}
+	if err := cfg.ValidateLLM(); err != nil {
+		fatal("failed to load configuration", "err", err)
 	}
 
-	allowedEmails := app.ParseAllowedEmails(os.Getenv("ALLOWED_EMAILS"))
-	if len(allowedEmails) == 0 {
-		log.Fatal("ALLOWED_EMAILS is required")
+	logger, err := app.NewLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fatal("invalid LOG_FORMAT", "err", err)
 	}
+	slog.SetDefault(logger)
+	slog.Info("effective configuration", "config", cfg)
 
-	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
-	if geminiAPIKey == "" {
-		log.Fatal("GEMINI_API_KEY is required")
+	if err := run(ctx, cfg); err != nil {
+		fatal("server failed", "err", err)
 	}
+	slog.Info("server stopped")
+}
 
-	client, err := firestore.NewClient(ctx, projectID)
-	if err != nil {
-		log.Fatalf("failed to create Firestore client: %v", err)
+// run serves until ctx is done or serving fails. It returns rather than
+// exits on failure, so the deferred closes still run before main reports a
+// nonzero status.
+func run(ctx context.Context, cfg *config.Config) error {
+	// Tracing starts first so the Firestore and genai clients' own spans are
+	// captured too.
+	if cfg.Tracing {
+		shutdown, err := app.StartTracing(ctx, "eagle-api")
+		if err != nil {
+			return fmt.Errorf("failed to start tracing: %w", err)
+		}
+		defer shutdown(context.Background())
 	}
-	defer client.Close()
 
-	verifier, err := app.NewFirebaseVerifier(ctx, projectID)
+	repo, closeRepo, err := app.OpenRepository(ctx, cfg.Repo)
 	if err != nil {
-		log.Fatalf("failed to create auth verifier: %v", err)
+		return fmt.Errorf("failed to open repository: %w", err)
 	}
+	defer closeRepo()
+	// metrics back /metrics: HTTP, repository and LLM latencies and errors,
+	// LLM token usage and grading outcomes.
+	metrics := app.NewMetrics()
+	repo = app.InstrumentRepository(repo, metrics)
 
-	explainer, err := app.NewGeminiExplainer(ctx, geminiAPIKey)
+	verifier, err := app.NewFirebaseVerifier(ctx, cfg.ProjectID)
 	if err != nil {
-		log.Fatalf("failed to create Gemini explainer: %v", err)
+		return fmt.Errorf("failed to create auth verifier: %w", err)
 	}
 
-	analyzer, err := app.NewGeminiWeaknessAnalyzer(ctx, geminiAPIKey)
+	models, closeModels, err := app.NewContentGenerator(ctx, cfg.LLM)
 	if err != nil {
-		log.Fatalf("failed to create Gemini weakness analyzer: %v", err)
+		return fmt.Errorf("failed to create LLM client: %w", err)
 	}
+	// Deferred after closeRepo, so on shutdown the LLM client closes first,
+	// then the repository, then tracing flushes the spans of both.
+	defer closeModels()
+	// The readiness check lists models through the bare client, keeping
+	// probes out of the LLM call metrics.
+	var readinessChecks []app.ReadinessCheck
+	if cfg.ReadinessLLM != "off" {
+		readinessChecks = append(readinessChecks, app.LLMReadinessCheck(models, cfg.ReadinessLLM == "required"))
+	}
+	models = app.InstrumentContentGenerator(models, metrics)
 
-	srv := app.NewServer(app.NewFirestoreRepo(client), explainer, analyzer)
-
-	frontendURL := os.Getenv("FRONTEND_URL")
-	mux := app.NewMux(srv, verifier, allowedEmails, frontendURL)
+	srv := app.NewServer(repo,
+		app.NewLLMExplainer(models, cfg.LLM.Explain),
+		app.NewLLMWeaknessAnalyzer(models, cfg.LLM.Insight))
+	srv.SetMetrics(metrics)
+	srv.SetMetricsToken(cfg.MetricsToken)
+	srv.SetLimits(cfg.Limits)
+	srv.SetReadiness(cfg.Readiness, readinessChecks...)
+	if cfg.GradingMode == "ai" {
+		srv.SetGrader(app.NewLLMGrader(models, cfg.LLM.Grade))
+	}
+	srv.SetGenerationCacheTTL(cfg.GenerationCacheTTL)
+	switch cfg.RateLimitBackend {
+	case "memory":
+		srv.SetRateLimiter(app.NewRateLimiter(app.NewMemoryRateCounter(), cfg.RateLimit))
+	case "repository":
+		srv.SetRateLimiter(app.NewRateLimiter(repo, cfg.RateLimit))
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	var handler http.Handler = app.NewMux(srv, verifier, cfg.AllowedEmails, cfg.AdminEmails, cfg.FrontendURL)
+	if cfg.Tracing {
+		handler = app.TraceHandler(handler)
 	}
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, mux))
+
+	slog.Info("server starting", "port", cfg.Port)
+	return app.Serve(ctx, cfg.Serve, handler)
+}
+
+// fatal logs msg and its attributes as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
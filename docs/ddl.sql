@@ -1,21 +1,104 @@
+-- Schema for the SQL repository backends (REPO_BACKEND=sqlite|postgres).
+-- The authoritative copy is sqlMigrations in api/internal/app/sql_repo.go,
+-- which creates and upgrades it automatically; this file is for reference.
+-- On Postgres, answer_histories.id is BIGSERIAL PRIMARY KEY instead.
+--
+-- Stats and histories are per user (uid = Firebase Auth uid), mirroring the
+-- Firestore layout users/{uid}/sentence_stats/{id}/histories. Their
+-- timestamps are Unix microseconds.
+
 -- Table: sentences
 CREATE TABLE sentences (
-    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    id INTEGER PRIMARY KEY,
     japanese TEXT NOT NULL,
     english TEXT NOT NULL,
-    page INT UNSIGNED NOT NULL,
+    page TEXT NOT NULL DEFAULT '',
+    level INTEGER NOT NULL DEFAULT 0,
     is_reported BOOLEAN NOT NULL DEFAULT FALSE,
-    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    -- JSON array of alternative translations graded as correct.
+    accepted_answers TEXT NOT NULL DEFAULT '[]'
+);
+
+-- Table: sentence_stats
+CREATE TABLE sentence_stats (
+    uid TEXT NOT NULL,
+    sentence_id INTEGER NOT NULL,
+    correct_count INTEGER NOT NULL DEFAULT 0,
+    incorrect_count INTEGER NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL,
+    -- Spaced-repetition state; 0 means unset.
+    due_at BIGINT NOT NULL DEFAULT 0,
+    ease DOUBLE PRECISION NOT NULL DEFAULT 0,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    reps INTEGER NOT NULL DEFAULT 0,
+    first_reviewed_at BIGINT NOT NULL DEFAULT 0,
+    last_reviewed_at BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (uid, sentence_id)
 );
+CREATE INDEX sentence_stats_uid_updated_at ON sentence_stats (uid, updated_at);
 
 -- Table: answer_histories
 CREATE TABLE answer_histories (
-    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
-    sentence_id INT UNSIGNED NOT NULL,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uid TEXT NOT NULL,
+    sentence_id INTEGER NOT NULL,
     is_correct BOOLEAN NOT NULL,
     incorrect_answer TEXT NOT NULL,
-    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    FOREIGN KEY (sentence_id) REFERENCES sentences(id)
+    created_at BIGINT NOT NULL,
+    graded_by TEXT NOT NULL DEFAULT 'exact',
+    grader_reason TEXT NOT NULL DEFAULT '',
+    overridden BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX answer_histories_uid_sentence_created_at ON answer_histories (uid, sentence_id, created_at);
+
+-- Table: generation_cache
+-- Cached LLM output (explanations, weakness insights); cache_key is a hash of
+-- the provider, model and normalized inputs. Rows past expires_at are ignored,
+-- and deleted on the next write.
+CREATE TABLE generation_cache (
+    cache_key TEXT PRIMARY KEY,
+    text TEXT NOT NULL,
+    expires_at BIGINT NOT NULL
+);
+CREATE INDEX generation_cache_expires_at ON generation_cache (expires_at);
+
+-- Table: rate_limits
+-- Per-user AI endpoint usage when RATE_LIMIT_BACKEND=repository; rate_key is
+-- "<uid>:<scope>" (scope is explain, insight or grade).
+CREATE TABLE rate_limits (
+    rate_key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at BIGINT NOT NULL,
+    day BIGINT NOT NULL,
+    day_count INTEGER NOT NULL
+);
+
+-- Table: sentence_reports
+-- One report per learner per sentence; a repeat report replaces the earlier
+-- one. sentences.is_reported is set once REPORT_HIDE_THRESHOLD learners have
+-- reported a sentence.
+CREATE TABLE sentence_reports (
+    sentence_id INTEGER NOT NULL,
+    uid TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    comment TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (sentence_id, uid)
+);
+
+-- Table: practice_sessions
+-- A learner's practice session. questions is a JSON array of the questions
+-- served so far with their answers; completed_at is 0 while the session runs.
+CREATE TABLE practice_sessions (
+    uid TEXT NOT NULL,
+    id TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    levels TEXT NOT NULL,
+    source TEXT NOT NULL,
+    questions TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    completed_at BIGINT NOT NULL,
+    PRIMARY KEY (uid, id)
 );
@@ -81,6 +81,27 @@ describe('Mistakes', () => {
     await screen.findByText(/no mistakes yet/i)
   })
 
+  it('appends the next page on Load more until the last page', async () => {
+    mockApi.listMistakes.mockResolvedValueOnce({ ...oneMistake, next_cursor: 'page-2' })
+    mockApi.listMistakes.mockResolvedValueOnce({
+      mistakes: [
+        {
+          sentence_id: 2,
+          japanese: '雨が降っています。',
+          correct_answer: "It's raining.",
+          wrong_answers: [{ id: 3, incorrect_answer: 'It rains now.', created_at: '2026-01-02T00:00:00Z' }],
+        },
+      ],
+    })
+    render(<Mistakes user={fakeUser} />)
+    await screen.findByText('時間がありません。')
+    fireEvent.click(screen.getByRole('button', { name: /load more/i }))
+    await screen.findByText('雨が降っています。')
+    expect(mockApi.listMistakes).toHaveBeenLastCalledWith('page-2')
+    expect(screen.getByText('時間がありません。')).toBeInTheDocument()
+    expect(screen.queryByRole('button', { name: /load more/i })).not.toBeInTheDocument()
+  })
+
   it('shows an error state with a working retry button', async () => {
     mockApi.listMistakes.mockRejectedValueOnce(new Error('boom'))
     render(<Mistakes user={fakeUser} />)
@@ -51,6 +51,9 @@ export default function Mistakes({ user }: Props) {
   const [mistakes, setMistakes] = useState<Mistake[] | null>(null)
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState<string | null>(null)
+  const [nextCursor, setNextCursor] = useState<string | undefined>()
+  const [loadingMore, setLoadingMore] = useState(false)
+  const [loadMoreError, setLoadMoreError] = useState<string | null>(null)
   const [insight, setInsight] = useState<string | null>(null)
   const [insightLoading, setInsightLoading] = useState(false)
   const [insightError, setInsightError] = useState<string | null>(null)
@@ -98,6 +101,7 @@ export default function Mistakes({ user }: Props) {
       setError(null)
       const result = await api.listMistakes()
       setMistakes(result.mistakes)
+      setNextCursor(result.next_cursor)
       if (result.mistakes.length > 0) {
         loadInsight(result.mistakes, loadStoredLanguage())
       }
@@ -108,6 +112,23 @@ export default function Mistakes({ user }: Props) {
     }
   }
 
+  // loadMore appends the next page. The insight keeps analyzing the first
+  // page: it is fetched server-side from the whole history either way.
+  const loadMore = async () => {
+    if (!nextCursor) return
+    try {
+      setLoadingMore(true)
+      setLoadMoreError(null)
+      const result = await api.listMistakes(nextCursor)
+      setMistakes(prev => [...(prev ?? []), ...result.mistakes])
+      setNextCursor(result.next_cursor)
+    } catch (err) {
+      setLoadMoreError(err instanceof Error ? err.message : 'Failed to load more mistakes')
+    } finally {
+      setLoadingMore(false)
+    }
+  }
+
   useEffect(() => {
     loadMistakes()
   }, [])
@@ -191,6 +212,12 @@ export default function Mistakes({ user }: Props) {
                 </CardContent>
               </Card>
             ))}
+            {loadMoreError && <p className="text-sm text-destructive">{loadMoreError}</p>}
+            {nextCursor && (
+              <Button variant="outline" onClick={loadMore} disabled={loadingMore} className="w-full">
+                {loadingMore ? 'Loading...' : 'Load more'}
+              </Button>
+            )}
           </div>
         )}
       </div>
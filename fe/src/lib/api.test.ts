@@ -69,6 +69,13 @@ describe('api.getRandomSentence', () => {
     const [url] = mockFetch.mock.calls[0]
     expect(url).toContain('/api/sentence/random?levels=1,3')
   })
+
+  it('sends the source query param alongside levels', async () => {
+    mockResponse({ id: 1 })
+    await api.getRandomSentence([2], 'mistakes')
+    const [url] = mockFetch.mock.calls[0]
+    expect(url).toContain('/api/sentence/random?levels=2&source=mistakes')
+  })
 })
 
 describe('api.checkAnswer', () => {
@@ -146,6 +153,15 @@ describe('api.listMistakes', () => {
     expect(result.mistakes[0].sentence_id).toBe(1)
     expect(result.mistakes[0].wrong_answers[0].incorrect_answer).toBe('I have no time.')
   })
+
+  it('passes the cursor of the previous page', async () => {
+    mockResponse({ mistakes: [] })
+    await api.listMistakes('eyJz+/=')
+    expect(mockFetch).toHaveBeenCalledWith(
+      expect.stringContaining('/api/mistakes?cursor=eyJz%2B%2F%3D'),
+      expect.anything()
+    )
+  })
 })
 
 describe('api.getMistakesInsight', () => {
@@ -167,4 +183,14 @@ describe('api error handling', () => {
     mockResponse({}, 500)
     await expect(api.getRandomSentence()).rejects.toThrow('API error: 500')
   })
+
+  it('exposes the problem code and request ID', async () => {
+    mockResponse({ status: 404, code: 'no_candidate', detail: 'No sentences found', request_id: 'req-1' }, 404)
+    await expect(api.getRandomSentence()).rejects.toMatchObject({
+      status: 404,
+      code: 'no_candidate',
+      detail: 'No sentences found',
+      requestId: 'req-1',
+    })
+  })
 })
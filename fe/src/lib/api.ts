@@ -12,6 +12,9 @@ export interface Sentence {
   updated_at: string
 }
 
+// Which sentences /api/sentence/random chooses from; the server defaults to 'all'.
+export type SentenceSource = 'all' | 'new' | 'seen' | 'review' | 'mistakes'
+
 export interface AnswerHistory {
   id: number
   incorrect_answer: string
@@ -22,9 +25,18 @@ export interface Mistake {
   sentence_id: number
   japanese: string
   correct_answer: string
+  level: number
+  correct_count: number
+  incorrect_count: number
   wrong_answers: AnswerHistory[]
 }
 
+export interface ListMistakesResponse {
+  mistakes: Mistake[]
+  // next_cursor is absent on the last page.
+  next_cursor?: string
+}
+
 export interface CheckAnswerResponse {
   is_correct: boolean
   correct_answer: string
@@ -41,6 +53,29 @@ export interface MistakesInsightResponse {
 
 const BASE_URL = process.env.NEXT_PUBLIC_API_URL ?? ''
 
+// ApiError carries the API's problem+json error body: `code` is stable and
+// safe to branch on, `requestId` identifies the request in server logs.
+export class ApiError extends Error {
+  constructor(
+    readonly status: number,
+    readonly code?: string,
+    readonly detail?: string,
+    readonly requestId?: string
+  ) {
+    super(`API error: ${status}${code ? ` (${code})` : ''}`)
+    this.name = 'ApiError'
+  }
+}
+
+async function apiError(res: Response): Promise<ApiError> {
+  try {
+    const problem = await res.json()
+    return new ApiError(res.status, problem.code, problem.detail, problem.request_id)
+  } catch {
+    return new ApiError(res.status)
+  }
+}
+
 async function request<T>(path: string, options?: RequestInit): Promise<T> {
   const token = await auth.currentUser?.getIdToken()
   const res = await fetch(`${BASE_URL}${path}`, {
@@ -51,16 +86,18 @@ async function request<T>(path: string, options?: RequestInit): Promise<T> {
       ...options?.headers,
     },
   })
-  if (!res.ok) throw new Error(`API error: ${res.status}`)
+  if (!res.ok) throw await apiError(res)
   if (res.status === 204) return undefined as T
   return res.json()
 }
 
 export const api = {
-  getRandomSentence: (levels?: number[]) =>
-    request<Sentence>(
-      `/api/sentence/random${levels && levels.length > 0 ? `?levels=${levels.join(',')}` : ''}`
-    ),
+  getRandomSentence: (levels?: number[], source?: SentenceSource) => {
+    const params: string[] = []
+    if (levels && levels.length > 0) params.push(`levels=${levels.join(',')}`)
+    if (source) params.push(`source=${source}`)
+    return request<Sentence>(`/api/sentence/random${params.length > 0 ? `?${params.join('&')}` : ''}`)
+  },
 
   checkAnswer: (sentenceId: number, userAnswer: string) =>
     request<CheckAnswerResponse>('/api/answer/check', {
@@ -74,7 +111,8 @@ export const api = {
       body: JSON.stringify({ sentence_id: sentenceId }),
     }),
 
-  listMistakes: () => request<{ mistakes: Mistake[] }>('/api/mistakes'),
+  listMistakes: (cursor?: string) =>
+    request<ListMistakesResponse>(`/api/mistakes${cursor ? `?cursor=${encodeURIComponent(cursor)}` : ''}`),
 
   getMistakesInsight: (language: 'en' | 'ja') =>
     request<MistakesInsightResponse>(`/api/mistakes/insight?language=${language}`),